@@ -7,7 +7,7 @@ import (
 )
 
 func main() {
-	cmd := grizzly.NewRootCmd()
+	cmd := grizzly.NewRootCmdWithArgs(os.Args[1:])
 	if err := cmd.Execute(); err != nil {
 		os.Exit(grizzly.ExitCode(err))
 	}
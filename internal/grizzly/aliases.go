@@ -0,0 +1,191 @@
+package grizzly
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// annotationAlias marks commands registered from the [aliases] config table;
+// the value is the expansion.
+const annotationAlias = "grizzly/alias"
+
+var aliasNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+var aliasPlaceholder = regexp.MustCompile(`\$([1-9])`)
+
+// loadAliases merges the [aliases] tables of the user and project config,
+// project entries winning. Unreadable files are skipped here; loading the
+// config reports them.
+func loadAliases() map[string]string {
+	aliases := map[string]string{}
+	for _, pathFunc := range []func() (string, error){userConfigPath, projectConfigPath} {
+		path, err := pathFunc()
+		if err != nil {
+			continue
+		}
+		v, err := openConfigFile(path)
+		if err != nil || v == nil {
+			continue
+		}
+		for name, expansion := range v.GetStringMapString("aliases") {
+			aliases[name] = expansion
+		}
+	}
+	return aliases
+}
+
+// registerAliases adds a command for each alias that passes checkAlias and
+// returns the problems with the others.
+func registerAliases(root *cobra.Command, aliases map[string]string) []string {
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var problems []string
+	for _, name := range names {
+		words, err := checkAlias(root, name, aliases[name])
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		root.AddCommand(newAliasCmd(name, aliases[name], words))
+	}
+	return problems
+}
+
+// checkAlias validates an alias against the built-in commands of root and
+// returns its expansion split into words.
+func checkAlias(root *cobra.Command, name, expansion string) ([]string, error) {
+	if !aliasNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("alias %q: names use lowercase letters, digits, - and _", name)
+	}
+	if name == "help" || builtinCommand(root, name) != nil {
+		return nil, fmt.Errorf("alias %q would shadow the built-in command", name)
+	}
+	words, err := splitCommandLine(expansion)
+	if err != nil {
+		return nil, fmt.Errorf("alias %q: %v", name, err)
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("alias %q is empty", name)
+	}
+	if builtinCommand(root, words[0]) == nil {
+		return nil, fmt.Errorf("alias %q: %q is not a grizzly command", name, words[0])
+	}
+	return words, nil
+}
+
+func builtinCommand(root *cobra.Command, name string) *cobra.Command {
+	for _, cmd := range root.Commands() {
+		if _, ok := cmd.Annotations[annotationAlias]; ok {
+			continue
+		}
+		if cmd.Name() == name || cmd.HasAlias(name) {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func newAliasCmd(name, expansion string, words []string) *cobra.Command {
+	return &cobra.Command{
+		Use:   name + " [args...]",
+		Short: fmt.Sprintf("Alias for %q", expansion),
+		Long: "Alias for: grizzly " + expansion + `
+
+Defined in the [aliases] config table. $1 to $9 are replaced by the alias's
+arguments and $@ by all of them; arguments not used by a placeholder are
+appended.`,
+		Annotations:        map[string]string{annotationAlias: expansion},
+		DisableFlagParsing: true,
+		// The expanded command loads the config itself.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			target, _, err := cmd.Root().Find(words)
+			if err != nil || target.ValidArgsFunction == nil {
+				return nil, cobra.ShellCompDirectiveDefault
+			}
+			return target.ValidArgsFunction(target, args, toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			globals, rest := splitGlobalFlags(cmd.Root(), args)
+			expanded, err := expandAlias(words, rest)
+			if err != nil {
+				return usageError(cmd, "%v", err)
+			}
+			// The expansion starts with a built-in command, so the aliases
+			// need not be read again.
+			sub, _ := newRootCmd(false)
+			sub.SetArgs(append(globals, expanded...))
+			return sub.ExecuteContext(cmd.Context())
+		},
+	}
+}
+
+// splitGlobalFlags separates the root's persistent flags at the start of
+// args, which cobra hands to flag-less alias commands along with the rest.
+func splitGlobalFlags(root *cobra.Command, args []string) (globals, rest []string) {
+	flags := root.PersistentFlags()
+	i := 0
+	for i < len(args) {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") || arg == "-" {
+			break
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		flag := flags.Lookup(name)
+		if !strings.HasPrefix(arg, "--") {
+			flag = nil
+			if len(name) == 1 {
+				flag = flags.ShorthandLookup(name)
+			}
+		}
+		if flag == nil {
+			break
+		}
+		i++
+		if !hasValue && flag.NoOptDefVal == "" && i < len(args) {
+			i++
+		}
+	}
+	return args[:i:i], args[i:]
+}
+
+// expandAlias substitutes $1..$9 with args and a "$@" word with all of them.
+// Without $@, args past the highest placeholder are appended.
+func expandAlias(words, args []string) ([]string, error) {
+	used := 0
+	all := false
+	var out []string
+	for _, word := range words {
+		if word == "$@" {
+			all = true
+			out = append(out, args...)
+			continue
+		}
+		var missing int
+		word = aliasPlaceholder.ReplaceAllStringFunc(word, func(m string) string {
+			n, _ := strconv.Atoi(m[1:])
+			used = max(used, n)
+			if n > len(args) {
+				missing = max(missing, n)
+				return m
+			}
+			return args[n-1]
+		})
+		if missing > 0 {
+			return nil, fmt.Errorf("alias needs %d argument(s), got %d", missing, len(args))
+		}
+		out = append(out, word)
+	}
+	if !all && used < len(args) {
+		out = append(out, args[used:]...)
+	}
+	return out, nil
+}
@@ -0,0 +1,99 @@
+package grizzly
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandAlias(t *testing.T) {
+	words := []string{"create", "--title", "$1", "--tag", "$2"}
+	got, err := expandAlias(words, []string{"My note", "work", "--pin"})
+	if want := []string{"create", "--title", "My note", "--tag", "work", "--pin"}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("expand = %q, %v", got, err)
+	}
+	if _, err := expandAlias(words, []string{"only"}); err == nil || !strings.Contains(err.Error(), "needs 2") {
+		t.Fatalf("missing argument: %v", err)
+	}
+	got, err = expandAlias([]string{"search", "--term", "tag:$1", "$@"}, []string{"a", "b"})
+	if want := []string{"search", "--term", "tag:a", "a", "b"}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("expand $@ = %q, %v", got, err)
+	}
+}
+
+func TestSplitGlobalFlags(t *testing.T) {
+	root := NewRootCmd()
+	globals, rest := splitGlobalFlags(root, []string{"--plain", "--profile", "work", "-q", "--timeout=2s", "hello", "--plain"})
+	if want := []string{"--plain", "--profile", "work", "-q", "--timeout=2s"}; !reflect.DeepEqual(globals, want) {
+		t.Fatalf("globals = %q", globals)
+	}
+	if want := []string{"hello", "--plain"}; !reflect.DeepEqual(rest, want) {
+		t.Fatalf("rest = %q", rest)
+	}
+	if globals, rest := splitGlobalFlags(root, []string{"--mode", "append"}); len(globals) != 0 || len(rest) != 2 {
+		t.Fatalf("command flag taken as global: %q %q", globals, rest)
+	}
+}
+
+func TestCheckAlias(t *testing.T) {
+	root := NewRootCmd()
+	for name, expansion := range map[string]string{
+		"tags":  "search",
+		"help":  "tags",
+		"Inbox": "add-text",
+		"loop":  "inbox",
+		"quote": `add-text --title "Inbox`,
+		"blank": "  ",
+		"-x":    "tags",
+	} {
+		if _, err := checkAlias(root, name, expansion); err == nil {
+			t.Fatalf("alias %s = %q accepted", name, expansion)
+		}
+	}
+	words, err := checkAlias(root, "inbox", `add-text --title "My Inbox"`)
+	if want := []string{"add-text", "--title", "My Inbox"}; err != nil || !reflect.DeepEqual(words, want) {
+		t.Fatalf("words = %q, %v", words, err)
+	}
+}
+
+func TestAliasCommands(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	t.Setenv("GRIZZLY_OPENER", "fake")
+	t.Setenv("GRIZZLY_FAKE_BEAR_STORE", filepath.Join(root, "bear.json"))
+	for _, name := range []string{"GRIZZLY_PROFILE", "GRIZZLY_TIMEOUT", "GRIZZLY_TOKEN_FILE", "GRIZZLY_TOKEN_COMMAND"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "grizzly"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	config := "[aliases]\nmk = 'create --title \"$1\" --tag $2'\ntags = \"search\"\n"
+	if err := os.WriteFile(filepath.Join(root, "grizzly", "config.toml"), []byte(config), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	cmd := NewRootCmd()
+	if mk, _, err := cmd.Find([]string{"mk"}); err != nil || mk.Annotations[annotationAlias] == "" {
+		t.Fatalf("alias not registered: %v", err)
+	}
+	if tags, _, _ := cmd.Find([]string{"tags"}); tags.Annotations[annotationAlias] != "" {
+		t.Fatalf("alias shadowed the built-in tags command")
+	}
+
+	cmd.SetArgs([]string{"--plain", "mk", "My note", "work"})
+	stdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := cmd.Execute()
+	w.Close()
+	os.Stdout = stdout
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	if err != nil || !strings.Contains(buf.String(), "title=My note\n") {
+		t.Fatalf("mk = %q, %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,314 @@
+package grizzly
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// secretServiceName is the service the token is filed under in OS secret
+// stores.
+const secretServiceName = "grizzly"
+
+// secretStore keeps the Bear API token for an account (a profile name).
+// Get returns "" when nothing is stored.
+type secretStore interface {
+	Name() string
+	Get(account string) (string, error)
+	Set(account, token string) error
+	Delete(account string) (bool, error)
+}
+
+// runSecretCommand runs a secret store helper. It returns stdout and the
+// exit code, or stderr when the command fails; err is set only when the
+// command could not run. Tests replace it.
+var runSecretCommand = func(stdin, name string, args ...string) (string, int, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return strings.TrimSpace(stderr.String()), exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	return stdout.String(), 0, nil
+}
+
+// systemSecretStore returns the platform's credential store, or nil when
+// there is none. GRIZZLY_SECRET_STORE=file skips it.
+func systemSecretStore() secretStore {
+	if v := os.Getenv("GRIZZLY_SECRET_STORE"); v == "file" || v == "none" {
+		return nil
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("security"); err == nil {
+			return keychainStore{}
+		}
+	case "linux", "freebsd", "openbsd", "netbsd":
+		// secret-tool needs a session bus to reach the keyring.
+		if _, err := exec.LookPath("secret-tool"); err == nil && os.Getenv("DBUS_SESSION_BUS_ADDRESS") != "" {
+			return secretToolStore{}
+		}
+	}
+	return nil
+}
+
+// keychainStore uses the macOS login keychain through security(1).
+type keychainStore struct{}
+
+func (keychainStore) Name() string { return "keychain" }
+
+// security exits with 44 when the item does not exist.
+const keychainNotFound = 44
+
+func (keychainStore) Get(account string) (string, error) {
+	out, code, err := runSecretCommand("", "security", "find-generic-password", "-s", secretServiceName, "-a", account, "-w")
+	if err != nil {
+		return "", err
+	}
+	switch code {
+	case 0:
+		return strings.TrimSpace(out), nil
+	case keychainNotFound:
+		return "", nil
+	}
+	return "", fmt.Errorf("keychain: %s", out)
+}
+
+func (keychainStore) Set(account, token string) error {
+	// Commands read by "security -i" keep the token out of the process list.
+	line := fmt.Sprintf("add-generic-password -U -s %s -a %s -l %s -w %s\n",
+		securityQuote(secretServiceName), securityQuote(account),
+		securityQuote("grizzly ("+account+")"), securityQuote(token))
+	out, code, err := runSecretCommand(line, "security", "-i")
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("keychain: %s", out)
+	}
+	return nil
+}
+
+func (keychainStore) Delete(account string) (bool, error) {
+	out, code, err := runSecretCommand("", "security", "delete-generic-password", "-s", secretServiceName, "-a", account)
+	if err != nil {
+		return false, err
+	}
+	switch code {
+	case 0:
+		return true, nil
+	case keychainNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("keychain: %s", out)
+}
+
+func securityQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// secretToolStore uses the freedesktop Secret Service through
+// secret-tool(1).
+type secretToolStore struct{}
+
+func (secretToolStore) Name() string { return "secret service" }
+
+func (secretToolStore) Get(account string) (string, error) {
+	out, code, err := runSecretCommand("", "secret-tool", "lookup", "service", secretServiceName, "account", account)
+	if err != nil {
+		return "", err
+	}
+	if code != 0 {
+		// lookup exits 1 without output when nothing matches.
+		if out == "" {
+			return "", nil
+		}
+		return "", fmt.Errorf("secret service: %s", out)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (secretToolStore) Set(account, token string) error {
+	out, code, err := runSecretCommand(token, "secret-tool", "store", "--label", "grizzly ("+account+")", "service", secretServiceName, "account", account)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("secret service: %s", out)
+	}
+	return nil
+}
+
+func (s secretToolStore) Delete(account string) (bool, error) {
+	existing, err := s.Get(account)
+	if err != nil || existing == "" {
+		return false, err
+	}
+	out, code, err := runSecretCommand("", "secret-tool", "clear", "service", secretServiceName, "account", account)
+	if err != nil {
+		return false, err
+	}
+	if code != 0 {
+		return false, fmt.Errorf("secret service: %s", out)
+	}
+	return true, nil
+}
+
+// fileStore keeps tokens in 0600 files next to the user config.
+type fileStore struct {
+	dir string
+}
+
+func newFileStore() (fileStore, error) {
+	path, err := userConfigPath()
+	if err != nil {
+		return fileStore{}, err
+	}
+	return fileStore{dir: filepath.Dir(path)}, nil
+}
+
+func (fileStore) Name() string { return "file" }
+
+func (s fileStore) path(account string) string {
+	if account == defaultAuthAccount {
+		return filepath.Join(s.dir, "token")
+	}
+	return filepath.Join(s.dir, "token-"+url.PathEscape(account))
+}
+
+func (s fileStore) Get(account string) (string, error) {
+	token, err := readTokenFromFile(s.path(account))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	return token, err
+}
+
+func (s fileStore) Set(account, token string) error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	path := s.path(account)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(token+"\n"), 0o600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of a leftover temp file.
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s fileStore) Delete(account string) (bool, error) {
+	err := os.Remove(s.path(account))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+const defaultAuthAccount = "default"
+
+// authAccount is the name the token of the active profile is stored under.
+func authAccount(opts *Options) string {
+	if opts.Profile != "" {
+		return opts.Profile
+	}
+	return defaultAuthAccount
+}
+
+// authStores lists where a stored token is looked up, in order.
+func authStores() ([]secretStore, error) {
+	files, err := newFileStore()
+	if err != nil {
+		return nil, err
+	}
+	if system := systemSecretStore(); system != nil {
+		return []secretStore{system, files}, nil
+	}
+	return []secretStore{files}, nil
+}
+
+// storedToken returns the token saved by "auth login" and the store it came
+// from, or "" when none is saved.
+func storedToken(opts *Options) (string, secretStore, error) {
+	stores, err := authStores()
+	if err != nil {
+		return "", nil, err
+	}
+	account := authAccount(opts)
+	for _, store := range stores {
+		token, err := store.Get(account)
+		if err != nil {
+			return "", nil, err
+		}
+		if token != "" {
+			return token, store, nil
+		}
+	}
+	return "", nil, nil
+}
+
+// saveToken stores token for the active profile. kind is auto, system or
+// file; auto falls back to a file when the OS store fails. The token is
+// removed from the other stores so lookups cannot find a stale copy.
+func saveToken(opts *Options, token, kind string) (secretStore, error) {
+	files, err := newFileStore()
+	if err != nil {
+		return nil, err
+	}
+	system := systemSecretStore()
+	var target secretStore
+	switch kind {
+	case "auto":
+		target = files
+		if system != nil {
+			target = system
+		}
+	case "system":
+		if system == nil {
+			return nil, fmt.Errorf("no OS secret store is available")
+		}
+		target = system
+	case "file":
+		target = files
+	default:
+		return nil, fmt.Errorf("unknown store %q (want auto, system or file)", kind)
+	}
+	account := authAccount(opts)
+	if err := target.Set(account, token); err != nil {
+		if kind != "auto" || target == secretStore(files) {
+			return nil, err
+		}
+		if opts.Verbose {
+			fmt.Fprintf(os.Stderr, "%s: %v; using a file instead\n", target.Name(), err)
+		}
+		target = files
+		if err := files.Set(account, token); err != nil {
+			return nil, err
+		}
+	}
+	for _, store := range []secretStore{system, files} {
+		if store != nil && store != target {
+			if _, err := store.Delete(account); err != nil && opts.Verbose {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", store.Name(), err)
+			}
+		}
+	}
+	return target, nil
+}
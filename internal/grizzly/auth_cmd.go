@@ -0,0 +1,257 @@
+package grizzly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"grizzly/pkg/bear"
+)
+
+// authStatus describes where the token comes from. It never holds the
+// token itself.
+type authStatus struct {
+	Profile  string `json:"profile"`
+	LoggedIn bool   `json:"logged_in"`
+	Source   string `json:"source,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Command  string `json:"command,omitempty"`
+	Verified *bool  `json:"verified,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type authLogoutReport struct {
+	Profile string   `json:"profile"`
+	Removed []string `json:"removed"`
+}
+
+func (s *authStatus) records() recordSet { return singleRecord(s) }
+
+func (s *authStatus) writeHuman(w io.Writer, style termStyle) {
+	if !s.LoggedIn {
+		fmt.Fprintf(w, "%s for profile %s\n", style.paint(ansiRed, "Not logged in"), s.Profile)
+		if s.Error != "" {
+			fmt.Fprintf(w, "  %s\n", s.Error)
+		}
+		return
+	}
+	source := s.Source
+	if s.Path != "" {
+		source += " " + s.Path
+	} else if s.Command != "" {
+		source += " " + strconv.Quote(s.Command)
+	}
+	fmt.Fprintf(w, "Profile %s uses the token from %s\n", s.Profile, source)
+	switch {
+	case s.Verified == nil:
+	case *s.Verified:
+		fmt.Fprintln(w, style.paint(ansiGreen, "Bear accepted the token"))
+	default:
+		fmt.Fprintf(w, "%s: %s\n", style.paint(ansiRed, "Could not verify the token"), s.Error)
+	}
+}
+
+func (r *authLogoutReport) records() recordSet { return singleRecord(r) }
+
+func (r *authLogoutReport) writeHuman(w io.Writer, style termStyle) {
+	if len(r.Removed) == 0 {
+		fmt.Fprintf(w, "No saved token for profile %s\n", r.Profile)
+		return
+	}
+	fmt.Fprintf(w, "Removed the token for profile %s from %s\n", r.Profile, strings.Join(r.Removed, " and "))
+}
+
+func newAuthCmd(opts *Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Store and check the Bear API token",
+		Long: `Store and check the Bear API token.
+
+"auth login" keeps the token in the OS secret store (the macOS keychain or
+the Secret Service via secret-tool) when one is available, and otherwise in
+a 0600 file next to the user config. Each profile has its own token. A
+--token-file, --token-stdin, token_file or token_command setting takes
+precedence over the stored token.`,
+	}
+	cmd.AddCommand(newAuthLoginCmd(opts))
+	cmd.AddCommand(newAuthStatusCmd(opts))
+	cmd.AddCommand(newAuthLogoutCmd(opts))
+	return cmd
+}
+
+func newAuthLoginCmd(opts *Options) *cobra.Command {
+	var store string
+	var noVerify bool
+
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Save the Bear API token",
+		Long: `Save the Bear API token.
+
+The token is read from the terminal without echo, or from stdin when stdin is
+not a terminal. It is checked by listing tags before it is saved; use
+--no-verify when Bear cannot answer.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var token string
+			var err error
+			if opts.TokenStdin || !stdinIsTTY() {
+				token, err = readTokenFromStdin()
+			} else if opts.NoInput {
+				return usageError(cmd, "--no-input: pipe the token on stdin")
+			} else {
+				token, err = promptToken()
+			}
+			if err != nil {
+				return &ExitError{Code: ExitFailure, Err: err}
+			}
+			if token == "" {
+				return usageError(cmd, "empty token")
+			}
+
+			out := NewOutputter(opts)
+			status := &authStatus{Profile: authAccount(opts), LoggedIn: true}
+			if !noVerify {
+				if err := verifyToken(opts, token); err != nil {
+					return writeClientError(out, "auth-login", err)
+				}
+				verified := true
+				status.Verified = &verified
+			}
+			saved, err := saveToken(opts, token, store)
+			if err != nil {
+				return out.WriteError(Result{Action: "auth-login"}, ErrorInfo{Message: err.Error(), Code: "token_store"}, ExitFailure)
+			}
+			status.Source = saved.Name()
+			if files, ok := saved.(fileStore); ok {
+				status.Path = files.path(status.Profile)
+			}
+			if opts.TokenFile != "" {
+				fmt.Fprintf(os.Stderr, "warning: token file %s takes precedence over the saved token\n", opts.TokenFile)
+			} else if opts.TokenCommand != "" {
+				fmt.Fprintln(os.Stderr, "warning: token_command takes precedence over the saved token")
+			}
+			out.WriteSuccess(Result{Action: "auth-login", Data: status})
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&store, "store", "auto", "Where to save the token: auto, system or file")
+	cmd.Flags().BoolVar(&noVerify, "no-verify", false, "Save the token without checking it with Bear")
+	_ = cmd.RegisterFlagCompletionFunc("store", cobra.FixedCompletions([]string{"auto", "system", "file"}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func newAuthStatusCmd(opts *Options) *cobra.Command {
+	var noVerify bool
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show where the token comes from and whether Bear accepts it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := NewOutputter(opts)
+			status := &authStatus{Profile: authAccount(opts)}
+			token, err := resolveToken(opts)
+			switch {
+			case err != nil:
+				status.Error = bear.RedactURL(err.Error())
+			case token != "":
+				status.Source = "token file"
+				status.Path = opts.TokenFile
+				if opts.TokenFile == "" {
+					status.Source = "stdin"
+				}
+			case opts.TokenCommand != "":
+				status.Source = "token command"
+				status.Command = opts.TokenCommand
+				if token, err = runTokenCommand(opts.TokenCommand); err != nil {
+					status.Error = bear.RedactURL(err.Error())
+				}
+			default:
+				var store secretStore
+				token, store, err = storedToken(opts)
+				if err != nil {
+					status.Error = bear.RedactURL(err.Error())
+				} else if store != nil {
+					status.Source = store.Name()
+					if files, ok := store.(fileStore); ok {
+						status.Path = files.path(status.Profile)
+					}
+				}
+			}
+			status.LoggedIn = token != ""
+			if !status.LoggedIn {
+				out.WriteSuccess(Result{Action: "auth-status", Data: status})
+				return &ExitError{Code: ExitFailure, Err: errors.New("not logged in")}
+			}
+			if noVerify {
+				out.WriteSuccess(Result{Action: "auth-status", Data: status})
+				return nil
+			}
+			err = verifyToken(opts, token)
+			verified := err == nil
+			status.Verified = &verified
+			if err != nil {
+				status.Error = bear.RedactURL(err.Error())
+			}
+			out.WriteSuccess(Result{Action: "auth-status", Data: status})
+			if err != nil {
+				code := ExitFailure
+				var be *bear.Error
+				if errors.As(err, &be) {
+					code = exitCodeForError(be)
+				}
+				return &ExitError{Code: code, Err: err}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&noVerify, "no-verify", false, "Do not check the token with Bear")
+	return cmd
+}
+
+func newAuthLogoutCmd(opts *Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout",
+		Short: "Remove the saved token",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := NewOutputter(opts)
+			stores, err := authStores()
+			if err != nil {
+				return &ExitError{Code: ExitFailure, Err: err}
+			}
+			report := &authLogoutReport{Profile: authAccount(opts), Removed: []string{}}
+			for _, store := range stores {
+				removed, err := store.Delete(report.Profile)
+				if err != nil {
+					return out.WriteError(Result{Action: "auth-logout"}, ErrorInfo{Message: err.Error(), Code: "token_store"}, ExitFailure)
+				}
+				if removed {
+					report.Removed = append(report.Removed, store.Name())
+				}
+			}
+			if opts.TokenFile != "" {
+				fmt.Fprintf(os.Stderr, "warning: token file %s is still configured\n", opts.TokenFile)
+			}
+			out.WriteSuccess(Result{Action: "auth-logout", Data: report})
+			return nil
+		},
+	}
+}
+
+// verifyToken makes a harmless tags call to check that Bear accepts token.
+func verifyToken(opts *Options, token string) error {
+	client, done, err := newSessionClient(opts, token)
+	if err != nil {
+		return fmt.Errorf("cannot verify the token: %w (use --no-verify)", err)
+	}
+	defer done()
+	ctx, cancel := withCallTimeout(context.Background(), opts)
+	defer cancel()
+	_, err = client.Tags(ctx)
+	return err
+}
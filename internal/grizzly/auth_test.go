@@ -0,0 +1,172 @@
+package grizzly
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadTokenFromFileRejectsWorldReadable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("secret\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := readTokenFromFile(path); err == nil || !strings.Contains(err.Error(), "chmod 600") {
+		t.Fatalf("world-readable token file: %v", err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	if token, err := readTokenFromFile(path); err != nil || token != "secret" {
+		t.Fatalf("token = %q, %v", token, err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	store := fileStore{dir: filepath.Join(t.TempDir(), "grizzly")}
+	if token, err := store.Get("work"); err != nil || token != "" {
+		t.Fatalf("empty store = %q, %v", token, err)
+	}
+	if err := store.Set("work", "s3cret"); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(store.dir, "token-work"))
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("token file mode: %v, %v", info, err)
+	}
+	if token, err := store.Get("work"); err != nil || token != "s3cret" {
+		t.Fatalf("get = %q, %v", token, err)
+	}
+	if token, _ := store.Get(defaultAuthAccount); token != "" {
+		t.Fatalf("profiles share a token: %q", token)
+	}
+	if removed, err := store.Delete("work"); !removed || err != nil {
+		t.Fatalf("delete = %v, %v", removed, err)
+	}
+	if removed, err := store.Delete("work"); removed || err != nil {
+		t.Fatalf("second delete = %v, %v", removed, err)
+	}
+}
+
+func TestKeychainStoreKeepsTokenOutOfArgs(t *testing.T) {
+	type call struct {
+		stdin string
+		args  []string
+	}
+	var calls []call
+	stored := ""
+	old := runSecretCommand
+	runSecretCommand = func(stdin, name string, args ...string) (string, int, error) {
+		calls = append(calls, call{stdin, args})
+		switch args[0] {
+		case "-i":
+			stored = "tok\"en"
+			return "", 0, nil
+		case "find-generic-password":
+			if stored == "" {
+				return "The specified item could not be found in the keychain.", keychainNotFound, nil
+			}
+			return stored + "\n", 0, nil
+		}
+		return "", 1, nil
+	}
+	defer func() { runSecretCommand = old }()
+
+	store := keychainStore{}
+	if token, err := store.Get("default"); err != nil || token != "" {
+		t.Fatalf("missing item = %q, %v", token, err)
+	}
+	if err := store.Set("default", `tok"en`); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	set := calls[len(calls)-1]
+	if strings.Contains(strings.Join(set.args, " "), "tok") || !strings.Contains(set.stdin, `-w "tok\"en"`) {
+		t.Fatalf("set call = %+v", set)
+	}
+	if token, err := store.Get("default"); err != nil || token != `tok"en` {
+		t.Fatalf("get = %q, %v", token, err)
+	}
+}
+
+func TestAuthCommands(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	t.Setenv("GRIZZLY_SECRET_STORE", "file")
+	t.Setenv("GRIZZLY_OPENER", "fake")
+	store := filepath.Join(root, "bear.json")
+	t.Setenv("GRIZZLY_FAKE_BEAR_STORE", store)
+	for _, name := range []string{"GRIZZLY_PROFILE", "GRIZZLY_TIMEOUT", "GRIZZLY_TOKEN_FILE"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	if err := os.WriteFile(store, []byte(`{"token":"secret","notes":[]}`), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	run := func(stdin string, args ...string) (string, error) {
+		cmd := NewRootCmd()
+		cmd.SetArgs(args)
+		oldIn, oldOut, oldErr := os.Stdin, os.Stdout, os.Stderr
+		inR, inW, _ := os.Pipe()
+		outR, outW, _ := os.Pipe()
+		_, _ = inW.WriteString(stdin)
+		inW.Close()
+		// stdout and stderr share the pipe, so tests see everything printed.
+		os.Stdin, os.Stdout, os.Stderr = inR, outW, outW
+		err := cmd.Execute()
+		outW.Close()
+		os.Stdin, os.Stdout, os.Stderr = oldIn, oldOut, oldErr
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(outR)
+		return buf.String(), err
+	}
+
+	if out, err := run("", "auth", "status"); ExitCode(err) != ExitFailure || !strings.Contains(out, `"logged_in":false`) {
+		t.Fatalf("status before login = %q, %v", out, err)
+	}
+	if out, err := run("wr0ngtoken\n", "auth", "login"); err == nil || strings.Contains(out, "wr0ngtoken") {
+		t.Fatalf("login with a token Bear rejects = %q, %v", out, err)
+	}
+	t.Setenv("GRIZZLY_OPENER_FILE", filepath.Join(root, "urls"))
+	for _, format := range []string{"--json", "--plain", "--print-url"} {
+		out, err := run("supersecret\n", format, "--opener", "file", "--timeout", "50ms", "auth", "login")
+		if ExitCode(err) != ExitTimeout || strings.Contains(out, "supersecret") {
+			t.Fatalf("%s login timing out = %q, %v", format, out, err)
+		}
+	}
+	if out, err := run("", "--opener", "file", "--timeout", "50ms", "--token-file", writeToken(t, "supersecret"), "auth", "status"); err == nil || strings.Contains(out, "supersecret") {
+		t.Fatalf("status timing out = %q, %v", out, err)
+	}
+	t.Setenv("GRIZZLY_OPENER_FILE", "")
+	os.Unsetenv("GRIZZLY_OPENER_FILE")
+	if _, err := os.Stat(filepath.Join(root, "grizzly", "token")); !os.IsNotExist(err) {
+		t.Fatalf("rejected token was saved: %v", err)
+	}
+	if out, err := run("secret\n", "auth", "login"); err != nil || !strings.Contains(out, `"verified":true`) {
+		t.Fatalf("login = %q, %v", out, err)
+	}
+	out, err := run("", "auth", "status")
+	if err != nil || !strings.Contains(out, `"source":"file"`) || strings.Contains(out, "secret") {
+		t.Fatalf("status = %q, %v", out, err)
+	}
+	if out, err := run("", "tags"); err != nil || !strings.Contains(out, `"ok":true`) {
+		t.Fatalf("tags with the saved token = %q, %v", out, err)
+	}
+	if out, err := run("", "auth", "logout"); err != nil || !strings.Contains(out, `"removed":["file"]`) {
+		t.Fatalf("logout = %q, %v", out, err)
+	}
+	if _, err := run("", "tags"); ExitCode(err) != ExitUsage {
+		t.Fatalf("tags after logout: %v", err)
+	}
+}
+
+func writeToken(t *testing.T, token string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	return path
+}
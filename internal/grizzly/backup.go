@@ -0,0 +1,466 @@
+package grizzly
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"grizzly/pkg/bear"
+)
+
+const (
+	backupFormat       = "grizzly-backup"
+	backupVersion      = 1
+	backupManifestName = "manifest.json"
+)
+
+// backupMaxEntrySize bounds each file read from an archive, so a crafted
+// archive cannot exhaust memory.
+var backupMaxEntrySize = 64 << 20
+
+type backupManifest struct {
+	Format  string        `json:"format"`
+	Version int           `json:"version"`
+	Created time.Time     `json:"created"`
+	Notes   []backupEntry `json:"notes"`
+}
+
+type backupEntry struct {
+	Identifier string    `json:"identifier"`
+	Title      string    `json:"title"`
+	Path       string    `json:"path"`
+	SHA256     string    `json:"sha256"`
+	Tags       []string  `json:"tags,omitempty"`
+	Created    time.Time `json:"created"`
+	Modified   time.Time `json:"modified"`
+	Pinned     bool      `json:"pinned,omitempty"`
+}
+
+type backupReport struct {
+	Archive string        `json:"archive"`
+	Notes   int           `json:"notes"`
+	Failed  []backupError `json:"failed,omitempty"`
+}
+
+type backupError struct {
+	Identifier string `json:"identifier"`
+	Title      string `json:"title"`
+	Error      string `json:"error"`
+}
+
+type restoredNote struct {
+	Identifier    string `json:"identifier"`
+	Title         string `json:"title"`
+	Action        string `json:"action"`
+	NewIdentifier string `json:"new_identifier,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+type restoreReport struct {
+	Archive     string         `json:"archive"`
+	DryRun      bool           `json:"dry_run,omitempty"`
+	Created     int            `json:"created"`
+	Overwritten int            `json:"overwritten"`
+	Unchanged   int            `json:"unchanged"`
+	Skipped     int            `json:"skipped"`
+	Trashed     int            `json:"trashed"`
+	Failed      int            `json:"failed"`
+	Notes       []restoredNote `json:"notes"`
+}
+
+func newBackupCmd(opts *Options) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Save every note to a compressed archive",
+		Long: `Save every note to a compressed archive (.tar.gz).
+
+Notes are listed with search and fetched with open-note. The archive holds one
+Markdown file per note with YAML front matter and a manifest with each note's
+metadata and SHA-256 checksum. Restore it with "grizzly restore".`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output == "" {
+				output = "grizzly-backup-" + time.Now().Format("20060102-150405") + ".tar.gz"
+			}
+			path, err := expandPath(output)
+			if err != nil {
+				return &ExitError{Code: ExitUsage, Err: err}
+			}
+			token, err := resolveToken(opts)
+			if err != nil {
+				return writeTokenError(opts, "backup", err)
+			}
+			out := NewOutputter(opts)
+			client, done, err := newSessionClient(opts, token)
+			if err != nil {
+				return usageError(cmd, "%v", err)
+			}
+			defer done()
+
+			report, err := runBackup(cmd.Context(), opts, client, path)
+			if err != nil {
+				return writeClientError(out, "backup", err)
+			}
+			out.WriteSuccess(Result{Action: "backup", Data: report})
+			if len(report.Failed) > 0 {
+				return &ExitError{Code: ExitFailure, Err: fmt.Errorf("%d notes could not be backed up", len(report.Failed))}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Archive path (default: grizzly-backup-<time>.tar.gz)")
+	return cmd
+}
+
+func runBackup(ctx context.Context, opts *Options, client *bear.Client, archive string) (*backupReport, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	listCtx, cancel := withCallTimeout(ctx, opts)
+	list, err := client.Search(listCtx, bear.SearchOptions{NoShowWindow: true})
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &backupReport{Archive: archive}
+	manifest := backupManifest{Format: backupFormat, Version: backupVersion, Created: time.Now().UTC(), Notes: []backupEntry{}}
+	files := map[string][]byte{}
+	for _, summary := range list.Notes {
+		callCtx, cancel := withCallTimeout(ctx, opts)
+		res, err := client.OpenNote(callCtx, bear.OpenNoteOptions{
+			NoteRef: bear.NoteRef{ID: summary.Identifier},
+			Window:  bear.Window{NoShowWindow: true, NoOpen: true},
+		})
+		cancel()
+		if err != nil {
+			report.Failed = append(report.Failed, backupError{Identifier: summary.Identifier, Title: summary.Title, Error: err.Error()})
+			continue
+		}
+		note := &res.Note
+		data, err := renderNoteFile(frontMatterFor(note), note.Text)
+		if err != nil {
+			return nil, err
+		}
+		name := path.Join("notes", sanitizeFilename(note.Identifier)+".md")
+		files[name] = data
+		manifest.Notes = append(manifest.Notes, backupEntry{
+			Identifier: note.Identifier,
+			Title:      note.Title,
+			Path:       name,
+			SHA256:     hashBytes(data),
+			Tags:       note.Tags,
+			Created:    note.CreationDate,
+			Modified:   note.ModificationDate,
+			Pinned:     note.Pinned,
+		})
+	}
+	report.Notes = len(manifest.Notes)
+	if err := writeBackupArchive(archive, manifest, files); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// writeBackupArchive writes the manifest first so readers can check the
+// format before reading notes. The file is renamed into place when complete.
+func writeBackupArchive(archive string, manifest backupManifest, files map[string][]byte) error {
+	tmp := archive + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		f.Close()
+		return err
+	}
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: manifest.Created, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	if err := add(backupManifestName, append(manifestData, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	for _, entry := range manifest.Notes {
+		if err := add(entry.Path, files[entry.Path]); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	for _, c := range []io.Closer{tw, gz, f} {
+		if err := c.Close(); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp, archive)
+}
+
+// readBackupArchive loads an archive and verifies every note's checksum.
+func readBackupArchive(archive string) (backupManifest, map[string][]byte, error) {
+	var manifest backupManifest
+	f, err := os.Open(archive)
+	if err != nil {
+		return manifest, nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return manifest, nil, fmt.Errorf("%s: %w", archive, err)
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return manifest, nil, fmt.Errorf("%s: %w", archive, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, io.LimitReader(tr, int64(backupMaxEntrySize)+1)); err != nil {
+			return manifest, nil, err
+		}
+		if buf.Len() > backupMaxEntrySize {
+			return manifest, nil, fmt.Errorf("%s: %s is larger than %d bytes", archive, hdr.Name, backupMaxEntrySize)
+		}
+		files[hdr.Name] = buf.Bytes()
+	}
+
+	data, ok := files[backupManifestName]
+	if !ok {
+		return manifest, nil, fmt.Errorf("%s: missing %s", archive, backupManifestName)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, nil, fmt.Errorf("%s: %w", backupManifestName, err)
+	}
+	if manifest.Format != backupFormat {
+		return manifest, nil, fmt.Errorf("%s is not a grizzly backup", archive)
+	}
+	if manifest.Version > backupVersion {
+		return manifest, nil, fmt.Errorf("backup version %d is newer than this grizzly supports (%d)", manifest.Version, backupVersion)
+	}
+	for _, entry := range manifest.Notes {
+		content, ok := files[entry.Path]
+		if !ok {
+			return manifest, nil, fmt.Errorf("%s: missing %s", archive, entry.Path)
+		}
+		sum := sha256.Sum256(content)
+		if hex.EncodeToString(sum[:]) != entry.SHA256 {
+			return manifest, nil, fmt.Errorf("%s: checksum mismatch for %s", archive, entry.Path)
+		}
+	}
+	return manifest, files, nil
+}
+
+func newRestoreCmd(opts *Options) *cobra.Command {
+	var ids []string
+
+	cmd := &cobra.Command{
+		Use:   "restore <archive>",
+		Short: "Recreate notes from a backup archive",
+		Long: `Recreate notes from a backup archive.
+
+By default, notes Bear reports as not found are created again. Notes in
+Bear's trash are reported as trashed and left alone; restore them from the
+trash in Bear first. With --id, the given notes are rolled back to their
+backed-up text, overwriting the current content (or created if missing). With
+--dry-run Bear is only read, and the report shows what would be created or
+overwritten.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			archive, err := expandPath(args[0])
+			if err != nil {
+				return &ExitError{Code: ExitUsage, Err: err}
+			}
+			manifest, files, err := readBackupArchive(archive)
+			if err != nil {
+				return &ExitError{Code: ExitFailure, Err: err}
+			}
+			selected, err := selectBackupEntries(manifest, ids)
+			if err != nil {
+				return usageError(cmd, "%v", err)
+			}
+			if len(ids) > 0 && !opts.DryRun {
+				if err := ensureForceOrPrompt(opts, fmt.Sprintf("Overwrite %d note(s) with the backed-up text? [y/N]: ", len(selected))); err != nil {
+					return &ExitError{Code: ExitFailure, Err: err}
+				}
+			}
+
+			token, err := resolveToken(opts)
+			if err != nil {
+				return writeTokenError(opts, "restore", err)
+			}
+			// A dry run still reads from Bear to tell what would change.
+			sessionOpts := *opts
+			sessionOpts.DryRun = false
+			out := NewOutputter(opts)
+			client, done, err := newSessionClient(&sessionOpts, token)
+			if err != nil {
+				return usageError(cmd, "%v", err)
+			}
+			defer done()
+
+			r := &restorer{opts: opts, client: client, files: files, rollback: len(ids) > 0}
+			report := r.run(cmd.Context(), selected)
+			report.Archive = archive
+			out.WriteSuccess(Result{Action: "restore", Data: report})
+			if report.Failed > 0 {
+				return &ExitError{Code: ExitFailure, Err: fmt.Errorf("%d notes failed to restore", report.Failed)}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVar(&ids, "id", nil, "Roll back this note (repeatable)")
+	return cmd
+}
+
+func selectBackupEntries(manifest backupManifest, ids []string) ([]backupEntry, error) {
+	if len(ids) == 0 {
+		return manifest.Notes, nil
+	}
+	byID := map[string]backupEntry{}
+	for _, entry := range manifest.Notes {
+		byID[entry.Identifier] = entry
+	}
+	selected := make([]backupEntry, 0, len(ids))
+	for _, id := range ids {
+		entry, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("note %s is not in the backup", id)
+		}
+		selected = append(selected, entry)
+	}
+	return selected, nil
+}
+
+type restorer struct {
+	opts     *Options
+	client   *bear.Client
+	files    map[string][]byte
+	rollback bool
+}
+
+func (r *restorer) run(ctx context.Context, entries []backupEntry) *restoreReport {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	report := &restoreReport{DryRun: r.opts.DryRun, Notes: []restoredNote{}}
+	for _, entry := range entries {
+		note := r.restore(ctx, entry)
+		switch note.Action {
+		case "created", "would-create":
+			report.Created++
+		case "overwritten", "would-overwrite":
+			report.Overwritten++
+		case "unchanged":
+			report.Unchanged++
+		case "skipped":
+			report.Skipped++
+		case "trashed":
+			report.Trashed++
+		default:
+			report.Failed++
+		}
+		report.Notes = append(report.Notes, note)
+	}
+	return report
+}
+
+func (r *restorer) restore(ctx context.Context, entry backupEntry) restoredNote {
+	result := restoredNote{Identifier: entry.Identifier, Title: entry.Title}
+	meta, body, err := parseNoteFile(r.files[entry.Path])
+	if err != nil {
+		return failRestore(result, err)
+	}
+	body = fullNoteText(entry.Path, meta, body)
+
+	callCtx, cancel := withCallTimeout(ctx, r.opts)
+	current, err := r.client.OpenNote(callCtx, bear.OpenNoteOptions{
+		NoteRef: bear.NoteRef{ID: entry.Identifier},
+		Window:  bear.Window{NoShowWindow: true, NoOpen: true},
+	})
+	cancel()
+	missing := bear.IsNoteNotFound(err)
+	if err != nil && !missing {
+		return failRestore(result, err)
+	}
+
+	switch {
+	case !missing && current.Note.IsTrashed:
+		// Bear has no action to take a note out of the trash, and a new note
+		// would leave the original behind under a different identifier.
+		result.Action = "trashed"
+	case missing && r.opts.DryRun:
+		result.Action = "would-create"
+	case missing:
+		callCtx, cancel := withCallTimeout(ctx, r.opts)
+		created, err := r.client.Create(callCtx, bear.CreateOptions{
+			Window: bear.Window{NoShowWindow: true, NoOpen: true},
+			Text:   body,
+			Tags:   tagsNotInText(body, meta.Tags),
+			Pin:    meta.Pinned,
+		})
+		cancel()
+		if err != nil {
+			return failRestore(result, err)
+		}
+		result.Action = "created"
+		result.NewIdentifier = created.Identifier
+	case sameText(current.Note.Text, body):
+		result.Action = "unchanged"
+	case !r.rollback:
+		result.Action = "skipped"
+	case r.opts.DryRun:
+		result.Action = "would-overwrite"
+	default:
+		callCtx, cancel := withCallTimeout(ctx, r.opts)
+		_, err := r.client.AddText(callCtx, bear.AddTextOptions{
+			NoteRef: bear.NoteRef{ID: entry.Identifier},
+			Window:  bear.Window{NoShowWindow: true, NoOpen: true},
+			Text:    body,
+			Mode:    bear.ModeReplaceAll,
+		})
+		cancel()
+		if err != nil {
+			return failRestore(result, err)
+		}
+		result.Action = "overwritten"
+	}
+	return result
+}
+
+func failRestore(note restoredNote, err error) restoredNote {
+	note.Action = "failed"
+	note.Error = err.Error()
+	return note
+}
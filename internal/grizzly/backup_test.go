@@ -0,0 +1,101 @@
+package grizzly
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	fb, client := newFakeSession(t)
+	keep := fakeCall(t, fb, "create", url.Values{"title": {"Keep"}, "text": {"original"}}).Get("identifier")
+	lost := fakeCall(t, fb, "create", url.Values{"title": {"Lost"}, "text": {"gone soon"}, "tags": {"old"}}).Get("identifier")
+
+	opts := &Options{Timeout: 2 * time.Second}
+	archive := filepath.Join(t.TempDir(), "b.tar.gz")
+	report, err := runBackup(context.Background(), opts, client, archive)
+	if err != nil {
+		t.Fatalf("runBackup: %v", err)
+	}
+	if report.Notes != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	manifest, files, err := readBackupArchive(archive)
+	if err != nil {
+		t.Fatalf("readBackupArchive: %v", err)
+	}
+
+	fakeCall(t, fb, "add-text", url.Values{"id": {keep}, "text": {"# Keep\nclobbered"}, "mode": {"replace_all"}})
+	fakeCall(t, fb, "trash", url.Values{"id": {lost}})
+
+	// A trashed note is reported, not duplicated under a new identifier.
+	trashed := (&restorer{opts: opts, client: client, files: files}).run(context.Background(), manifest.Notes)
+	if trashed.Trashed != 1 || trashed.Created != 0 || trashed.Skipped != 1 {
+		t.Fatalf("trashed restore = %+v", trashed)
+	}
+	for i, note := range fb.state.Notes {
+		if note.Identifier == lost {
+			fb.state.Notes = append(fb.state.Notes[:i], fb.state.Notes[i+1:]...)
+			break
+		}
+	}
+
+	dryOpts := &Options{Timeout: 2 * time.Second, DryRun: true}
+	dry := (&restorer{opts: dryOpts, client: client, files: files}).run(context.Background(), manifest.Notes)
+	if dry.Created != 1 || dry.Skipped != 1 {
+		t.Fatalf("dry run = %+v", dry)
+	}
+	if fakeCall(t, fb, "search", url.Values{"term": {"gone soon"}, "token": {"x"}}).Get("notes") != "[]" {
+		t.Fatalf("dry run created a note")
+	}
+
+	res := (&restorer{opts: opts, client: client, files: files}).run(context.Background(), manifest.Notes)
+	if res.Created != 1 || res.Skipped != 1 {
+		t.Fatalf("restore = %+v", res)
+	}
+	if notes := fakeCall(t, fb, "search", url.Values{"term": {"gone soon"}, "token": {"x"}}).Get("notes"); !strings.Contains(notes, "Lost") {
+		t.Fatalf("lost note not recreated: %s", notes)
+	}
+	for _, note := range fb.state.Notes {
+		if note.title() == "Lost" && strings.Count(note.Text, "#old") != 1 {
+			t.Fatalf("recreated note = %q", note.Text)
+		}
+	}
+
+	selected, err := selectBackupEntries(manifest, []string{keep})
+	if err != nil {
+		t.Fatalf("selectBackupEntries: %v", err)
+	}
+	res = (&restorer{opts: opts, client: client, files: files, rollback: true}).run(context.Background(), selected)
+	if res.Overwritten != 1 {
+		t.Fatalf("rollback = %+v", res)
+	}
+	if got := fakeCall(t, fb, "open-note", url.Values{"id": {keep}}).Get("note"); got != "# Keep\noriginal" {
+		t.Fatalf("rolled back note = %q", got)
+	}
+
+	// A note Bear cannot open, but that exists, is not recreated.
+	fb.noteByID(keep).Locked = true
+	res = (&restorer{opts: opts, client: client, files: files}).run(context.Background(), selected)
+	if res.Failed != 1 || res.Created != 0 {
+		t.Fatalf("locked restore = %+v", res)
+	}
+
+	files[manifest.Notes[0].Path] = []byte("tampered")
+	tampered := filepath.Join(t.TempDir(), "t.tar.gz")
+	if err := writeBackupArchive(tampered, manifest, files); err != nil {
+		t.Fatalf("writeBackupArchive: %v", err)
+	}
+	if _, _, err := readBackupArchive(tampered); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("tampered archive err = %v", err)
+	}
+
+	defer func(limit int) { backupMaxEntrySize = limit }(backupMaxEntrySize)
+	backupMaxEntrySize = 4
+	if _, _, err := readBackupArchive(archive); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Fatalf("oversized entry err = %v", err)
+	}
+}
@@ -0,0 +1,243 @@
+package grizzly
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"grizzly/pkg/bear"
+)
+
+type batchItem struct {
+	Action string                     `json:"action"`
+	Params map[string]json.RawMessage `json:"params"`
+}
+
+type batchRecord struct {
+	Line     int        `json:"line"`
+	OK       bool       `json:"ok"`
+	Action   string     `json:"action,omitempty"`
+	URL      string     `json:"url,omitempty"`
+	Data     any        `json:"data,omitempty"`
+	Error    *ErrorInfo `json:"error,omitempty"`
+	ExitCode int        `json:"exit_code"`
+}
+
+type batchSummary struct {
+	Summary   bool `json:"summary"`
+	Total     int  `json:"total"`
+	Succeeded int  `json:"succeeded"`
+	Failed    int  `json:"failed"`
+	Skipped   int  `json:"skipped"`
+}
+
+type batchRunner struct {
+	opts     *Options
+	client   *bear.Client
+	failFast bool
+}
+
+func newBatchCmd(opts *Options) *cobra.Command {
+	var file string
+	var failFast bool
+	var summary bool
+
+	cmd := &cobra.Command{
+		Use:   "batch",
+		Short: "Run actions from a JSON Lines script",
+		Long: `Run actions from a JSON Lines script, one per line:
+
+  {"action":"add-text","params":{"title":"Inbox","text":"hello","mode":"append"}}
+
+Params are Bear's own parameter names. Booleans become yes/no and arrays are
+joined with commas. One JSON result is written per input line.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				file = "-"
+			}
+			if err := ensureNoStdinConflict(opts.TokenStdin, file == "-"); err != nil {
+				return &ExitError{Code: ExitUsage, Err: err}
+			}
+			var input io.Reader = os.Stdin
+			if file != "-" {
+				path, err := expandPath(file)
+				if err != nil {
+					return &ExitError{Code: ExitUsage, Err: err}
+				}
+				f, err := os.Open(path)
+				if err != nil {
+					return &ExitError{Code: ExitUsage, Err: err}
+				}
+				defer f.Close()
+				input = f
+			}
+
+			token, err := resolveToken(opts)
+			if err != nil {
+				return writeTokenError(opts, "batch", err)
+			}
+			client, err := newClient(opts, token)
+			if err != nil {
+				return usageError(cmd, "%v", err)
+			}
+			if shared := shareCallbacks(client); shared != nil {
+				defer shared.Close()
+			}
+
+			runner := &batchRunner{opts: opts, client: client, failFast: failFast}
+			sum, err := runner.run(cmd.Context(), input, os.Stdout)
+			if err != nil {
+				return &ExitError{Code: ExitFailure, Err: err}
+			}
+			if summary {
+				_ = json.NewEncoder(os.Stdout).Encode(sum)
+			}
+			if sum.Failed > 0 {
+				return &ExitError{Code: ExitFailure, Err: fmt.Errorf("%d of %d actions failed", sum.Failed, sum.Total)}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&file, "file", "-", "JSON Lines script (- for stdin)")
+	cmd.Flags().BoolVar(&failFast, "fail-fast", false, "Stop at the first failed action")
+	cmd.Flags().BoolVar(&summary, "summary", false, "Write a final summary record")
+	return cmd
+}
+
+// shareCallbacks swaps the client's per-request callback listener for one
+// shared listener, returning it so the caller can close it.
+func shareCallbacks(client *bear.Client) *bear.SharedCallbacks {
+	transport, ok := client.Transport.(*bear.URLTransport)
+	if !ok {
+		return nil
+	}
+	local, ok := transport.Callbacks.(bear.LocalCallbacks)
+	if !ok {
+		return nil
+	}
+	shared := &bear.SharedCallbacks{Scheme: local.Scheme, Source: local.Source, OnReject: local.OnReject}
+	transport.Callbacks = shared
+	return shared
+}
+
+func (b *batchRunner) run(ctx context.Context, r io.Reader, w io.Writer) (batchSummary, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	sum := batchSummary{Summary: true}
+	enc := json.NewEncoder(w)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	lineNo := 0
+	stopped := false
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sum.Total++
+		if stopped {
+			sum.Skipped++
+			continue
+		}
+		rec := b.runLine(ctx, lineNo, line)
+		if err := enc.Encode(rec); err != nil {
+			return sum, err
+		}
+		if rec.OK {
+			sum.Succeeded++
+			continue
+		}
+		sum.Failed++
+		if b.failFast {
+			stopped = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return sum, err
+	}
+	return sum, nil
+}
+
+func (b *batchRunner) runLine(ctx context.Context, lineNo int, line string) batchRecord {
+	rec := batchRecord{Line: lineNo}
+	var item batchItem
+	if err := json.Unmarshal([]byte(line), &item); err != nil {
+		return failRecord(rec, "invalid_input", err.Error(), ExitUsage)
+	}
+	rec.Action = item.Action
+	if strings.TrimSpace(item.Action) == "" {
+		return failRecord(rec, "invalid_input", "missing action", ExitUsage)
+	}
+	params, err := batchParams(item.Params)
+	if err != nil {
+		return failRecord(rec, "invalid_input", err.Error(), ExitUsage)
+	}
+
+	ctx, cancel := withCallTimeout(ctx, b.opts)
+	defer cancel()
+	resp, err := b.client.Do(ctx, item.Action, params)
+	if err != nil {
+		var be *bear.Error
+		if !errors.As(err, &be) {
+			return failRecord(rec, "invalid_request", err.Error(), ExitUsage)
+		}
+		rec.URL = bear.RedactURL(be.URL)
+		return failRecord(rec, be.Code, be.Message, exitCodeForError(be))
+	}
+	rec.OK = true
+	rec.URL = bear.RedactURL(resp.URL)
+	if len(resp.Values) > 0 {
+		rec.Data = ParseCallbackValues(resp.Values)
+	}
+	return rec
+}
+
+func failRecord(rec batchRecord, code, message string, exitCode int) batchRecord {
+	rec.OK = false
+	rec.Error = &ErrorInfo{Message: message, Code: code}
+	rec.ExitCode = exitCode
+	return rec
+}
+
+func batchParams(raw map[string]json.RawMessage) (url.Values, error) {
+	params := url.Values{}
+	for key, value := range raw {
+		var decoded any
+		if err := json.Unmarshal(value, &decoded); err != nil {
+			return nil, fmt.Errorf("param %s: %w", key, err)
+		}
+		switch typed := decoded.(type) {
+		case nil:
+		case string:
+			params.Set(key, typed)
+		case bool:
+			params.Set(key, yesNo(typed))
+		case float64:
+			params.Set(key, strconv.FormatFloat(typed, 'f', -1, 64))
+		case []any:
+			parts := make([]string, 0, len(typed))
+			for _, part := range typed {
+				s, ok := part.(string)
+				if !ok {
+					return nil, fmt.Errorf("param %s: arrays must contain strings", key)
+				}
+				parts = append(parts, s)
+			}
+			params.Set(key, strings.Join(parts, ","))
+		default:
+			return nil, fmt.Errorf("param %s: unsupported value %s", key, string(value))
+		}
+	}
+	return params, nil
+}
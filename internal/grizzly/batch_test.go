@@ -0,0 +1,86 @@
+package grizzly
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"grizzly/pkg/bear"
+)
+
+func TestBatchRunnerSharesListener(t *testing.T) {
+	fb, err := NewFakeBear("")
+	if err != nil {
+		t.Fatalf("NewFakeBear: %v", err)
+	}
+	shared := &bear.SharedCallbacks{Scheme: "http"}
+	defer shared.Close()
+	client := &bear.Client{
+		Transport: &bear.URLTransport{Opener: fb, Callbacks: shared},
+		Token:     bear.StaticToken("secret"),
+	}
+	runner := &batchRunner{opts: &Options{Timeout: 2 * time.Second}, client: client, failFast: true}
+
+	script := strings.Join([]string{
+		`{"action":"create","params":{"title":"Inbox","tags":["work"]}}`,
+		`{"action":"add-text","params":{"title":"Inbox","text":"hi","new_line":true}}`,
+		`{"action":"tags"}`,
+		`{"action":"open-note","params":{"id":"missing"}}`,
+		`{"action":"tags"}`,
+	}, "\n")
+	out := &bytes.Buffer{}
+	sum, err := runner.run(context.Background(), strings.NewReader(script), out)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if sum.Total != 5 || sum.Succeeded != 3 || sum.Failed != 1 || sum.Skipped != 1 {
+		t.Fatalf("summary = %+v", sum)
+	}
+
+	var records []batchRecord
+	dec := json.NewDecoder(out)
+	for dec.More() {
+		var rec batchRecord
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		records = append(records, rec)
+	}
+	if len(records) != 4 {
+		t.Fatalf("records = %d", len(records))
+	}
+	if strings.Contains(records[2].URL, "secret") || !strings.Contains(records[2].URL, "token=REDACTED") {
+		t.Fatalf("tags url not redacted: %s", records[2].URL)
+	}
+	first, _ := records[0].Data.(map[string]any)
+	second, _ := records[1].Data.(map[string]any)
+	if first["title"] != "Inbox" || second["note"] != "# Inbox\n#work\nhi" {
+		t.Fatalf("data = %#v / %#v", records[0].Data, records[1].Data)
+	}
+	if records[3].OK || records[3].ExitCode != ExitCallback || records[3].Line != 4 {
+		t.Fatalf("failure record = %+v", records[3])
+	}
+}
+
+func TestBatchParams(t *testing.T) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(`{"title":"x","pin":true,"show_window":false,"tags":["a","b"],"n":3}`), &raw); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	params, err := batchParams(raw)
+	if err != nil {
+		t.Fatalf("batchParams: %v", err)
+	}
+	if params.Get("pin") != "yes" || params.Get("show_window") != "no" || params.Get("tags") != "a,b" || params.Get("n") != "3" {
+		t.Fatalf("params = %v", params)
+	}
+	if err := json.Unmarshal([]byte(`{"x":{"nested":1}}`), &raw); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if _, err := batchParams(raw); err == nil {
+		t.Fatalf("expected error for object param")
+	}
+}
@@ -1,88 +1,40 @@
 package grizzly
 
 import (
-	"context"
 	"fmt"
-	"net"
 	"net/http"
 	"net/url"
-	"sync"
-	"time"
+	"os"
+
+	"grizzly/pkg/bear"
 )
 
-type CallbackResult struct {
-	Success bool
-	Values  url.Values
-}
+const callbackScheme = "gzlcb"
+
+type CallbackResult = bear.CallbackResult
 
 type CallbackServer struct {
-	BaseURL    string
-	SuccessURL string
-	ErrorURL   string
-	results    chan CallbackResult
-	server     *http.Server
-	listener   net.Listener
-	once       sync.Once
+	*bear.CallbackServer
 }
 
 func StartCallbackServer() (*CallbackServer, error) {
-	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	server, err := bear.StartCallbackServer(callbackScheme)
 	if err != nil {
 		return nil, err
 	}
-
-	baseURL := fmt.Sprintf("gzlcb://%s", ln.Addr().String())
-	server := &CallbackServer{
-		BaseURL:    baseURL,
-		SuccessURL: baseURL + "/success",
-		ErrorURL:   baseURL + "/error",
-		results:    make(chan CallbackResult, 1),
-		listener:   ln,
-	}
-
-	mux := http.NewServeMux()
-	mux.HandleFunc("/success", func(w http.ResponseWriter, r *http.Request) {
-		server.finish(true, r.URL.Query())
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ok"))
-	})
-	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
-		server.finish(false, r.URL.Query())
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ok"))
-	})
-
-	server.server = &http.Server{Handler: mux}
-
-	go func() {
-		_ = server.server.Serve(ln)
-	}()
-
-	return server, nil
+	return &CallbackServer{CallbackServer: server}, nil
 }
 
 func (c *CallbackServer) finish(success bool, values url.Values) {
-	c.once.Do(func() {
-		c.results <- CallbackResult{Success: success, Values: values}
-	})
-}
-
-func (c *CallbackServer) Wait(ctx context.Context) (CallbackResult, error) {
-	select {
-	case res := <-c.results:
-		_ = c.Shutdown()
-		return res, nil
-	case <-ctx.Done():
-		_ = c.Shutdown()
-		return CallbackResult{}, ctx.Err()
-	}
+	c.Deliver(success, values)
 }
 
-func (c *CallbackServer) Shutdown() error {
-	if c.server == nil {
+// callbackRejectLogger reports ignored callback hits under --verbose.
+func callbackRejectLogger(opts *Options) bear.RejectFunc {
+	if !opts.Verbose {
 		return nil
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
-	return c.server.Shutdown(ctx)
+	return func(r *http.Request, reason string) {
+		fmt.Fprintf(os.Stderr, "grizzly: rejected callback %s from %s: %s\n", r.URL.Path, r.RemoteAddr, reason)
+	}
 }
@@ -0,0 +1,288 @@
+package grizzly
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"grizzly/pkg/bear"
+)
+
+const (
+	defaultInboxTitle     = "Inbox"
+	defaultCaptureLayout  = "2006-01-02 15:04"
+	captureStyleBullet    = "bullet"
+	captureStyleChecklist = "checklist"
+)
+
+type captureReport struct {
+	Title      string `json:"title"`
+	Identifier string `json:"identifier"`
+	Entry      string `json:"entry"`
+	Created    bool   `json:"created,omitempty"`
+}
+
+// inboxCache maps an inbox target ("id:…", "title:…", "daily:…") to the
+// identifier it last resolved to.
+type inboxCache struct {
+	Notes map[string]string `json:"notes"`
+}
+
+type capturer struct {
+	opts      *Options
+	client    *bear.Client
+	cfg       InboxConfig
+	daily     DailyConfig
+	cachePath string
+	now       func() time.Time
+}
+
+func newCaptureCmd(opts *Options) *cobra.Command {
+	var text string
+	var style string
+	var header string
+	var noTimestamp bool
+
+	cmd := &cobra.Command{
+		Use:   "capture [text...]",
+		Short: "Append a timestamped entry to the inbox note",
+		Long: `Append a timestamped entry to the inbox note.
+
+The inbox is configured in an [inbox] table: id, or daily = true for the
+day's note (see "grizzly daily"), or title (default "` + defaultInboxTitle + `").
+The resolved identifier is cached, so renaming the note does not break
+capture; a trashed or missing inbox is found again by title or recreated.
+
+Entries are bullets ("- 2024-03-14 15:04 text") or, with style = "checklist",
+todo items ("- [ ] ..."). Other keys: tag (for a recreated inbox), header and
+timestamp_format (a Go time layout).`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 && text != "" {
+				return usageError(cmd, "text arguments cannot be combined with --text")
+			}
+			if len(args) > 0 {
+				text = strings.Join(args, " ")
+			}
+			if err := ensureNoStdinConflict(opts.TokenStdin, text == "-" || (text == "" && !stdinIsTTY())); err != nil {
+				return &ExitError{Code: ExitUsage, Err: err}
+			}
+			entry, _, err := resolveTextInput(text, false, true)
+			if err != nil {
+				return &ExitError{Code: ExitUsage, Err: err}
+			}
+			if strings.TrimSpace(entry) == "" {
+				return usageError(cmd, "text required (arguments, --text, or stdin)")
+			}
+
+			cfg := opts.Inbox
+			if style != "" {
+				cfg.Style = style
+			}
+			if header != "" {
+				cfg.Header = header
+			}
+			if cfg.Style != "" && cfg.Style != captureStyleBullet && cfg.Style != captureStyleChecklist {
+				return usageError(cmd, "style must be %s or %s", captureStyleBullet, captureStyleChecklist)
+			}
+			if noTimestamp {
+				cfg.TimestampFormat = "-"
+			}
+
+			// Search returns no notes without a token, so finding the inbox by
+			// title or as the daily note needs one.
+			token, err := maybeRequireToken(opts, cfg.ID == "")
+			if err != nil {
+				return writeTokenError(opts, "capture", err)
+			}
+			cachePath, err := inboxCachePath()
+			if err != nil {
+				return &ExitError{Code: ExitFailure, Err: err}
+			}
+			out := NewOutputter(opts)
+			client, done, err := newSessionClient(opts, token)
+			if err != nil {
+				return usageError(cmd, "%v", err)
+			}
+			defer done()
+
+			c := &capturer{opts: opts, client: client, cfg: cfg, daily: opts.Daily, cachePath: cachePath, now: time.Now}
+			report, err := c.capture(cmd.Context(), entry)
+			if err != nil {
+				return writeClientError(out, "capture", err)
+			}
+			out.WriteSuccess(Result{Action: "capture", Data: report})
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&text, "text", "", "Entry text (use - for stdin)")
+	cmd.Flags().StringVar(&style, "style", "", "Entry style: bullet or checklist")
+	cmd.Flags().StringVar(&header, "header", "", "Append under this heading")
+	cmd.Flags().BoolVar(&noTimestamp, "no-timestamp", false, "Do not prefix the entry with the time")
+	return cmd
+}
+
+func (c *capturer) capture(ctx context.Context, text string) (*captureReport, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	id, title, created, err := c.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entry := formatCaptureEntry(text, c.cfg.Style, c.cfg.TimestampFormat, c.now())
+	notes := &periodNotes{opts: c.opts, client: c.client, cfg: c.daily}
+	if err := notes.appendEntry(ctx, id, c.cfg.Header, entry); err != nil {
+		return nil, err
+	}
+	return &captureReport{Title: title, Identifier: id, Entry: entry, Created: created}, nil
+}
+
+// resolve returns the inbox identifier, trying the cached identifier first,
+// then a search by title, and creating the note as a last resort.
+func (c *capturer) resolve(ctx context.Context) (string, string, bool, error) {
+	title := c.cfg.Title
+	if title == "" {
+		title = defaultInboxTitle
+	}
+	key := "title:" + title
+	var start time.Time
+	if c.cfg.ID != "" {
+		key = "id:" + c.cfg.ID
+	} else if c.cfg.Daily {
+		start = periodStart(c.now(), periodDay)
+		title = periodTitle(c.daily, periodDay, start)
+		key = "daily:" + title
+	}
+
+	cache := c.loadCache()
+	candidate := cache.Notes[key]
+	if candidate == "" {
+		candidate = c.cfg.ID
+	}
+	if candidate != "" {
+		note, err := c.openNote(ctx, candidate)
+		if err == nil && !note.IsTrashed {
+			return candidate, note.Title, false, c.remember(cache, key, candidate)
+		}
+		if err != nil && !bear.IsNoteNotFound(err) {
+			return "", "", false, err
+		}
+	}
+
+	var id string
+	var created bool
+	var err error
+	if c.cfg.Daily && c.cfg.ID == "" {
+		notes := &periodNotes{opts: c.opts, client: c.client, cfg: c.daily}
+		id, created, err = notes.ensure(ctx, title, start)
+	} else {
+		id, created, err = c.ensureTitled(ctx, title)
+	}
+	if err != nil {
+		return "", "", false, err
+	}
+	return id, title, created, c.remember(cache, key, id)
+}
+
+func (c *capturer) ensureTitled(ctx context.Context, title string) (string, bool, error) {
+	existing, err := findNoteByTitle(ctx, c.opts, c.client, title, c.cfg.Tag)
+	if err != nil {
+		return "", false, err
+	}
+	if existing != nil {
+		return existing.Identifier, false, nil
+	}
+	create := bear.CreateOptions{
+		Window: bear.Window{NoShowWindow: true, NoOpen: true},
+		Title:  title,
+	}
+	if c.cfg.Tag != "" {
+		create.Tags = []string{c.cfg.Tag}
+	}
+	callCtx, cancel := withCallTimeout(ctx, c.opts)
+	defer cancel()
+	res, err := c.client.Create(callCtx, create)
+	if err != nil {
+		return "", false, err
+	}
+	if res.Identifier == "" {
+		return "", false, fmt.Errorf("bear did not return an identifier")
+	}
+	return res.Identifier, true, nil
+}
+
+func (c *capturer) openNote(ctx context.Context, id string) (*bear.Note, error) {
+	callCtx, cancel := withCallTimeout(ctx, c.opts)
+	defer cancel()
+	res, err := c.client.OpenNote(callCtx, bear.OpenNoteOptions{
+		NoteRef: bear.NoteRef{ID: id},
+		Window:  bear.Window{NoShowWindow: true, NoOpen: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &res.Note, nil
+}
+
+// formatCaptureEntry renders text as a bullet or checklist item. Layout "-"
+// leaves out the timestamp; continuation lines are indented under the item.
+func formatCaptureEntry(text, style, layout string, now time.Time) string {
+	prefix := "- "
+	if style == captureStyleChecklist {
+		prefix = "- [ ] "
+	}
+	if layout == "" {
+		layout = defaultCaptureLayout
+	}
+	if layout != "-" {
+		prefix += now.Format(layout) + " "
+	}
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n")), "\n")
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) != "" {
+			lines[i] = "  " + lines[i]
+		}
+	}
+	return prefix + strings.Join(lines, "\n")
+}
+
+func inboxCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "grizzly", "inbox.json"), nil
+}
+
+// loadCache reads the cache; an unreadable cache is treated as empty.
+func (c *capturer) loadCache() *inboxCache {
+	cache := &inboxCache{}
+	if data, err := os.ReadFile(c.cachePath); err == nil {
+		_ = json.Unmarshal(data, cache)
+	}
+	if cache.Notes == nil {
+		cache.Notes = map[string]string{}
+	}
+	return cache
+}
+
+func (c *capturer) remember(cache *inboxCache, key, id string) error {
+	if cache.Notes[key] == id {
+		return nil
+	}
+	cache.Notes[key] = id
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.cachePath), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(c.cachePath, append(data, '\n'), 0o600)
+}
@@ -0,0 +1,86 @@
+package grizzly
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCapturerFollowsRenameAndRecreatesTrashedInbox(t *testing.T) {
+	fb, client := newFakeSession(t)
+	c := &capturer{
+		opts:      &Options{Timeout: 2 * time.Second},
+		client:    client,
+		cfg:       InboxConfig{Style: captureStyleChecklist, Tag: "inbox"},
+		cachePath: filepath.Join(t.TempDir(), "inbox.json"),
+		now:       func() time.Time { return time.Date(2024, 3, 14, 9, 30, 0, 0, time.UTC) },
+	}
+	capture := func(text string) *captureReport {
+		t.Helper()
+		report, err := c.capture(context.Background(), text)
+		if err != nil {
+			t.Fatalf("capture(%q): %v", text, err)
+		}
+		return report
+	}
+
+	first := capture("call bank\nabout the card")
+	if !first.Created || first.Entry != "- [ ] 2024-03-14 09:30 call bank\n  about the card" {
+		t.Fatalf("first = %+v", first)
+	}
+	fakeCall(t, fb, "add-text", url.Values{"id": {first.Identifier}, "mode": {"replace_all"}, "text": {"# Renamed inbox\n#inbox"}})
+	if second := capture("buy milk"); second.Created || second.Identifier != first.Identifier || second.Title != "Renamed inbox" {
+		t.Fatalf("second = %+v", second)
+	}
+
+	fakeCall(t, fb, "trash", url.Values{"id": {first.Identifier}})
+	third := capture("water plants")
+	if !third.Created || third.Identifier == first.Identifier || third.Title != defaultInboxTitle {
+		t.Fatalf("third = %+v", third)
+	}
+	values := fakeCall(t, fb, "open-note", url.Values{"id": {third.Identifier}})
+	if got, want := values.Get("note"), "# Inbox\n#inbox\n- [ ] 2024-03-14 09:30 water plants"; got != want {
+		t.Fatalf("note = %q, want %q", got, want)
+	}
+	if cached := c.loadCache().Notes["title:Inbox"]; cached != third.Identifier {
+		t.Fatalf("cached = %q, want %q", cached, third.Identifier)
+	}
+
+	// An inbox Bear cannot open for another reason is not replaced.
+	fb.noteByID(third.Identifier).Locked = true
+	if report, err := c.capture(context.Background(), "locked"); err == nil {
+		t.Fatalf("locked inbox = %+v", report)
+	}
+	if n := len(fb.state.Notes); n != 2 {
+		t.Fatalf("notes = %d, want 2", n)
+	}
+}
+
+func TestCaptureRequiresTokenForTitledInbox(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	t.Setenv("XDG_CACHE_HOME", root)
+	t.Setenv("GRIZZLY_SECRET_STORE", "file")
+	t.Setenv("GRIZZLY_FAKE_BEAR_STORE", filepath.Join(root, "store.json"))
+	for _, name := range []string{"GRIZZLY_PROFILE", "GRIZZLY_TOKEN_FILE", "GRIZZLY_TOKEN_COMMAND"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	cmd := NewRootCmd()
+	cmd.SetArgs([]string{"--opener", "fake", "--timeout", "2s", "capture", "buy milk"})
+	cmd.SetOut(io.Discard)
+	if err := cmd.Execute(); ExitCode(err) != ExitUsage || !strings.Contains(err.Error(), "token") {
+		t.Fatalf("capture without a token: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "store.json")); err == nil {
+		data, _ := os.ReadFile(filepath.Join(root, "store.json"))
+		if strings.Contains(string(data), "Inbox") {
+			t.Fatalf("inbox created without a token: %s", data)
+		}
+	}
+}
@@ -1,11 +1,13 @@
 package grizzly
 
 import (
+	"context"
 	"fmt"
-	"net/url"
 	"strings"
 
 	"github.com/spf13/cobra"
+
+	"grizzly/pkg/bear"
 )
 
 func AddCommands(root *cobra.Command, opts *Options) {
@@ -25,11 +27,25 @@ func AddCommands(root *cobra.Command, opts *Options) {
 	root.AddCommand(newLockedCmd(opts))
 	root.AddCommand(newSearchCmd(opts))
 	root.AddCommand(newGrabURLCmd(opts))
+	root.AddCommand(newBatchCmd(opts))
+	root.AddCommand(newExportCmd(opts))
+	root.AddCommand(newImportCmd(opts))
+	root.AddCommand(newSyncCmd(opts))
+	root.AddCommand(newBackupCmd(opts))
+	root.AddCommand(newRestoreCmd(opts))
+	root.AddCommand(newDailyCmd(opts))
+	root.AddCommand(newCaptureCmd(opts))
+	root.AddCommand(newConfigCmd(opts))
+	root.AddCommand(newAuthCmd(opts))
+	root.AddCommand(newPluginsCmd(opts))
+	root.AddCommand(newDaemonCmd(opts))
+	root.AddCommand(newFakeBearCmd(opts))
 	root.AddCommand(newCompletionCmd(root))
 }
 
 func newOpenNoteCmd(opts *Options) *cobra.Command {
 	var id string
+	var pick bool
 	var title string
 	var header string
 	var excludeTrashed bool
@@ -49,34 +65,40 @@ func newOpenNoteCmd(opts *Options) *cobra.Command {
 			if selected && (id != "" || title != "") {
 				return usageError(cmd, "--selected cannot be combined with --id or --title")
 			}
+			picked, err := pickTarget(cmd, opts, "open-note", pick, id != "" || title != "" || selected)
+			if err != nil {
+				return err
+			}
+			if picked != "" {
+				id = picked
+			}
 			if id == "" && title == "" && !selected {
 				return usageError(cmd, "one of --id, --title, or --selected is required")
 			}
-			params := url.Values{}
-			addStringParam(params, "id", id)
-			addStringParam(params, "title", title)
-			addStringParam(params, "header", header)
-			addStringParam(params, "search", find)
-			addYesParam(params, "exclude_trashed", excludeTrashed)
-			addYesParam(params, "new_window", newWindow)
-			addYesParam(params, "float", floatWindow)
-			addNoParam(params, "show_window", noShowWindow)
-			addNoParam(params, "open_note", noOpen)
-			addYesParam(params, "selected", selected)
-			addYesParam(params, "pin", pin)
-			addYesParam(params, "edit", edit)
+			token := ""
 			if selected {
-				token, err := maybeRequireToken(opts, true)
+				var err error
+				token, err = maybeRequireToken(opts, true)
 				if err != nil {
-					return &ExitError{Code: ExitUsage, Err: err}
+					return writeTokenError(opts, "open-note", err)
 				}
-				params.Set("token", token)
 			}
-			return executeAction(opts, "open-note", params)
+			req := bear.OpenNoteOptions{
+				NoteRef:        bear.NoteRef{ID: id, Title: title, Selected: selected},
+				Window:         bear.Window{NewWindow: newWindow, Float: floatWindow, NoShowWindow: noShowWindow, NoOpen: noOpen, Edit: edit},
+				Header:         header,
+				Search:         find,
+				ExcludeTrashed: excludeTrashed,
+				Pin:            pin,
+			}
+			return executeAction(opts, "open-note", token, func(ctx context.Context, c *bear.Client) (*bear.NoteResult, error) {
+				return c.OpenNote(ctx, req)
+			})
 		},
 	}
 
 	cmd.Flags().StringVar(&id, "id", "", "Note identifier")
+	cmd.Flags().BoolVar(&pick, "pick", false, "Choose the note interactively (default on a terminal when no note is given)")
 	cmd.Flags().StringVar(&title, "title", "", "Note title")
 	cmd.Flags().StringVar(&header, "header", "", "Header inside the note")
 	cmd.Flags().BoolVar(&excludeTrashed, "exclude-trashed", false, "Exclude trashed notes")
@@ -88,6 +110,7 @@ func newOpenNoteCmd(opts *Options) *cobra.Command {
 	cmd.Flags().BoolVar(&pin, "pin", false, "Pin the note to the top of the list")
 	cmd.Flags().BoolVar(&edit, "edit", false, "Place cursor inside the note editor")
 	cmd.Flags().StringVar(&find, "find", "", "Open in-note search with the specified text")
+	cmd.Flags().BoolVar(&opts.Raw, "raw", false, "Print the note's Markdown as-is instead of rendering it (human output)")
 
 	return cmd
 }
@@ -109,13 +132,21 @@ func newCreateCmd(opts *Options) *cobra.Command {
 	var timestamp bool
 	var typeStr string
 	var baseURL string
+	var templateName string
+	var templateVars []string
 
 	cmd := &cobra.Command{
 		Use:   "create",
 		Short: "Create a new note",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if templateName != "" && (text != "" || clipboard) {
+				return usageError(cmd, "--template cannot be combined with --text or --clipboard")
+			}
+			if templateName == "" && len(templateVars) > 0 {
+				return usageError(cmd, "--var requires --template")
+			}
 			stdinIsText := false
-			if text == "-" || (text == "" && !clipboard && filePath == "" && !stdinIsTTY()) {
+			if text == "-" || (text == "" && !clipboard && filePath == "" && templateName == "" && !stdinIsTTY()) {
 				stdinIsText = true
 			}
 			fileUsesStdin := filePath == "-"
@@ -123,12 +154,33 @@ func newCreateCmd(opts *Options) *cobra.Command {
 				return &ExitError{Code: ExitUsage, Err: err}
 			}
 
-			resolvedText, usedStdin, err := resolveTextInput(text, clipboard, filePath == "")
+			resolvedText, usedStdin, err := resolveTextInput(text, clipboard, filePath == "" && templateName == "")
 			if err != nil {
 				return &ExitError{Code: ExitUsage, Err: err}
 			}
 			stdinIsText = usedStdin
 
+			if templateName != "" {
+				vars, err := parseTemplateVars(templateVars)
+				if err != nil {
+					return &ExitError{Code: ExitUsage, Err: err}
+				}
+				tpl, err := findTemplate(templateName)
+				if err != nil {
+					return NewOutputter(opts).WriteError(Result{Action: "create"}, ErrorInfo{Message: err.Error(), Code: "template"}, ExitUsage)
+				}
+				renderedTitle, body, err := newTemplateRenderer(opts, vars).render(tpl)
+				if err != nil {
+					return NewOutputter(opts).WriteError(Result{Action: "create"}, ErrorInfo{Message: err.Error(), Code: "template"}, ExitUsage)
+				}
+				if title == "" {
+					title = renderedTitle
+				}
+				resolvedText = body
+				tags = append(append([]string{}, tpl.Tags...), tags...)
+				pin = pin || tpl.Pin
+			}
+
 			fileData, fileName, fileUsedStdin, err := loadFileParam(filePath, filename)
 			if err != nil {
 				return &ExitError{Code: ExitUsage, Err: err}
@@ -153,30 +205,24 @@ func newCreateCmd(opts *Options) *cobra.Command {
 				return &ExitError{Code: ExitUsage, Err: err}
 			}
 
-			params := url.Values{}
-			addStringParam(params, "title", title)
-			if resolvedText != "" {
-				addStringParam(params, "text", resolvedText)
+			req := bear.CreateOptions{
+				Window:    bear.Window{NewWindow: newWindow, Float: floatWindow, NoShowWindow: noShowWindow, NoOpen: noOpen, Edit: edit},
+				Title:     title,
+				Text:      resolvedText,
+				Clipboard: clipboard,
+				Tags:      splitCSV(tagParam),
+				Pin:       pin,
+				Timestamp: timestamp,
+				HTML:      typeStr == "html",
+				BaseURL:   baseURL,
 			}
-			addYesParam(params, "clipboard", clipboard)
-			addStringParam(params, "tags", tagParam)
 			if fileData != "" {
-				params.Set("file", fileData)
-				params.Set("filename", fileName)
-			}
-			addNoParam(params, "open_note", noOpen)
-			addYesParam(params, "new_window", newWindow)
-			addYesParam(params, "float", floatWindow)
-			addNoParam(params, "show_window", noShowWindow)
-			addYesParam(params, "pin", pin)
-			addYesParam(params, "edit", edit)
-			addYesParam(params, "timestamp", timestamp)
-			if typeStr == "html" {
-				params.Set("type", "html")
-				addStringParam(params, "url", baseURL)
-			}
-
-			return executeAction(opts, "create", params)
+				req.File = &bear.File{Name: fileName, Base64: fileData}
+			}
+
+			return executeAction(opts, "create", "", func(ctx context.Context, c *bear.Client) (*bear.CreateResult, error) {
+				return c.Create(ctx, req)
+			})
 		},
 	}
 
@@ -196,12 +242,15 @@ func newCreateCmd(opts *Options) *cobra.Command {
 	cmd.Flags().BoolVar(&timestamp, "timestamp", false, "Prepend current date/time to the text")
 	cmd.Flags().StringVar(&typeStr, "type", "", "Content type (html or markdown)")
 	cmd.Flags().StringVar(&baseURL, "url", "", "Base URL for relative links when --type html")
+	cmd.Flags().StringVar(&templateName, "template", "", "Render the note from a template (name or path)")
+	cmd.Flags().StringArrayVar(&templateVars, "var", nil, "Template variable as key=value (repeatable)")
 
 	return cmd
 }
 
 func newAddTextCmd(opts *Options) *cobra.Command {
 	var id string
+	var pick bool
 	var title string
 	var selected bool
 	var text string
@@ -225,6 +274,13 @@ func newAddTextCmd(opts *Options) *cobra.Command {
 			if selected && (id != "" || title != "") {
 				return usageError(cmd, "--selected cannot be combined with --id or --title")
 			}
+			picked, err := pickTarget(cmd, opts, "add-text", pick, id != "" || title != "" || selected)
+			if err != nil {
+				return err
+			}
+			if picked != "" {
+				id = picked
+			}
 			if id == "" && title == "" && !selected {
 				return usageError(cmd, "one of --id, --title, or --selected is required")
 			}
@@ -253,37 +309,34 @@ func newAddTextCmd(opts *Options) *cobra.Command {
 				return &ExitError{Code: ExitUsage, Err: err}
 			}
 
-			params := url.Values{}
-			addStringParam(params, "id", id)
-			addStringParam(params, "title", title)
-			addYesParam(params, "selected", selected)
+			token := ""
 			if selected {
-				token, err := maybeRequireToken(opts, true)
+				token, err = maybeRequireToken(opts, true)
 				if err != nil {
-					return &ExitError{Code: ExitUsage, Err: err}
+					return writeTokenError(opts, "add-text", err)
 				}
-				params.Set("token", token)
-			}
-			if resolvedText != "" {
-				params.Set("text", resolvedText)
-			}
-			addYesParam(params, "clipboard", clipboard)
-			addStringParam(params, "header", header)
-			addStringParam(params, "mode", modeParam)
-			addYesParam(params, "new_line", newLine)
-			addStringParam(params, "tags", tagParam)
-			addYesParam(params, "exclude_trashed", excludeTrashed)
-			addNoParam(params, "open_note", noOpen)
-			addYesParam(params, "new_window", newWindow)
-			addNoParam(params, "show_window", noShowWindow)
-			addYesParam(params, "edit", edit)
-			addYesParam(params, "timestamp", timestamp)
-
-			return executeAction(opts, "add-text", params)
+			}
+			req := bear.AddTextOptions{
+				NoteRef:        bear.NoteRef{ID: id, Title: title, Selected: selected},
+				Window:         bear.Window{NewWindow: newWindow, NoShowWindow: noShowWindow, NoOpen: noOpen, Edit: edit},
+				Text:           resolvedText,
+				Clipboard:      clipboard,
+				Header:         header,
+				Mode:           bear.Mode(modeParam),
+				NewLine:        newLine,
+				Tags:           splitCSV(tagParam),
+				ExcludeTrashed: excludeTrashed,
+				Timestamp:      timestamp,
+			}
+
+			return executeAction(opts, "add-text", token, func(ctx context.Context, c *bear.Client) (*bear.AddTextResult, error) {
+				return c.AddText(ctx, req)
+			})
 		},
 	}
 
 	cmd.Flags().StringVar(&id, "id", "", "Note identifier")
+	cmd.Flags().BoolVar(&pick, "pick", false, "Choose the note interactively (default on a terminal when no note is given)")
 	cmd.Flags().StringVar(&title, "title", "", "Note title")
 	cmd.Flags().BoolVar(&selected, "selected", false, "Use the note currently selected in Bear (token required)")
 	cmd.Flags().StringVar(&text, "text", "", "Text to add (use - for stdin)")
@@ -305,6 +358,7 @@ func newAddTextCmd(opts *Options) *cobra.Command {
 
 func newAddFileCmd(opts *Options) *cobra.Command {
 	var id string
+	var pick bool
 	var title string
 	var selected bool
 	var filePath string
@@ -323,6 +377,13 @@ func newAddFileCmd(opts *Options) *cobra.Command {
 			if selected && (id != "" || title != "") {
 				return usageError(cmd, "--selected cannot be combined with --id or --title")
 			}
+			picked, err := pickTarget(cmd, opts, "add-file", pick, id != "" || title != "" || selected)
+			if err != nil {
+				return err
+			}
+			if picked != "" {
+				id = picked
+			}
 			if id == "" && title == "" && !selected {
 				return usageError(cmd, "one of --id, --title, or --selected is required")
 			}
@@ -343,31 +404,29 @@ func newAddFileCmd(opts *Options) *cobra.Command {
 				return &ExitError{Code: ExitUsage, Err: err}
 			}
 
-			params := url.Values{}
-			addStringParam(params, "id", id)
-			addStringParam(params, "title", title)
-			addYesParam(params, "selected", selected)
+			token := ""
 			if selected {
-				token, err := maybeRequireToken(opts, true)
+				token, err = maybeRequireToken(opts, true)
 				if err != nil {
-					return &ExitError{Code: ExitUsage, Err: err}
+					return writeTokenError(opts, "add-file", err)
 				}
-				params.Set("token", token)
-			}
-			params.Set("file", fileData)
-			params.Set("filename", fileName)
-			addStringParam(params, "header", header)
-			addStringParam(params, "mode", modeParam)
-			addNoParam(params, "open_note", noOpen)
-			addYesParam(params, "new_window", newWindow)
-			addNoParam(params, "show_window", noShowWindow)
-			addYesParam(params, "edit", edit)
-
-			return executeAction(opts, "add-file", params)
+			}
+			req := bear.AddFileOptions{
+				NoteRef: bear.NoteRef{ID: id, Title: title, Selected: selected},
+				Window:  bear.Window{NewWindow: newWindow, NoShowWindow: noShowWindow, NoOpen: noOpen, Edit: edit},
+				File:    bear.File{Name: fileName, Base64: fileData},
+				Header:  header,
+				Mode:    bear.Mode(modeParam),
+			}
+
+			return executeAction(opts, "add-file", token, func(ctx context.Context, c *bear.Client) (*bear.Response, error) {
+				return c.AddFile(ctx, req)
+			})
 		},
 	}
 
 	cmd.Flags().StringVar(&id, "id", "", "Note identifier")
+	cmd.Flags().BoolVar(&pick, "pick", false, "Choose the note interactively (default on a terminal when no note is given)")
 	cmd.Flags().StringVar(&title, "title", "", "Note title")
 	cmd.Flags().BoolVar(&selected, "selected", false, "Use the note currently selected in Bear (token required)")
 	cmd.Flags().StringVar(&filePath, "file", "", "File to add (path or - for stdin)")
@@ -389,11 +448,11 @@ func newTagsCmd(opts *Options) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			token, err := maybeRequireToken(opts, true)
 			if err != nil {
-				return &ExitError{Code: ExitUsage, Err: err}
+				return writeTokenError(opts, "tags", err)
 			}
-			params := url.Values{}
-			params.Set("token", token)
-			return executeAction(opts, "tags", params)
+			return executeAction(opts, "tags", token, func(ctx context.Context, c *bear.Client) (*bear.TagsResult, error) {
+				return c.Tags(ctx)
+			})
 		},
 	}
 	return cmd
@@ -408,16 +467,13 @@ func newOpenTagCmd(opts *Options) *cobra.Command {
 			if name == "" {
 				return usageError(cmd, "--name is required")
 			}
-			params := url.Values{}
-			params.Set("name", name)
 			token, err := resolveToken(opts)
 			if err != nil {
-				return &ExitError{Code: ExitUsage, Err: err}
+				return writeTokenError(opts, "open-tag", err)
 			}
-			if token != "" {
-				params.Set("token", token)
-			}
-			return executeAction(opts, "open-tag", params)
+			return executeAction(opts, "open-tag", token, func(ctx context.Context, c *bear.Client) (*bear.NotesResult, error) {
+				return c.OpenTag(ctx, bear.OpenTagOptions{Names: splitCSV(name)})
+			})
 		},
 	}
 	cmd.Flags().StringVar(&name, "name", "", "Tag name or comma-separated list")
@@ -436,11 +492,9 @@ func newRenameTagCmd(opts *Options) *cobra.Command {
 			if name == "" || newName == "" {
 				return usageError(cmd, "--name and --new-name are required")
 			}
-			params := url.Values{}
-			params.Set("name", name)
-			params.Set("new_name", newName)
-			addNoParam(params, "show_window", noShowWindow)
-			return executeAction(opts, "rename-tag", params)
+			return executeAction(opts, "rename-tag", "", func(ctx context.Context, c *bear.Client) (*bear.Response, error) {
+				return c.RenameTag(ctx, bear.RenameTagOptions{Name: name, NewName: newName, NoShowWindow: noShowWindow})
+			})
 		},
 	}
 	cmd.Flags().StringVar(&name, "name", "", "Existing tag name")
@@ -465,10 +519,9 @@ func newDeleteTagCmd(opts *Options) *cobra.Command {
 					return &ExitError{Code: ExitFailure, Err: err}
 				}
 			}
-			params := url.Values{}
-			params.Set("name", name)
-			addNoParam(params, "show_window", noShowWindow)
-			return executeAction(opts, "delete-tag", params)
+			return executeAction(opts, "delete-tag", "", func(ctx context.Context, c *bear.Client) (*bear.Response, error) {
+				return c.DeleteTag(ctx, bear.DeleteTagOptions{Name: name, NoShowWindow: noShowWindow})
+			})
 		},
 	}
 	cmd.Flags().StringVar(&name, "name", "", "Tag name")
@@ -478,6 +531,7 @@ func newDeleteTagCmd(opts *Options) *cobra.Command {
 
 func newTrashCmd(opts *Options) *cobra.Command {
 	var id string
+	var pick bool
 	var search string
 	var noShowWindow bool
 
@@ -485,6 +539,13 @@ func newTrashCmd(opts *Options) *cobra.Command {
 		Use:   "trash",
 		Short: "Move a note to Bear trash",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			picked, err := pickTarget(cmd, opts, "trash", pick, id != "" || search != "")
+			if err != nil {
+				return err
+			}
+			if picked != "" {
+				id = picked
+			}
 			if id == "" && search == "" {
 				return usageError(cmd, "--id or --search is required")
 			}
@@ -493,16 +554,13 @@ func newTrashCmd(opts *Options) *cobra.Command {
 					return &ExitError{Code: ExitFailure, Err: err}
 				}
 			}
-			params := url.Values{}
-			addStringParam(params, "id", id)
-			if id == "" {
-				addStringParam(params, "search", search)
-			}
-			addNoParam(params, "show_window", noShowWindow)
-			return executeAction(opts, "trash", params)
+			return executeAction(opts, "trash", "", func(ctx context.Context, c *bear.Client) (*bear.Response, error) {
+				return c.Trash(ctx, bear.MoveOptions{ID: id, Search: search, NoShowWindow: noShowWindow})
+			})
 		},
 	}
 	cmd.Flags().StringVar(&id, "id", "", "Note identifier")
+	cmd.Flags().BoolVar(&pick, "pick", false, "Choose the note interactively (default on a terminal when no note is given)")
 	cmd.Flags().StringVar(&search, "search", "", "Search term (ignored if --id is provided)")
 	cmd.Flags().BoolVar(&noShowWindow, "no-show-window", false, "Do not force Bear main window to open (macOS)")
 	return cmd
@@ -510,6 +568,7 @@ func newTrashCmd(opts *Options) *cobra.Command {
 
 func newArchiveCmd(opts *Options) *cobra.Command {
 	var id string
+	var pick bool
 	var search string
 	var noShowWindow bool
 
@@ -517,6 +576,13 @@ func newArchiveCmd(opts *Options) *cobra.Command {
 		Use:   "archive",
 		Short: "Move a note to Bear archive",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			picked, err := pickTarget(cmd, opts, "archive", pick, id != "" || search != "")
+			if err != nil {
+				return err
+			}
+			if picked != "" {
+				id = picked
+			}
 			if id == "" && search == "" {
 				return usageError(cmd, "--id or --search is required")
 			}
@@ -525,16 +591,13 @@ func newArchiveCmd(opts *Options) *cobra.Command {
 					return &ExitError{Code: ExitFailure, Err: err}
 				}
 			}
-			params := url.Values{}
-			addStringParam(params, "id", id)
-			if id == "" {
-				addStringParam(params, "search", search)
-			}
-			addNoParam(params, "show_window", noShowWindow)
-			return executeAction(opts, "archive", params)
+			return executeAction(opts, "archive", "", func(ctx context.Context, c *bear.Client) (*bear.Response, error) {
+				return c.Archive(ctx, bear.MoveOptions{ID: id, Search: search, NoShowWindow: noShowWindow})
+			})
 		},
 	}
 	cmd.Flags().StringVar(&id, "id", "", "Note identifier")
+	cmd.Flags().BoolVar(&pick, "pick", false, "Choose the note interactively (default on a terminal when no note is given)")
 	cmd.Flags().StringVar(&search, "search", "", "Search term (ignored if --id is provided)")
 	cmd.Flags().BoolVar(&noShowWindow, "no-show-window", false, "Do not force Bear main window to open (macOS)")
 	return cmd
@@ -548,17 +611,13 @@ func newUntaggedCmd(opts *Options) *cobra.Command {
 		Use:   "untagged",
 		Short: "Show untagged notes",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			params := url.Values{}
-			addStringParam(params, "search", search)
-			addNoParam(params, "show_window", noShowWindow)
 			token, err := resolveToken(opts)
 			if err != nil {
-				return &ExitError{Code: ExitUsage, Err: err}
-			}
-			if token != "" {
-				params.Set("token", token)
+				return writeTokenError(opts, "untagged", err)
 			}
-			return executeAction(opts, "untagged", params)
+			return executeAction(opts, "untagged", token, func(ctx context.Context, c *bear.Client) (*bear.NotesResult, error) {
+				return c.Untagged(ctx, bear.ListOptions{Search: search, NoShowWindow: noShowWindow})
+			})
 		},
 	}
 	cmd.Flags().StringVar(&search, "search", "", "Search term")
@@ -574,17 +633,13 @@ func newTodoCmd(opts *Options) *cobra.Command {
 		Use:   "todo",
 		Short: "Show todo notes",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			params := url.Values{}
-			addStringParam(params, "search", search)
-			addNoParam(params, "show_window", noShowWindow)
 			token, err := resolveToken(opts)
 			if err != nil {
-				return &ExitError{Code: ExitUsage, Err: err}
+				return writeTokenError(opts, "todo", err)
 			}
-			if token != "" {
-				params.Set("token", token)
-			}
-			return executeAction(opts, "todo", params)
+			return executeAction(opts, "todo", token, func(ctx context.Context, c *bear.Client) (*bear.NotesResult, error) {
+				return c.Todo(ctx, bear.ListOptions{Search: search, NoShowWindow: noShowWindow})
+			})
 		},
 	}
 	cmd.Flags().StringVar(&search, "search", "", "Search term")
@@ -600,17 +655,13 @@ func newTodayCmd(opts *Options) *cobra.Command {
 		Use:   "today",
 		Short: "Show today's notes",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			params := url.Values{}
-			addStringParam(params, "search", search)
-			addNoParam(params, "show_window", noShowWindow)
 			token, err := resolveToken(opts)
 			if err != nil {
-				return &ExitError{Code: ExitUsage, Err: err}
-			}
-			if token != "" {
-				params.Set("token", token)
+				return writeTokenError(opts, "today", err)
 			}
-			return executeAction(opts, "today", params)
+			return executeAction(opts, "today", token, func(ctx context.Context, c *bear.Client) (*bear.NotesResult, error) {
+				return c.Today(ctx, bear.ListOptions{Search: search, NoShowWindow: noShowWindow})
+			})
 		},
 	}
 	cmd.Flags().StringVar(&search, "search", "", "Search term")
@@ -626,10 +677,9 @@ func newLockedCmd(opts *Options) *cobra.Command {
 		Use:   "locked",
 		Short: "Show locked notes",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			params := url.Values{}
-			addStringParam(params, "search", search)
-			addNoParam(params, "show_window", noShowWindow)
-			return executeAction(opts, "locked", params)
+			return executeAction(opts, "locked", "", func(ctx context.Context, c *bear.Client) (*bear.NotesResult, error) {
+				return c.Locked(ctx, bear.ListOptions{Search: search, NoShowWindow: noShowWindow})
+			})
 		},
 	}
 	cmd.Flags().StringVar(&search, "search", "", "Search term")
@@ -649,18 +699,13 @@ func newSearchCmd(opts *Options) *cobra.Command {
 			if term == "" && tag == "" {
 				return usageError(cmd, "--term or --tag is required")
 			}
-			params := url.Values{}
-			addStringParam(params, "term", term)
-			addStringParam(params, "tag", tag)
-			addNoParam(params, "show_window", noShowWindow)
 			token, err := resolveToken(opts)
 			if err != nil {
-				return &ExitError{Code: ExitUsage, Err: err}
-			}
-			if token != "" {
-				params.Set("token", token)
+				return writeTokenError(opts, "search", err)
 			}
-			return executeAction(opts, "search", params)
+			return executeAction(opts, "search", token, func(ctx context.Context, c *bear.Client) (*bear.NotesResult, error) {
+				return c.Search(ctx, bear.SearchOptions{Term: term, Tag: tag, NoShowWindow: noShowWindow})
+			})
 		},
 	}
 	cmd.Flags().StringVar(&term, "term", "", "Search term")
@@ -687,14 +732,10 @@ func newGrabURLCmd(opts *Options) *cobra.Command {
 			if err != nil {
 				return &ExitError{Code: ExitUsage, Err: err}
 			}
-			params := url.Values{}
-			params.Set("url", pageURL)
-			addStringParam(params, "tags", tagParam)
-			addYesParam(params, "pin", pin)
-			if noWait {
-				params.Set("wait", "no")
-			}
-			return executeAction(opts, "grab-url", params)
+			req := bear.GrabURLOptions{URL: pageURL, Tags: splitCSV(tagParam), Pin: pin, NoWait: noWait}
+			return executeAction(opts, "grab-url", "", func(ctx context.Context, c *bear.Client) (*bear.CreateResult, error) {
+				return c.GrabURL(ctx, req)
+			})
 		},
 	}
 	cmd.Flags().StringVar(&pageURL, "url", "", "URL to grab")
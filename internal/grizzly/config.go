@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -11,6 +12,15 @@ import (
 )
 
 func LoadConfig() (Config, error) {
+	return LoadProfileConfig("")
+}
+
+// LoadProfileConfig loads the config with a named profile applied. An empty
+// name falls back to GRIZZLY_PROFILE, then to the "profile" key of the
+// project or user config. In each file, [profiles.<name>] overrides that
+// file's top-level keys, so the order is: flags, env, project profile,
+// project, user profile, user.
+func LoadProfileConfig(profile string) (Config, error) {
 	cfg := Config{}
 
 	userPath, err := userConfigPath()
@@ -21,18 +31,58 @@ func LoadConfig() (Config, error) {
 	if err != nil {
 		return cfg, err
 	}
-
-	if fileCfg, err := readConfigFile(userPath); err != nil {
+	userFile, err := openConfigFile(userPath)
+	if err != nil {
 		return cfg, err
-	} else {
-		applyConfig(&cfg, fileCfg)
 	}
-
-	if fileCfg, err := readConfigFile(projectPath); err != nil {
+	projectFile, err := openConfigFile(projectPath)
+	if err != nil {
 		return cfg, err
-	} else {
+	}
+
+	profile = resolveConfigProfile(profile, userFile, projectFile)
+
+	found := false
+	for _, file := range []struct {
+		path    string
+		v       *viper.Viper
+		project bool
+	}{{userPath, userFile, false}, {projectPath, projectFile, true}} {
+		if file.v == nil {
+			continue
+		}
+		warnings, _ := checkConfigFile(file.v, file.path)
+		cfg.Warnings = append(cfg.Warnings, warnings...)
+		if file.project {
+			cfg.Warnings = append(cfg.Warnings, checkProjectFile(file.v, file.path)...)
+		}
+		fileCfg, err := configFromViper(file.v, file.path)
+		if err != nil {
+			return cfg, err
+		}
+		if file.project {
+			dropUserOnly(&fileCfg)
+		}
 		applyConfig(&cfg, fileCfg)
+		if profile == "" {
+			continue
+		}
+		if sub := file.v.Sub("profiles." + profile); sub != nil {
+			found = true
+			profileCfg, err := configFromViper(sub, fmt.Sprintf("%s [profiles.%s]", file.path, profile))
+			if err != nil {
+				return cfg, err
+			}
+			if file.project {
+				dropUserOnly(&profileCfg)
+			}
+			applyConfig(&cfg, profileCfg)
+		}
+	}
+	if profile != "" && !found {
+		return cfg, fmt.Errorf("profile %q is not defined in %s or %s", profile, userPath, projectPath)
 	}
+	cfg.Profile = profile
 
 	if envCfg, err := readEnvConfig(); err != nil {
 		return cfg, err
@@ -43,6 +93,179 @@ func LoadConfig() (Config, error) {
 	return cfg, nil
 }
 
+// resolveConfigProfile picks the active profile: the flag, GRIZZLY_PROFILE,
+// then the project's and the user's profile key.
+func resolveConfigProfile(flag string, userFile, projectFile *viper.Viper) string {
+	if flag != "" {
+		return flag
+	}
+	if env := strings.TrimSpace(os.Getenv("GRIZZLY_PROFILE")); env != "" {
+		return env
+	}
+	for _, v := range []*viper.Viper{projectFile, userFile} {
+		if v != nil {
+			if name := strings.TrimSpace(v.GetString("profile")); name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// configProfiles lists the profile names defined in the user and project
+// config files.
+func configProfiles() ([]string, error) {
+	seen := map[string]bool{}
+	var names []string
+	for _, pathFn := range []func() (string, error){userConfigPath, projectConfigPath} {
+		path, err := pathFn()
+		if err != nil {
+			return nil, err
+		}
+		v, err := openConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			continue
+		}
+		for name := range v.GetStringMap("profiles") {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+type configKeyKind int
+
+const (
+	configString configKeyKind = iota
+	configDuration
+	configBool
+)
+
+// configKeys lists the keys config files understand. Top-level keys also have
+// a GRIZZLY_<KEY> environment variable, and every key except profile may be
+// set inside [profiles.<name>].
+var configKeys = map[string]configKeyKind{
+	"profile":                configString,
+	"token_file":             configString,
+	"token_command":          configString,
+	"callback_url":           configString,
+	"timeout":                configDuration,
+	"opener":                 configString,
+	"opener_command":         configString,
+	"opener_file":            configString,
+	"opener_url":             configString,
+	"fake_bear_store":        configString,
+	"daemon_socket":          configString,
+	"plugins_dir":            configString,
+	"daily.format":           configString,
+	"daily.week_format":      configString,
+	"daily.month_format":     configString,
+	"daily.template":         configString,
+	"daily.tag":              configString,
+	"inbox.id":               configString,
+	"inbox.title":            configString,
+	"inbox.daily":            configBool,
+	"inbox.tag":              configString,
+	"inbox.header":           configString,
+	"inbox.style":            configString,
+	"inbox.timestamp_format": configString,
+}
+
+// userOnlyKeys run programs, or send the token, URLs or files elsewhere. A
+// project file comes with whatever repository grizzly runs in, so these keys
+// are only read from the user config, env and flags.
+var userOnlyKeys = map[string]bool{
+	"token_command":  true,
+	"opener_command": true,
+	"opener_file":    true,
+	"opener_url":     true,
+	"plugins_dir":    true,
+}
+
+// isUserOnlyKey reports whether key, possibly inside profiles.<name>, is one
+// of userOnlyKeys.
+func isUserOnlyKey(key string) bool {
+	if rest, ok := strings.CutPrefix(key, "profiles."); ok {
+		_, key, _ = strings.Cut(rest, ".")
+	}
+	return userOnlyKeys[key]
+}
+
+// checkProjectFile reports the user-only keys a project file sets.
+func checkProjectFile(v *viper.Viper, path string) []string {
+	keys := v.AllKeys()
+	sort.Strings(keys)
+	var problems []string
+	for _, key := range keys {
+		if isUserOnlyKey(key) {
+			problems = append(problems, fmt.Sprintf("%s: %s is ignored in project config; set it in the user config, env or flags", path, key))
+		}
+	}
+	return problems
+}
+
+func dropUserOnly(cfg *Config) {
+	cfg.TokenCommand = ""
+	cfg.OpenerCommand = ""
+	cfg.OpenerFile = ""
+	cfg.OpenerURL = ""
+	cfg.PluginsDir = ""
+}
+
+// lookupConfigKey resolves a dotted key, including profiles.<name>.<key>
+// and aliases.<name>.
+func lookupConfigKey(key string) (configKeyKind, bool) {
+	if name, ok := strings.CutPrefix(key, "aliases."); ok {
+		return configString, name != ""
+	}
+	if rest, ok := strings.CutPrefix(key, "profiles."); ok {
+		_, inner, found := strings.Cut(rest, ".")
+		if !found || inner == "profile" {
+			return 0, false
+		}
+		key = inner
+	}
+	kind, ok := configKeys[key]
+	return kind, ok
+}
+
+// checkConfigFile reports unknown keys as warnings and values of the wrong
+// kind as errors.
+func checkConfigFile(v *viper.Viper, path string) (warnings, errs []string) {
+	keys := v.AllKeys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		kind, ok := lookupConfigKey(key)
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("%s: unknown key %q", path, key))
+			continue
+		}
+		switch kind {
+		case configDuration:
+			if _, err := time.ParseDuration(strings.TrimSpace(v.GetString(key))); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %s is not a duration (e.g. 5s, 2m): %q", path, key, v.GetString(key)))
+			}
+		case configBool:
+			if _, ok := v.Get(key).(bool); !ok {
+				errs = append(errs, fmt.Sprintf("%s: %s must be true or false", path, key))
+			}
+		case configString:
+			switch v.Get(key).(type) {
+			case map[string]any, []any:
+				errs = append(errs, fmt.Sprintf("%s: %s must be a string", path, key))
+			}
+		}
+	}
+	return warnings, errs
+}
+
 func userConfigPath() (string, error) {
 	if base := os.Getenv("XDG_CONFIG_HOME"); base != "" {
 		return filepath.Join(base, "grizzly", "config.toml"), nil
@@ -63,25 +286,64 @@ func projectConfigPath() (string, error) {
 }
 
 func readConfigFile(path string) (Config, error) {
-	cfg := Config{}
+	v, err := openConfigFile(path)
+	if err != nil || v == nil {
+		return Config{}, err
+	}
+	return configFromViper(v, path)
+}
+
+// openConfigFile reads a TOML config file, returning nil when it does not
+// exist.
+func openConfigFile(path string) (*viper.Viper, error) {
 	if path == "" {
-		return cfg, nil
+		return nil, nil
 	}
 	if _, err := os.Stat(path); err != nil {
 		if os.IsNotExist(err) {
-			return cfg, nil
+			return nil, nil
 		}
-		return cfg, err
+		return nil, err
 	}
 
 	v := viper.New()
 	v.SetConfigFile(path)
 	if err := v.ReadInConfig(); err != nil {
-		return cfg, fmt.Errorf("read config %s: %w", path, err)
+		return nil, fmt.Errorf("read config %s: %w", path, err)
 	}
+	return v, nil
+}
 
+// configFromViper reads the config keys from v; path names the file (and
+// profile) in errors.
+func configFromViper(v *viper.Viper, path string) (Config, error) {
+	cfg := Config{}
 	cfg.TokenFile = strings.TrimSpace(v.GetString("token_file"))
+	cfg.TokenCommand = strings.TrimSpace(v.GetString("token_command"))
 	cfg.CallbackURL = strings.TrimSpace(v.GetString("callback_url"))
+	cfg.Opener = strings.TrimSpace(v.GetString("opener"))
+	cfg.OpenerCommand = strings.TrimSpace(v.GetString("opener_command"))
+	cfg.OpenerFile = strings.TrimSpace(v.GetString("opener_file"))
+	cfg.OpenerURL = strings.TrimSpace(v.GetString("opener_url"))
+	cfg.FakeBearStore = strings.TrimSpace(v.GetString("fake_bear_store"))
+	cfg.DaemonSocket = strings.TrimSpace(v.GetString("daemon_socket"))
+	cfg.PluginsDir = strings.TrimSpace(v.GetString("plugins_dir"))
+	cfg.Daily = DailyConfig{
+		Format:      strings.TrimSpace(v.GetString("daily.format")),
+		WeekFormat:  strings.TrimSpace(v.GetString("daily.week_format")),
+		MonthFormat: strings.TrimSpace(v.GetString("daily.month_format")),
+		Template:    strings.TrimSpace(v.GetString("daily.template")),
+		Tag:         strings.TrimSpace(v.GetString("daily.tag")),
+	}
+	cfg.Inbox = InboxConfig{
+		ID:              strings.TrimSpace(v.GetString("inbox.id")),
+		Title:           strings.TrimSpace(v.GetString("inbox.title")),
+		Daily:           v.GetBool("inbox.daily"),
+		Tag:             strings.TrimSpace(v.GetString("inbox.tag")),
+		Header:          strings.TrimSpace(v.GetString("inbox.header")),
+		Style:           strings.TrimSpace(v.GetString("inbox.style")),
+		TimestampFormat: v.GetString("inbox.timestamp_format"),
+	}
 	if v.IsSet("timeout") {
 		raw := strings.TrimSpace(v.GetString("timeout"))
 		if raw == "" {
@@ -103,9 +365,33 @@ func readEnvConfig() (Config, error) {
 	if val, ok := os.LookupEnv("GRIZZLY_TOKEN_FILE"); ok {
 		cfg.TokenFile = strings.TrimSpace(val)
 	}
+	if val, ok := os.LookupEnv("GRIZZLY_TOKEN_COMMAND"); ok {
+		cfg.TokenCommand = strings.TrimSpace(val)
+	}
 	if val, ok := os.LookupEnv("GRIZZLY_CALLBACK_URL"); ok {
 		cfg.CallbackURL = strings.TrimSpace(val)
 	}
+	if val, ok := os.LookupEnv("GRIZZLY_OPENER"); ok {
+		cfg.Opener = strings.TrimSpace(val)
+	}
+	if val, ok := os.LookupEnv("GRIZZLY_OPENER_COMMAND"); ok {
+		cfg.OpenerCommand = strings.TrimSpace(val)
+	}
+	if val, ok := os.LookupEnv("GRIZZLY_OPENER_FILE"); ok {
+		cfg.OpenerFile = strings.TrimSpace(val)
+	}
+	if val, ok := os.LookupEnv("GRIZZLY_OPENER_URL"); ok {
+		cfg.OpenerURL = strings.TrimSpace(val)
+	}
+	if val, ok := os.LookupEnv("GRIZZLY_FAKE_BEAR_STORE"); ok {
+		cfg.FakeBearStore = strings.TrimSpace(val)
+	}
+	if val, ok := os.LookupEnv("GRIZZLY_DAEMON_SOCKET"); ok {
+		cfg.DaemonSocket = strings.TrimSpace(val)
+	}
+	if val, ok := os.LookupEnv("GRIZZLY_PLUGINS_DIR"); ok {
+		cfg.PluginsDir = strings.TrimSpace(val)
+	}
 	if val, ok := os.LookupEnv("GRIZZLY_TIMEOUT"); ok {
 		d, err := time.ParseDuration(strings.TrimSpace(val))
 		if err != nil {
@@ -118,8 +404,11 @@ func readEnvConfig() (Config, error) {
 }
 
 func applyConfig(dest *Config, src Config) {
-	if src.TokenFile != "" {
+	// token_file and token_command are one setting: the highest layer that
+	// sets either decides where the token comes from.
+	if src.TokenFile != "" || src.TokenCommand != "" {
 		dest.TokenFile = src.TokenFile
+		dest.TokenCommand = src.TokenCommand
 	}
 	if src.CallbackURL != "" {
 		dest.CallbackURL = src.CallbackURL
@@ -128,4 +417,69 @@ func applyConfig(dest *Config, src Config) {
 		dest.Timeout = src.Timeout
 		dest.TimeoutSet = true
 	}
+	if src.Opener != "" {
+		dest.Opener = src.Opener
+	}
+	if src.OpenerCommand != "" {
+		dest.OpenerCommand = src.OpenerCommand
+	}
+	if src.OpenerFile != "" {
+		dest.OpenerFile = src.OpenerFile
+	}
+	if src.OpenerURL != "" {
+		dest.OpenerURL = src.OpenerURL
+	}
+	if src.FakeBearStore != "" {
+		dest.FakeBearStore = src.FakeBearStore
+	}
+	if src.DaemonSocket != "" {
+		dest.DaemonSocket = src.DaemonSocket
+	}
+	if src.PluginsDir != "" {
+		dest.PluginsDir = src.PluginsDir
+	}
+	applyDailyConfig(&dest.Daily, src.Daily)
+	applyInboxConfig(&dest.Inbox, src.Inbox)
+}
+
+func applyDailyConfig(dest *DailyConfig, src DailyConfig) {
+	if src.Format != "" {
+		dest.Format = src.Format
+	}
+	if src.WeekFormat != "" {
+		dest.WeekFormat = src.WeekFormat
+	}
+	if src.MonthFormat != "" {
+		dest.MonthFormat = src.MonthFormat
+	}
+	if src.Template != "" {
+		dest.Template = src.Template
+	}
+	if src.Tag != "" {
+		dest.Tag = src.Tag
+	}
+}
+
+func applyInboxConfig(dest *InboxConfig, src InboxConfig) {
+	if src.ID != "" {
+		dest.ID = src.ID
+	}
+	if src.Title != "" {
+		dest.Title = src.Title
+	}
+	if src.Daily {
+		dest.Daily = true
+	}
+	if src.Tag != "" {
+		dest.Tag = src.Tag
+	}
+	if src.Header != "" {
+		dest.Header = src.Header
+	}
+	if src.Style != "" {
+		dest.Style = src.Style
+	}
+	if src.TimestampFormat != "" {
+		dest.TimestampFormat = src.TimestampFormat
+	}
 }
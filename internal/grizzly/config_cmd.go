@@ -0,0 +1,794 @@
+package grizzly
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// annotationLenientConfig marks commands that run even when the config does
+// not load, so they can report and fix it.
+const annotationLenientConfig = "grizzly/lenient-config"
+
+// configSetting is one effective config value and the layer it came from.
+type configSetting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Origin string `json:"origin"`
+	Path   string `json:"path,omitempty"`
+}
+
+type configListReport struct {
+	Profile  string          `json:"profile,omitempty"`
+	Settings []configSetting `json:"settings"`
+}
+
+type configFileReport struct {
+	Path     string   `json:"path"`
+	Exists   bool     `json:"exists"`
+	Warnings []string `json:"warnings,omitempty"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+type configValidateReport struct {
+	Valid bool               `json:"valid"`
+	Files []configFileReport `json:"files"`
+}
+
+func (s *configSetting) records() recordSet { return singleRecord(s) }
+
+func (s *configSetting) writeHuman(w io.Writer, style termStyle) {
+	fmt.Fprintln(w, s.Value)
+}
+
+func (r *configListReport) records() recordSet {
+	return listRecords(r.Settings, configSetting{})
+}
+
+func (r *configListReport) writeHuman(w io.Writer, style termStyle) {
+	rows := make([][]tableCell, len(r.Settings))
+	for i, setting := range r.Settings {
+		rows[i] = []tableCell{{text: setting.Key}, {text: setting.Value}, {text: setting.Origin}}
+	}
+	style.writeTable(w, []string{"KEY", "VALUE", "ORIGIN"}, rows, []int{1})
+}
+
+func (r *configValidateReport) records() recordSet { return singleRecord(r) }
+
+func (r *configValidateReport) writeHuman(w io.Writer, style termStyle) {
+	for _, file := range r.Files {
+		for _, problem := range file.Errors {
+			fmt.Fprintf(w, "error: %s\n", problem)
+		}
+		for _, problem := range file.Warnings {
+			fmt.Fprintf(w, "warning: %s\n", problem)
+		}
+	}
+	if r.Valid {
+		fmt.Fprintln(w, "OK")
+	}
+}
+
+// configFlags maps top-level keys to the global flags that override them.
+var configFlags = map[string]string{
+	"profile":       "profile",
+	"token_file":    "token-file",
+	"callback_url":  "callback",
+	"timeout":       "timeout",
+	"opener":        "opener",
+	"daemon_socket": "socket",
+}
+
+func newConfigCmd(opts *Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect, edit and validate configuration",
+	}
+	cmd.AddCommand(
+		newConfigPathCmd(opts),
+		newConfigGetCmd(opts),
+		newConfigSetCmd(opts),
+		newConfigUnsetCmd(opts),
+		newConfigListCmd(opts),
+		newConfigEditCmd(opts),
+		newConfigValidateCmd(opts),
+	)
+	for _, sub := range cmd.Commands() {
+		sub.Annotations = map[string]string{annotationLenientConfig: "true"}
+	}
+	return cmd
+}
+
+// configScope holds the --user/--project flags shared by config subcommands.
+type configScope struct {
+	user    bool
+	project bool
+}
+
+func (s *configScope) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&s.user, "user", false, "Use the user config file")
+	cmd.Flags().BoolVar(&s.project, "project", false, "Use the project config file (.grizzly.toml)")
+	cmd.MarkFlagsMutuallyExclusive("user", "project")
+}
+
+func (s *configScope) set() bool { return s.user || s.project }
+
+// path returns the scoped file, or the user config when no scope is given.
+func (s *configScope) path() (string, error) {
+	if s.project {
+		return projectConfigPath()
+	}
+	return userConfigPath()
+}
+
+// paths returns the scoped file, or both files when no scope is given.
+func (s *configScope) paths() ([]string, error) {
+	if s.set() {
+		path, err := s.path()
+		return []string{path}, err
+	}
+	userPath, err := userConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	projectPath, err := projectConfigPath()
+	return []string{userPath, projectPath}, err
+}
+
+func newConfigPathCmd(opts *Options) *cobra.Command {
+	var scope configScope
+	cmd := &cobra.Command{
+		Use:   "path",
+		Short: "Print config file paths",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := NewOutputter(opts)
+			data := map[string]any{}
+			if !scope.project {
+				path, err := userConfigPath()
+				if err != nil {
+					return out.WriteError(Result{Action: "config-path"}, ErrorInfo{Message: err.Error(), Code: "config"}, ExitFailure)
+				}
+				data["user"] = path
+			}
+			if !scope.user {
+				path, err := projectConfigPath()
+				if err != nil {
+					return out.WriteError(Result{Action: "config-path"}, ErrorInfo{Message: err.Error(), Code: "config"}, ExitFailure)
+				}
+				data["project"] = path
+			}
+			out.WriteSuccess(Result{Action: "config-path", Data: data})
+			return nil
+		},
+	}
+	scope.register(cmd)
+	return cmd
+}
+
+func newConfigGetCmd(opts *Options) *cobra.Command {
+	var scope configScope
+	cmd := &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print a config value and where it comes from",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := NewOutputter(opts)
+			key := strings.ToLower(args[0])
+			if _, ok := lookupConfigKey(key); !ok {
+				return usageError(cmd, "unknown config key %q (see grizzly config list)", args[0])
+			}
+			var setting *configSetting
+			if scope.set() {
+				key = profileKey(cmd, opts, key)
+				path, err := scope.path()
+				if err != nil {
+					return out.WriteError(Result{Action: "config-get"}, ErrorInfo{Message: err.Error(), Code: "config"}, ExitFailure)
+				}
+				v, err := openConfigFile(path)
+				if err != nil {
+					return out.WriteError(Result{Action: "config-get"}, ErrorInfo{Message: err.Error(), Code: "config"}, ExitFailure)
+				}
+				if value, ok := configLayerValue(v, key); ok {
+					setting = &configSetting{Key: key, Value: value, Origin: scopeName(scope), Path: path}
+				}
+			} else {
+				settings, err := effectiveConfig(cmd, opts)
+				if err != nil {
+					return out.WriteError(Result{Action: "config-get"}, ErrorInfo{Message: err.Error(), Code: "config"}, ExitFailure)
+				}
+				for i := range settings.Settings {
+					if settings.Settings[i].Key == key {
+						setting = &settings.Settings[i]
+					}
+				}
+			}
+			if setting == nil {
+				return out.WriteError(Result{Action: "config-get"}, ErrorInfo{Message: fmt.Sprintf("%s is not set", key), Code: "not_set"}, ExitFailure)
+			}
+			out.WriteSuccess(Result{Action: "config-get", Data: setting})
+			return nil
+		},
+	}
+	scope.register(cmd)
+	return cmd
+}
+
+func newConfigSetCmd(opts *Options) *cobra.Command {
+	var scope configScope
+	cmd := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a value in the user (default) or project config file",
+		Long: "Set a value in the user (default) or project config file. With --profile,\n" +
+			"top-level keys are written to that profile's section.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := NewOutputter(opts)
+			key := profileKey(cmd, opts, strings.ToLower(args[0]))
+			kind, ok := lookupConfigKey(key)
+			if !ok {
+				return usageError(cmd, "unknown config key %q (see grizzly config list)", args[0])
+			}
+			literal, err := tomlLiteral(kind, args[1])
+			if err != nil {
+				return usageError(cmd, "invalid value for %s: %v", key, err)
+			}
+			if name, ok := strings.CutPrefix(key, "aliases."); ok {
+				if _, err := checkAlias(cmd.Root(), name, args[1]); err != nil {
+					return usageError(cmd, "%v", err)
+				}
+			}
+			if scope.project && isUserOnlyKey(key) {
+				return usageError(cmd, "%s is ignored in project config; set it in the user config", key)
+			}
+			path, err := scope.path()
+			if err == nil {
+				err = editConfigFile(path, func(src []byte) ([]byte, error) {
+					return tomlSet(src, key, literal), nil
+				})
+			}
+			if err != nil {
+				return out.WriteError(Result{Action: "config-set"}, ErrorInfo{Message: err.Error(), Code: "config"}, ExitFailure)
+			}
+			out.WriteSuccess(Result{Action: "config-set", Data: &configSetting{Key: key, Value: args[1], Origin: scopeName(scope), Path: path}})
+			return nil
+		},
+	}
+	scope.register(cmd)
+	return cmd
+}
+
+func newConfigUnsetCmd(opts *Options) *cobra.Command {
+	var scope configScope
+	cmd := &cobra.Command{
+		Use:   "unset <key>",
+		Short: "Remove a value from the user (default) or project config file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := NewOutputter(opts)
+			key := profileKey(cmd, opts, strings.ToLower(args[0]))
+			path, err := scope.path()
+			removed := false
+			if err == nil {
+				err = editConfigFile(path, func(src []byte) ([]byte, error) {
+					var edited []byte
+					edited, removed = tomlUnset(src, key)
+					return edited, nil
+				})
+			}
+			if err != nil {
+				return out.WriteError(Result{Action: "config-unset"}, ErrorInfo{Message: err.Error(), Code: "config"}, ExitFailure)
+			}
+			out.WriteSuccess(Result{Action: "config-unset", Data: map[string]any{"key": key, "path": path, "removed": removed}})
+			return nil
+		},
+	}
+	scope.register(cmd)
+	return cmd
+}
+
+func newConfigListCmd(opts *Options) *cobra.Command {
+	var scope configScope
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List effective config values and their origins",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := NewOutputter(opts)
+			var report *configListReport
+			var err error
+			if scope.set() {
+				report, err = fileConfig(scope)
+			} else {
+				report, err = effectiveConfig(cmd, opts)
+			}
+			if err != nil {
+				return out.WriteError(Result{Action: "config-list"}, ErrorInfo{Message: err.Error(), Code: "config"}, ExitFailure)
+			}
+			out.WriteSuccess(Result{Action: "config-list", Data: report})
+			return nil
+		},
+	}
+	scope.register(cmd)
+	return cmd
+}
+
+func newConfigEditCmd(opts *Options) *cobra.Command {
+	var scope configScope
+	cmd := &cobra.Command{
+		Use:   "edit",
+		Short: "Open the user (default) or project config file in $VISUAL or $EDITOR",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := NewOutputter(opts)
+			if opts.NoInput || !stdinIsTTY() {
+				return usageError(cmd, "config edit needs an interactive terminal")
+			}
+			path, err := scope.path()
+			if err == nil {
+				err = os.MkdirAll(filepath.Dir(path), 0755)
+			}
+			if err != nil {
+				return out.WriteError(Result{Action: "config-edit"}, ErrorInfo{Message: err.Error(), Code: "config"}, ExitFailure)
+			}
+			editor := os.Getenv("VISUAL")
+			if editor == "" {
+				editor = os.Getenv("EDITOR")
+			}
+			if editor == "" {
+				editor = "vi"
+			}
+			run := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+			run.Stdin, run.Stdout, run.Stderr = os.Stdin, os.Stderr, os.Stderr
+			if err := run.Run(); err != nil {
+				return out.WriteError(Result{Action: "config-edit"}, ErrorInfo{Message: fmt.Sprintf("editor: %v", err), Code: "editor"}, ExitFailure)
+			}
+			report := validateConfigFiles(cmd.Root(), []string{path})
+			out.WriteSuccess(Result{Action: "config-edit", Data: report})
+			if !report.Valid {
+				return &ExitError{Code: ExitFailure, Err: fmt.Errorf("%s has problems", path)}
+			}
+			return nil
+		},
+	}
+	scope.register(cmd)
+	return cmd
+}
+
+func newConfigValidateCmd(opts *Options) *cobra.Command {
+	var scope configScope
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check config files for unknown keys and invalid values",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := NewOutputter(opts)
+			paths, err := scope.paths()
+			if err != nil {
+				return out.WriteError(Result{Action: "config-validate"}, ErrorInfo{Message: err.Error(), Code: "config"}, ExitFailure)
+			}
+			report := validateConfigFiles(cmd.Root(), paths)
+			if _, err := LoadProfileConfig(""); err != nil && report.Valid {
+				report.Valid = false
+				report.Files[0].Errors = append(report.Files[0].Errors, err.Error())
+			}
+			out.WriteSuccess(Result{Action: "config-validate", Data: report})
+			if !report.Valid {
+				return &ExitError{Code: ExitFailure, Err: fmt.Errorf("config has problems")}
+			}
+			return nil
+		},
+	}
+	scope.register(cmd)
+	return cmd
+}
+
+func scopeName(scope configScope) string {
+	if scope.project {
+		return "project"
+	}
+	return "user"
+}
+
+// profileKey places a top-level key in the --profile section when the flag
+// was given explicitly.
+func profileKey(cmd *cobra.Command, opts *Options, key string) string {
+	if !cmd.Flags().Changed("profile") || opts.Profile == "" || key == "profile" || strings.HasPrefix(key, "profiles.") {
+		return key
+	}
+	return "profiles." + opts.Profile + "." + key
+}
+
+// validateConfigFiles reports unknown keys (warnings) and invalid values and
+// aliases (errors). Only errors make the report invalid.
+func validateConfigFiles(root *cobra.Command, paths []string) *configValidateReport {
+	report := &configValidateReport{Valid: true}
+	projectPath, _ := projectConfigPath()
+	for _, path := range paths {
+		file := configFileReport{Path: path}
+		v, err := openConfigFile(path)
+		switch {
+		case err != nil:
+			file.Exists = true
+			file.Errors = []string{err.Error()}
+		case v != nil:
+			file.Exists = true
+			file.Warnings, file.Errors = checkConfigFile(v, path)
+			if path == projectPath {
+				file.Errors = append(file.Errors, checkProjectFile(v, path)...)
+			}
+			aliases := v.GetStringMapString("aliases")
+			names := make([]string, 0, len(aliases))
+			for name := range aliases {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if _, err := checkAlias(root, name, aliases[name]); err != nil {
+					file.Errors = append(file.Errors, fmt.Sprintf("%s: %v", path, err))
+				}
+			}
+		}
+		if len(file.Errors) > 0 {
+			report.Valid = false
+		}
+		report.Files = append(report.Files, file)
+	}
+	return report
+}
+
+// configLayerValue reads key from one layer the way applyConfig merges it:
+// empty strings and false do not override lower layers.
+func configLayerValue(v *viper.Viper, key string) (string, bool) {
+	if v == nil || !v.IsSet(key) {
+		return "", false
+	}
+	if kind, _ := lookupConfigKey(key); kind == configBool {
+		return "true", v.GetBool(key)
+	}
+	value := strings.TrimSpace(v.GetString(key))
+	return value, value != ""
+}
+
+// effectiveConfig resolves every known key through the layers LoadConfig
+// uses, lowest first: user, user profile, project, project profile, env and
+// flags.
+func effectiveConfig(cmd *cobra.Command, opts *Options) (*configListReport, error) {
+	userPath, err := userConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	projectPath, err := projectConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	userFile, err := openConfigFile(userPath)
+	if err != nil {
+		return nil, err
+	}
+	projectFile, err := openConfigFile(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	flagProfile := ""
+	if cmd.Flags().Changed("profile") {
+		flagProfile = opts.Profile
+	}
+	profile := resolveConfigProfile(flagProfile, userFile, projectFile)
+
+	type layer struct {
+		origin string
+		path   string
+		lookup func(key string) (string, bool)
+	}
+	fileLayers := func(origin, path string, v *viper.Viper) []layer {
+		layers := []layer{{origin: origin, path: path, lookup: func(key string) (string, bool) { return configLayerValue(v, key) }}}
+		if profile != "" && v != nil {
+			sub := v.Sub("profiles." + profile)
+			layers = append(layers, layer{origin: origin + " profile " + profile, path: path, lookup: func(key string) (string, bool) {
+				if key == "profile" {
+					return "", false
+				}
+				return configLayerValue(sub, key)
+			}})
+		}
+		return layers
+	}
+	projectLayers := fileLayers("project", projectPath, projectFile)
+	for i := range projectLayers {
+		lookup := projectLayers[i].lookup
+		projectLayers[i].lookup = func(key string) (string, bool) {
+			if userOnlyKeys[key] {
+				return "", false
+			}
+			return lookup(key)
+		}
+	}
+	layers := append(fileLayers("user", userPath, userFile), projectLayers...)
+	layers = append(layers,
+		layer{origin: "env", lookup: func(key string) (string, bool) {
+			if strings.Contains(key, ".") {
+				return "", false
+			}
+			value := strings.TrimSpace(os.Getenv("GRIZZLY_" + strings.ToUpper(key)))
+			return value, value != ""
+		}},
+		layer{origin: "flag", lookup: func(key string) (string, bool) {
+			name, ok := configFlags[key]
+			if !ok || !cmd.Flags().Changed(name) {
+				return "", false
+			}
+			return cmd.Flags().Lookup(name).Value.String(), true
+		}},
+	)
+
+	keys := make([]string, 0, len(configKeys))
+	for key := range configKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	report := &configListReport{Profile: profile, Settings: []configSetting{}}
+	layerOf := map[string]int{}
+	for _, key := range keys {
+		var setting *configSetting
+		for i, l := range layers {
+			if value, ok := l.lookup(key); ok {
+				layerOf[key] = i
+				setting = &configSetting{Key: key, Value: value, Origin: l.origin, Path: l.path}
+				if l.origin == "env" {
+					setting.Origin = "env GRIZZLY_" + strings.ToUpper(key)
+				}
+				if l.origin == "flag" {
+					setting.Origin = "flag --" + configFlags[key]
+				}
+			}
+		}
+		if setting != nil {
+			report.Settings = append(report.Settings, *setting)
+		}
+	}
+	// Only the highest layer's token source is used (token_file if one layer
+	// sets both); leave out the other.
+	fileLayer, hasFile := layerOf["token_file"]
+	commandLayer, hasCommand := layerOf["token_command"]
+	if hasFile && hasCommand {
+		shadowed := "token_command"
+		if commandLayer > fileLayer {
+			shadowed = "token_file"
+		}
+		settings := report.Settings[:0]
+		for _, setting := range report.Settings {
+			if setting.Key != shadowed {
+				settings = append(settings, setting)
+			}
+		}
+		report.Settings = settings
+	}
+	return report, nil
+}
+
+// fileConfig lists every key set in one file, including profile sections and
+// unknown keys.
+func fileConfig(scope configScope) (*configListReport, error) {
+	path, err := scope.path()
+	if err != nil {
+		return nil, err
+	}
+	v, err := openConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	report := &configListReport{Settings: []configSetting{}}
+	if v == nil {
+		return report, nil
+	}
+	keys := v.AllKeys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		origin := scopeName(scope)
+		if _, ok := lookupConfigKey(key); !ok {
+			origin += " (unknown key)"
+		}
+		report.Settings = append(report.Settings, configSetting{Key: key, Value: v.GetString(key), Origin: origin, Path: path})
+	}
+	return report, nil
+}
+
+// editConfigFile rewrites path through edit, refusing results that are not
+// valid TOML, and replaces the file atomically with its mode preserved.
+func editConfigFile(path string, edit func(src []byte) ([]byte, error)) error {
+	mode := os.FileMode(0644)
+	src, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+	case os.IsNotExist(err):
+		src = nil
+	default:
+		return err
+	}
+	edited, err := edit(src)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(edited, src) {
+		return nil
+	}
+	check := viper.New()
+	check.SetConfigType("toml")
+	if err := check.ReadConfig(bytes.NewReader(edited)); err != nil {
+		return fmt.Errorf("refusing to write %s: the edit would not be valid TOML: %w", path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, edited, mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// tomlLiteral encodes a command-line value as a TOML value of the key's kind.
+func tomlLiteral(kind configKeyKind, value string) (string, error) {
+	switch kind {
+	case configBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("want true or false")
+		}
+		return strconv.FormatBool(b), nil
+	case configDuration:
+		if _, err := time.ParseDuration(value); err != nil {
+			return "", err
+		}
+	}
+	return tomlString(value), nil
+}
+
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+var (
+	tomlTableLine = regexp.MustCompile(`^\s*\[([^\[\]]+)\]\s*(?:#.*)?$`)
+	tomlKeyLine   = regexp.MustCompile(`^\s*([A-Za-z0-9_\-."' ]+?)\s*=`)
+)
+
+// tomlLines splits src into lines and returns, for each line, the dotted key
+// it assigns (table prefix included) or "" for other lines, plus the table
+// each line belongs to.
+func tomlLines(src []byte) (lines, keys, tables []string) {
+	text := strings.TrimSuffix(string(src), "\n")
+	if text != "" {
+		lines = strings.Split(text, "\n")
+	}
+	table := ""
+	for _, line := range lines {
+		key := ""
+		if m := tomlTableLine.FindStringSubmatch(line); m != nil && !strings.HasPrefix(strings.TrimSpace(line), "[[") {
+			table = normalizeTOMLKey(m[1])
+		} else if m := tomlKeyLine.FindStringSubmatch(line); m != nil && !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			key = normalizeTOMLKey(m[1])
+			if table != "" {
+				key = table + "." + key
+			}
+		}
+		keys = append(keys, key)
+		tables = append(tables, table)
+	}
+	return lines, keys, tables
+}
+
+func normalizeTOMLKey(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		parts[i] = strings.ToLower(strings.Trim(strings.TrimSpace(part), `"'`))
+	}
+	return strings.Join(parts, ".")
+}
+
+// tomlSet assigns key = literal, replacing an existing assignment in place so
+// comments and layout survive, or adding it to the key's table.
+func tomlSet(src []byte, key, literal string) []byte {
+	lines, keys, tables := tomlLines(src)
+	table, leaf := "", key
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		table, leaf = key[:i], key[i+1:]
+	}
+	assignment := leaf + " = " + literal
+	for i, k := range keys {
+		if k == key {
+			indent := lines[i][:len(lines[i])-len(strings.TrimLeft(lines[i], " \t"))]
+			written := assignment
+			if tables[i] != table || !strings.HasPrefix(strings.TrimSpace(lines[i]), leaf) {
+				// A dotted key such as daily.format = "..." at the top level.
+				written = strings.TrimSpace(strings.SplitN(lines[i], "=", 2)[0]) + " = " + literal
+			}
+			lines[i] = indent + written
+			return joinTOMLLines(lines)
+		}
+	}
+
+	// Insert after the last assignment in the table, or before the first
+	// table header for top-level keys.
+	insert := -1
+	for i := range lines {
+		if tables[i] == table && (keys[i] != "" || tomlTableLine.MatchString(lines[i])) {
+			insert = i + 1
+		}
+		if table == "" && tables[i] != "" {
+			if insert < 0 {
+				insert = i
+				for insert > 0 && strings.TrimSpace(lines[insert-1]) == "" {
+					insert--
+				}
+			}
+			break
+		}
+	}
+	if table == "" && insert < 0 {
+		insert = len(lines)
+	}
+	if insert < 0 {
+		if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "["+table+"]", assignment)
+		return joinTOMLLines(lines)
+	}
+	lines = append(lines[:insert], append([]string{assignment}, lines[insert:]...)...)
+	return joinTOMLLines(lines)
+}
+
+// tomlUnset removes the assignment of key and reports whether it existed.
+func tomlUnset(src []byte, key string) ([]byte, bool) {
+	lines, keys, _ := tomlLines(src)
+	for i, k := range keys {
+		if k == key {
+			lines = append(lines[:i], lines[i+1:]...)
+			return joinTOMLLines(lines), true
+		}
+	}
+	return src, false
+}
+
+func joinTOMLLines(lines []string) []byte {
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
@@ -0,0 +1,175 @@
+package grizzly
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTOMLSet(t *testing.T) {
+	src := "# grizzly\ntoken_file = \"old\" # mine\n\n[daily]\nformat = \"2006\"\n\n[profiles.work]\ntimeout = \"1s\"\n"
+	cases := []struct {
+		key, literal, want string
+	}{
+		{"token_file", `"new"`, "# grizzly\ntoken_file = \"new\"\n\n[daily]\nformat = \"2006\"\n\n[profiles.work]\ntimeout = \"1s\"\n"},
+		{"timeout", `"5s"`, "# grizzly\ntoken_file = \"old\" # mine\ntimeout = \"5s\"\n\n[daily]\nformat = \"2006\"\n\n[profiles.work]\ntimeout = \"1s\"\n"},
+		{"daily.tag", `"journal"`, "# grizzly\ntoken_file = \"old\" # mine\n\n[daily]\nformat = \"2006\"\ntag = \"journal\"\n\n[profiles.work]\ntimeout = \"1s\"\n"},
+		{"profiles.work.timeout", `"9s"`, "# grizzly\ntoken_file = \"old\" # mine\n\n[daily]\nformat = \"2006\"\n\n[profiles.work]\ntimeout = \"9s\"\n"},
+		{"inbox.daily", `true`, src + "\n[inbox]\ndaily = true\n"},
+	}
+	for _, tc := range cases {
+		if got := string(tomlSet([]byte(src), tc.key, tc.literal)); got != tc.want {
+			t.Fatalf("set %s:\n got %q\nwant %q", tc.key, got, tc.want)
+		}
+	}
+	if got := string(tomlSet(nil, "opener", `"fake"`)); got != "opener = \"fake\"\n" {
+		t.Fatalf("set in empty file = %q", got)
+	}
+	if got := string(tomlSet([]byte("[daily]\nformat = \"x\"\n"), "opener", `"fake"`)); got != "opener = \"fake\"\n[daily]\nformat = \"x\"\n" {
+		t.Fatalf("set top-level before tables = %q", got)
+	}
+
+	got, removed := tomlUnset([]byte(src), "daily.format")
+	if !removed || strings.Contains(string(got), "2006") {
+		t.Fatalf("unset = %q, %v", got, removed)
+	}
+	if _, removed := tomlUnset([]byte(src), "daily.tag"); removed {
+		t.Fatalf("unset of a missing key reported removal")
+	}
+}
+
+func TestTOMLLiteral(t *testing.T) {
+	if got, err := tomlLiteral(configString, "a \"b\"\n\\"); err != nil || got != `"a \"b\"\n\\"` {
+		t.Fatalf("string literal = %q, %v", got, err)
+	}
+	if got, err := tomlLiteral(configBool, "yes"); err == nil {
+		t.Fatalf("bool literal accepted %q", got)
+	}
+	if _, err := tomlLiteral(configDuration, "5 minutes"); err == nil {
+		t.Fatalf("duration literal accepted a malformed value")
+	}
+}
+
+func TestConfigCommands(t *testing.T) {
+	root := t.TempDir()
+	projectDir := filepath.Join(root, "project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(projectDir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	defer func() {
+		_ = os.Chdir(oldWd)
+	}()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "user"))
+	for _, name := range []string{"GRIZZLY_PROFILE", "GRIZZLY_TIMEOUT", "GRIZZLY_OPENER", "GRIZZLY_TOKEN_FILE"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+
+	run := func(args ...string) (string, error) {
+		cmd := NewRootCmd()
+		var buf bytes.Buffer
+		cmd.SetArgs(append([]string{"--plain"}, args...))
+		stdout := os.Stdout
+		r, w, _ := os.Pipe()
+		os.Stdout = w
+		err := cmd.Execute()
+		w.Close()
+		os.Stdout = stdout
+		_, _ = buf.ReadFrom(r)
+		return buf.String(), err
+	}
+
+	if _, err := run("config", "set", "timeout", "3s"); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if _, err := run("--profile", "work", "config", "set", "token_file", "/work"); err != nil {
+		t.Fatalf("set in profile: %v", err)
+	}
+	if _, err := run("config", "set", "--project", "timeout", "4s"); err != nil {
+		t.Fatalf("set project: %v", err)
+	}
+	user, err := os.ReadFile(filepath.Join(root, "user", "grizzly", "config.toml"))
+	if err != nil || string(user) != "timeout = \"3s\"\n\n[profiles.work]\ntoken_file = \"/work\"\n" {
+		t.Fatalf("user config = %q, %v", user, err)
+	}
+
+	out, err := run("config", "get", "timeout")
+	if err != nil || !strings.Contains(out, "value=4s\n") || !strings.Contains(out, "origin=project\n") {
+		t.Fatalf("get = %q, %v", out, err)
+	}
+	t.Setenv("GRIZZLY_TIMEOUT", "7s")
+	out, err = run("--profile", "work", "config", "list")
+	if err != nil || !strings.Contains(out, "env GRIZZLY_TIMEOUT") || !strings.Contains(out, "user profile work") {
+		t.Fatalf("list = %q, %v", out, err)
+	}
+	out, err = run("config", "get", "timeout", "--user")
+	if err != nil || !strings.Contains(out, "value=3s\n") {
+		t.Fatalf("get --user = %q, %v", out, err)
+	}
+
+	if _, err := run("config", "set", "timeout", "soon"); ExitCode(err) != ExitUsage {
+		t.Fatalf("malformed duration: %v", err)
+	}
+	if _, err := run("config", "set", "colour", "on"); ExitCode(err) != ExitUsage {
+		t.Fatalf("unknown key: %v", err)
+	}
+
+	if _, err := run("config", "unset", "--project", "timeout"); err != nil {
+		t.Fatalf("unset: %v", err)
+	}
+	if err := os.WriteFile(".grizzly.toml", []byte("timeout = \"later\"\ncolour = \"on\"\n"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out, err = run("config", "validate")
+	if ExitCode(err) != ExitFailure || !strings.Contains(out, "unknown key") || !strings.Contains(out, "colour") || !strings.Contains(out, "timeout is not a duration") {
+		t.Fatalf("validate = %q, %v", out, err)
+	}
+
+	// A project file cannot run commands or redirect the token.
+	if _, err := run("config", "set", "--project", "token_command", "touch x"); ExitCode(err) != ExitUsage {
+		t.Fatalf("set --project token_command: %v", err)
+	}
+	pwned := filepath.Join(root, "pwned")
+	project := "token_command = \"touch " + pwned + "\"\n\n[profiles.work]\nopener_command = \"touch " + pwned + "\"\n"
+	if err := os.WriteFile(".grizzly.toml", []byte(project), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	t.Setenv("GRIZZLY_TOKEN_COMMAND", "")
+	os.Unsetenv("GRIZZLY_TOKEN_COMMAND")
+	_, _ = run("--opener", "fake", "tags")
+	if _, err := os.Stat(pwned); !os.IsNotExist(err) {
+		t.Fatalf("project token_command ran: %v", err)
+	}
+	out, err = run("config", "validate")
+	if ExitCode(err) != ExitFailure || !strings.Contains(out, "token_command is ignored in project config") || !strings.Contains(out, "profiles.work.opener_command is ignored") {
+		t.Fatalf("validate project = %q, %v", out, err)
+	}
+	if out, err := run("config", "get", "token_command"); err == nil && strings.Contains(out, "touch") {
+		t.Fatalf("get token_command = %q", out)
+	}
+
+	// A broken opener setting can still be fixed with config set.
+	store := filepath.Join(root, "store.json")
+	if err := os.WriteFile(store, []byte("{not json"), 0644); err != nil {
+		t.Fatalf("write store: %v", err)
+	}
+	t.Setenv("GRIZZLY_FAKE_BEAR_STORE", store)
+	if _, err := run("--opener", "fake", "config", "set", "opener", "fake"); err != nil {
+		t.Fatalf("set with a corrupt fake store: %v", err)
+	}
+	if _, err := run("--opener", "bogus", "config", "set", "opener", "open"); err != nil {
+		t.Fatalf("set with an unknown opener: %v", err)
+	}
+	if _, err := run("--opener", "bogus", "tags"); ExitCode(err) != ExitUsage {
+		t.Fatalf("unknown opener: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package grizzly
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -75,3 +76,102 @@ func TestLoadConfigTimeoutZero(t *testing.T) {
 		t.Fatalf("Timeout = %v, TimeoutSet = %v", cfg.Timeout, cfg.TimeoutSet)
 	}
 }
+
+func TestLoadProfileConfig(t *testing.T) {
+	root := t.TempDir()
+	projectDir := filepath.Join(root, "project")
+	userConfig := filepath.Join(root, "user", "grizzly", "config.toml")
+	if err := os.MkdirAll(filepath.Dir(userConfig), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	user := `profile = "personal"
+token_file = "user"
+callback_url = "http://user"
+timeout = "1s"
+
+[profiles.personal]
+token_file = "personal"
+
+[profiles.work]
+token_file = "work"
+callback_url = "http://work"
+
+[profiles.work.daily]
+tag = "work/journal"
+`
+	if err := os.WriteFile(userConfig, []byte(user), 0644); err != nil {
+		t.Fatalf("write user config: %v", err)
+	}
+	project := "callback_url = \"http://project\"\n\n[profiles.work]\ntimeout = \"4s\"\n"
+	if err := os.WriteFile(filepath.Join(projectDir, ".grizzly.toml"), []byte(project), 0644); err != nil {
+		t.Fatalf("write project config: %v", err)
+	}
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(projectDir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	defer func() {
+		_ = os.Chdir(oldWd)
+	}()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "user"))
+	t.Setenv("GRIZZLY_PROFILE", "")
+
+	cfg, err := LoadProfileConfig("")
+	if err != nil {
+		t.Fatalf("LoadProfileConfig: %v", err)
+	}
+	if cfg.Profile != "personal" || cfg.TokenFile != "personal" || cfg.CallbackURL != "http://project" || cfg.Timeout != time.Second {
+		t.Fatalf("default profile = %+v", cfg)
+	}
+
+	t.Setenv("GRIZZLY_PROFILE", "work")
+	cfg, err = LoadProfileConfig("")
+	if err != nil {
+		t.Fatalf("LoadProfileConfig: %v", err)
+	}
+	// The project's top-level callback_url beats the user's work profile.
+	if cfg.Profile != "work" || cfg.TokenFile != "work" || cfg.CallbackURL != "http://project" || cfg.Timeout != 4*time.Second || cfg.Daily.Tag != "work/journal" {
+		t.Fatalf("env profile = %+v", cfg)
+	}
+
+	t.Setenv("GRIZZLY_TOKEN_FILE", "env")
+	cfg, err = LoadProfileConfig("personal")
+	if err != nil {
+		t.Fatalf("LoadProfileConfig: %v", err)
+	}
+	if cfg.Profile != "personal" || cfg.TokenFile != "env" {
+		t.Fatalf("flag profile = %+v", cfg)
+	}
+
+	// A token_command in a higher layer beats a token_file in a lower one.
+	t.Setenv("GRIZZLY_TOKEN_FILE", "")
+	os.Unsetenv("GRIZZLY_TOKEN_FILE")
+	t.Setenv("GRIZZLY_TOKEN_COMMAND", "pass bear")
+	cfg, err = LoadProfileConfig("personal")
+	if err != nil || cfg.TokenCommand != "pass bear" || cfg.TokenFile != "" {
+		t.Fatalf("env token_command = %+v, %v", cfg, err)
+	}
+	os.Unsetenv("GRIZZLY_TOKEN_COMMAND")
+	user += "\n[profiles.helper]\ntoken_command = \"op read bear\"\n"
+	if err := os.WriteFile(userConfig, []byte(user), 0644); err != nil {
+		t.Fatalf("write user config: %v", err)
+	}
+	cfg, err = LoadProfileConfig("helper")
+	if err != nil || cfg.TokenCommand != "op read bear" || cfg.TokenFile != "" {
+		t.Fatalf("profile token_command = %+v, %v", cfg, err)
+	}
+
+	if _, err := LoadProfileConfig("missing"); err == nil || !strings.Contains(err.Error(), `profile "missing"`) {
+		t.Fatalf("missing profile error = %v", err)
+	}
+	names, err := configProfiles()
+	if err != nil || strings.Join(names, ",") != "helper,personal,work" {
+		t.Fatalf("configProfiles = %v, %v", names, err)
+	}
+}
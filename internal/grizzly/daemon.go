@@ -0,0 +1,395 @@
+package grizzly
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"grizzly/pkg/bear"
+)
+
+const (
+	daemonOpCall   = "call"
+	daemonOpStatus = "status"
+	daemonOpStop   = "stop"
+
+	daemonProbeTimeout = 200 * time.Millisecond
+)
+
+// daemonRequest is one newline-terminated JSON message sent over the daemon
+// socket. Each connection carries a single request.
+type daemonRequest struct {
+	Op       string     `json:"op"`
+	Action   string     `json:"action,omitempty"`
+	Params   url.Values `json:"params,omitempty"`
+	Deadline time.Time  `json:"deadline,omitempty"`
+}
+
+type daemonResponse struct {
+	URL    string        `json:"url,omitempty"`
+	Values url.Values    `json:"values,omitempty"`
+	Error  *daemonError  `json:"error,omitempty"`
+	Status *daemonStatus `json:"status,omitempty"`
+}
+
+type daemonError struct {
+	Kind    bear.ErrorKind `json:"kind"`
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+}
+
+type daemonStatus struct {
+	PID     int       `json:"pid"`
+	Socket  string    `json:"socket"`
+	Started time.Time `json:"started"`
+	Served  int64     `json:"served"`
+	Queued  int64     `json:"queued"`
+	Waiting int       `json:"waiting"`
+}
+
+type daemonJob struct {
+	ctx  context.Context
+	req  *bear.Request
+	done chan daemonResponse
+}
+
+// daemon owns one callback listener and runs Bear calls one at a time, in
+// the order they arrive.
+type daemon struct {
+	socket    string
+	transport *bear.URLTransport
+	callbacks *bear.SharedCallbacks
+	verbose   bool
+
+	jobs    chan *daemonJob
+	stopped chan struct{}
+	once    sync.Once
+	started time.Time
+	served  atomic.Int64
+	queued  atomic.Int64
+}
+
+func newDaemon(opts *Options, socket string) (*daemon, error) {
+	opener, err := newOpener(opts)
+	if err != nil {
+		return nil, err
+	}
+	callbacks := &bear.SharedCallbacks{Scheme: callbackScheme, Source: callbackSource, OnReject: callbackRejectLogger(opts)}
+	return &daemon{
+		socket:    socket,
+		transport: &bear.URLTransport{Opener: opener, Callbacks: callbacks, Source: callbackSource},
+		callbacks: callbacks,
+		verbose:   opts.Verbose,
+		jobs:      make(chan *daemonJob),
+		stopped:   make(chan struct{}),
+		started:   time.Now(),
+	}, nil
+}
+
+// listenDaemon opens the daemon socket, replacing a stale one left behind by
+// a daemon that did not shut down cleanly.
+func listenDaemon(socket string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(socket), 0o700); err != nil {
+		return nil, err
+	}
+	if err := checkDaemonDir(filepath.Dir(socket)); err != nil {
+		return nil, err
+	}
+	if daemonReachable(socket) {
+		return nil, fmt.Errorf("daemon already running on %s", socket)
+	}
+	if err := os.Remove(socket); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socket, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+func (d *daemon) serve(ctx context.Context, ln net.Listener) error {
+	defer d.callbacks.Close()
+	go d.work()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-d.stopped:
+		}
+		ln.Close()
+	}()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			d.stop()
+			select {
+			case <-d.stopped:
+				return nil
+			default:
+				return err
+			}
+		}
+		go d.handle(conn)
+	}
+}
+
+func (d *daemon) stop() {
+	d.once.Do(func() {
+		close(d.stopped)
+	})
+}
+
+func (d *daemon) work() {
+	for {
+		select {
+		case <-d.stopped:
+			return
+		case job := <-d.jobs:
+			d.queued.Add(-1)
+			job.done <- d.run(job)
+		}
+	}
+}
+
+func (d *daemon) run(job *daemonJob) daemonResponse {
+	if err := job.ctx.Err(); err != nil {
+		return daemonResponse{Error: &daemonError{Kind: bear.KindTimeout, Code: bear.CodeTimeout, Message: "callback timed out"}}
+	}
+	d.logf("%s", job.req.Action)
+	resp, err := d.transport.RoundTrip(job.ctx, job.req)
+	d.served.Add(1)
+	if err != nil {
+		var be *bear.Error
+		if !errors.As(err, &be) {
+			return daemonResponse{Error: &daemonError{Kind: bear.KindTransport, Code: "daemon", Message: err.Error()}}
+		}
+		d.logf("%s failed: %s", job.req.Action, be.Message)
+		return daemonResponse{URL: be.URL, Error: &daemonError{Kind: be.Kind, Code: be.Code, Message: be.Message}}
+	}
+	return daemonResponse{URL: resp.URL, Values: resp.Values}
+}
+
+func (d *daemon) handle(conn net.Conn) {
+	defer conn.Close()
+	var req daemonRequest
+	dec := json.NewDecoder(conn)
+	if err := dec.Decode(&req); err != nil {
+		if !errors.Is(err, io.EOF) {
+			d.logf("bad request: %v", err)
+		}
+		return
+	}
+	enc := json.NewEncoder(conn)
+	switch req.Op {
+	case daemonOpStatus:
+		_ = enc.Encode(daemonResponse{Status: d.status()})
+	case daemonOpStop:
+		_ = enc.Encode(daemonResponse{Status: d.status()})
+		d.stop()
+	case daemonOpCall:
+		_ = enc.Encode(d.call(conn, req))
+	default:
+		_ = enc.Encode(daemonResponse{Error: &daemonError{Kind: bear.KindTransport, Code: "daemon", Message: fmt.Sprintf("unknown op %q", req.Op)}})
+	}
+}
+
+// call queues a request and waits for its turn. The request is abandoned if
+// the client hangs up or its deadline passes.
+func (d *daemon) call(conn net.Conn, req daemonRequest) daemonResponse {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if !req.Deadline.IsZero() {
+		ctx, cancel = context.WithDeadline(ctx, req.Deadline)
+		defer cancel()
+	}
+	go func() {
+		_, _ = io.Copy(io.Discard, conn)
+		cancel()
+	}()
+
+	job := &daemonJob{
+		ctx:  ctx,
+		req:  &bear.Request{Action: req.Action, Params: req.Params},
+		done: make(chan daemonResponse, 1),
+	}
+	d.queued.Add(1)
+	select {
+	case d.jobs <- job:
+	case <-ctx.Done():
+		d.queued.Add(-1)
+		return daemonResponse{Error: &daemonError{Kind: bear.KindTimeout, Code: bear.CodeTimeout, Message: "callback timed out"}}
+	case <-d.stopped:
+		d.queued.Add(-1)
+		return daemonResponse{Error: &daemonError{Kind: bear.KindTransport, Code: "daemon", Message: "daemon stopped"}}
+	}
+	return <-job.done
+}
+
+func (d *daemon) status() *daemonStatus {
+	return &daemonStatus{
+		PID:     os.Getpid(),
+		Socket:  d.socket,
+		Started: d.started,
+		Served:  d.served.Load(),
+		Queued:  d.queued.Load(),
+		Waiting: d.callbacks.Pending(),
+	}
+}
+
+func (d *daemon) logf(format string, args ...any) {
+	if d.verbose {
+		fmt.Fprintf(os.Stderr, "grizzly daemon: "+format+"\n", args...)
+	}
+}
+
+// daemonTransport sends requests to a running daemon instead of opening URLs
+// in this process.
+type daemonTransport struct {
+	socket string
+}
+
+func (t *daemonTransport) RoundTrip(ctx context.Context, req *bear.Request) (*bear.Response, error) {
+	req2 := daemonRequest{Op: daemonOpCall, Action: req.Action, Params: req.Params}
+	if deadline, ok := ctx.Deadline(); ok {
+		req2.Deadline = deadline
+	}
+	resp, err := daemonRoundTrip(ctx, t.socket, req2)
+	if err != nil {
+		return nil, &bear.Error{Kind: bear.KindTransport, Action: req.Action, Code: "daemon", Message: err.Error(), Err: err}
+	}
+	if resp.Error != nil {
+		return nil, &bear.Error{Kind: resp.Error.Kind, Action: req.Action, URL: resp.URL, Code: resp.Error.Code, Message: resp.Error.Message}
+	}
+	values := resp.Values
+	if values == nil {
+		values = url.Values{}
+	}
+	return &bear.Response{Action: req.Action, URL: resp.URL, Values: values}, nil
+}
+
+// daemonRoundTrip sends one request and reads the reply. The connection stays
+// open for writing until the reply arrives so that the daemon can tell a
+// client that gave up.
+func daemonRoundTrip(ctx context.Context, socket string, req daemonRequest) (daemonResponse, error) {
+	var resp daemonResponse
+	if err := checkDaemonSocket(socket); err != nil {
+		return resp, err
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socket)
+	if err != nil {
+		return resp, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() {
+		// Leave the daemon a moment to report its own timeout first.
+		time.AfterFunc(time.Second, func() { conn.Close() })
+	})
+	defer stop()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return resp, err
+	}
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		if ctx.Err() != nil {
+			return resp, ctx.Err()
+		}
+		return resp, fmt.Errorf("daemon: %w", err)
+	}
+	return resp, nil
+}
+
+func daemonReachable(socket string) bool {
+	conn, err := net.DialTimeout("unix", socket, daemonProbeTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// daemonSocketPath returns the configured socket, or one under
+// $XDG_RUNTIME_DIR (falling back to the temp dir).
+func daemonSocketPath(opts *Options) (string, error) {
+	if opts.DaemonSocket != "" {
+		return expandPath(opts.DaemonSocket)
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "grizzly", "daemon.sock"), nil
+	}
+	return filepath.Join(os.TempDir(), "grizzly-"+strconv.Itoa(os.Getuid()), "daemon.sock"), nil
+}
+
+// runningDaemon returns the socket of a reachable daemon, if any.
+func runningDaemon(opts *Options) (string, bool) {
+	if opts.NoDaemon {
+		return "", false
+	}
+	socket, err := daemonSocketPath(opts)
+	if err != nil {
+		return "", false
+	}
+	if _, err := os.Stat(socket); err != nil {
+		return "", false
+	}
+	if err := checkDaemonSocket(socket); err != nil {
+		fmt.Fprintf(os.Stderr, "grizzly: not using the daemon: %v\n", err)
+		return "", false
+	}
+	return socket, daemonReachable(socket)
+}
+
+// checkDaemonDir refuses a socket directory another local user could have
+// created or could write to: requests carry the token and replies are
+// trusted as Bear's.
+func checkDaemonDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if uid, ok := fileOwner(info); ok && uid != os.Getuid() {
+		return fmt.Errorf("%s is owned by uid %d, not by you", dir, uid)
+	}
+	if perm := info.Mode().Perm(); perm != 0o700 && runtime.GOOS != "windows" {
+		return fmt.Errorf("%s has mode %#o; chmod 700 it", dir, perm)
+	}
+	return nil
+}
+
+// checkDaemonSocket refuses a daemon socket that is not ours, or that lives
+// in a directory checkDaemonDir refuses.
+func checkDaemonSocket(socket string) error {
+	if err := checkDaemonDir(filepath.Dir(socket)); err != nil {
+		return err
+	}
+	info, err := os.Lstat(socket)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Type() != fs.ModeSocket && runtime.GOOS != "windows" {
+		return fmt.Errorf("%s is not a socket", socket)
+	}
+	if uid, ok := fileOwner(info); ok && uid != os.Getuid() {
+		return fmt.Errorf("%s is owned by uid %d, not by you", socket, uid)
+	}
+	return nil
+}
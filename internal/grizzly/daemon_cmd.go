@@ -0,0 +1,181 @@
+package grizzly
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newDaemonCmd(opts *Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Run a background process that owns the callback listener",
+		Long: `Run a background process that owns the callback listener.
+
+While the daemon is running, other grizzly invocations send their requests to
+it over a Unix socket instead of opening Bear themselves. The daemon runs one
+Bear call at a time, in arrival order, and routes each callback back to the
+invocation that made the request. URLs are opened with the daemon's opener.
+Use --no-daemon to bypass it.`,
+	}
+	cmd.PersistentFlags().StringVar(&opts.DaemonSocket, "socket", "", "Unix socket path (default: $XDG_RUNTIME_DIR/grizzly/daemon.sock)")
+	cmd.AddCommand(newDaemonRunCmd(opts))
+	cmd.AddCommand(newDaemonStartCmd(opts))
+	cmd.AddCommand(newDaemonStatusCmd(opts))
+	cmd.AddCommand(newDaemonStopCmd(opts))
+	return cmd
+}
+
+func newDaemonRunCmd(opts *Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "run",
+		Short: "Run the daemon in the foreground",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			socket, err := daemonSocketPath(opts)
+			if err != nil {
+				return &ExitError{Code: ExitFailure, Err: err}
+			}
+			d, err := newDaemon(opts, socket)
+			if err != nil {
+				return usageError(cmd, "%v", err)
+			}
+			ln, err := listenDaemon(socket)
+			if err != nil {
+				return &ExitError{Code: ExitFailure, Err: err}
+			}
+			defer os.Remove(socket)
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			fmt.Fprintf(os.Stderr, "grizzly daemon listening on %s\n", socket)
+			if err := d.serve(ctx, ln); err != nil {
+				return &ExitError{Code: ExitFailure, Err: err}
+			}
+			return nil
+		},
+	}
+}
+
+func newDaemonStartCmd(opts *Options) *cobra.Command {
+	var logFile string
+
+	cmd := &cobra.Command{
+		Use:   "start",
+		Short: "Start the daemon in the background",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := NewOutputter(opts)
+			socket, err := daemonSocketPath(opts)
+			if err != nil {
+				return &ExitError{Code: ExitFailure, Err: err}
+			}
+			if !daemonReachable(socket) {
+				if err := spawnDaemon(opts, socket, logFile); err != nil {
+					return out.WriteError(Result{Action: "daemon-start"}, ErrorInfo{Message: err.Error(), Code: "daemon_start"}, ExitFailure)
+				}
+			}
+			return writeDaemonStatus(out, "daemon-start", socket, daemonOpStatus)
+		},
+	}
+	cmd.Flags().StringVar(&logFile, "log", "", "Write daemon diagnostics to this file")
+	return cmd
+}
+
+func newDaemonStatusCmd(opts *Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show whether the daemon is running",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			socket, err := daemonSocketPath(opts)
+			if err != nil {
+				return &ExitError{Code: ExitFailure, Err: err}
+			}
+			return writeDaemonStatus(NewOutputter(opts), "daemon-status", socket, daemonOpStatus)
+		},
+	}
+}
+
+func newDaemonStopCmd(opts *Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the daemon",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			socket, err := daemonSocketPath(opts)
+			if err != nil {
+				return &ExitError{Code: ExitFailure, Err: err}
+			}
+			return writeDaemonStatus(NewOutputter(opts), "daemon-stop", socket, daemonOpStop)
+		},
+	}
+}
+
+func writeDaemonStatus(out *Outputter, action, socket, op string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	resp, err := daemonRoundTrip(ctx, socket, daemonRequest{Op: op})
+	if err != nil {
+		return out.WriteError(Result{Action: action}, ErrorInfo{Message: fmt.Sprintf("daemon not running on %s", socket), Code: "daemon_not_running"}, ExitFailure)
+	}
+	out.WriteSuccess(Result{Action: action, Data: resp.Status})
+	return nil
+}
+
+// spawnDaemon re-executes this binary as "daemon run" detached from the
+// terminal and waits for its socket to come up.
+func spawnDaemon(opts *Options, socket, logFile string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	args := []string{"daemon", "run", "--socket", socket}
+	if opts.Opener != "" {
+		args = append(args, "--opener", opts.Opener)
+	}
+	if logFile != "" {
+		args = append(args, "--verbose")
+	}
+	child := exec.Command(exe, args...)
+	if logFile != "" {
+		path, err := expandPath(logFile)
+		if err != nil {
+			return err
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		child.Stdout = f
+		child.Stderr = f
+	}
+	detach(child)
+	if err := child.Start(); err != nil {
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- child.Wait()
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		if daemonReachable(socket) {
+			return nil
+		}
+		select {
+		case err := <-exited:
+			if err == nil {
+				err = fmt.Errorf("exited")
+			}
+			return fmt.Errorf("daemon failed to start: %w", err)
+		case <-deadline:
+			return fmt.Errorf("daemon did not come up on %s", socket)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
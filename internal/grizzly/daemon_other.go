@@ -0,0 +1,12 @@
+//go:build !unix
+
+package grizzly
+
+import (
+	"io/fs"
+	"os/exec"
+)
+
+func detach(cmd *exec.Cmd) {}
+
+func fileOwner(info fs.FileInfo) (int, bool) { return 0, false }
@@ -0,0 +1,118 @@
+package grizzly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"grizzly/pkg/bear"
+)
+
+func TestDaemonCorrelatesConcurrentRequests(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gzd")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "d.sock")
+
+	d, err := newDaemon(&Options{Opener: OpenerFake}, socket)
+	if err != nil {
+		t.Fatalf("newDaemon: %v", err)
+	}
+	ln, err := listenDaemon(socket)
+	if err != nil {
+		t.Fatalf("listenDaemon: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- d.serve(ctx, ln)
+	}()
+
+	if _, ok := runningDaemon(&Options{DaemonSocket: socket}); !ok {
+		t.Fatalf("daemon not detected")
+	}
+	if _, ok := runningDaemon(&Options{DaemonSocket: socket, NoDaemon: true}); ok {
+		t.Fatalf("--no-daemon ignored")
+	}
+
+	client := &bear.Client{Transport: &daemonTransport{socket: socket}}
+	callCtx, callCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer callCancel()
+
+	const n = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			title := fmt.Sprintf("Note %d", i)
+			res, err := client.Create(callCtx, bear.CreateOptions{Title: title, Text: "x"})
+			if err != nil {
+				errs <- err
+				return
+			}
+			if res.Title != title || res.Identifier == "" {
+				errs <- fmt.Errorf("request %q got %#v", title, res)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	_, err = client.OpenNote(callCtx, bear.OpenNoteOptions{NoteRef: bear.NoteRef{ID: "missing"}})
+	var be *bear.Error
+	if !errors.As(err, &be) || be.Kind != bear.KindBear || be.Code != fakeErrNoteNotFound {
+		t.Fatalf("err = %#v", err)
+	}
+
+	if got := d.status().Served; got != n+1 {
+		t.Fatalf("served = %d, want %d", got, n+1)
+	}
+	cancel()
+	if err := <-served; err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+}
+
+func TestDaemonRefusesUnsafeSocketDir(t *testing.T) {
+	root := t.TempDir()
+	shared := filepath.Join(root, "shared")
+	if err := os.Mkdir(shared, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.Chmod(shared, 0o755); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	if _, err := listenDaemon(filepath.Join(shared, "d.sock")); err == nil {
+		t.Fatalf("listened in a 0755 directory")
+	}
+	private := filepath.Join(root, "private")
+	if err := os.Mkdir(private, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(private, link); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	if _, err := listenDaemon(filepath.Join(link, "d.sock")); err == nil {
+		t.Fatalf("listened through a symlinked directory")
+	}
+	if err := os.WriteFile(filepath.Join(private, "d.sock"), nil, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := checkDaemonSocket(filepath.Join(private, "d.sock")); err == nil {
+		t.Fatalf("regular file accepted as the daemon socket")
+	}
+}
@@ -0,0 +1,314 @@
+package grizzly
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"grizzly/pkg/bear"
+)
+
+const (
+	periodDay   = "day"
+	periodWeek  = "week"
+	periodMonth = "month"
+)
+
+const (
+	defaultDailyFormat = "2006-01-02"
+	defaultWeekFormat  = "2006-W{week}"
+	defaultMonthFormat = "2006-01"
+)
+
+type dailyReport struct {
+	Period     string `json:"period"`
+	Date       string `json:"date"`
+	Title      string `json:"title"`
+	Identifier string `json:"identifier"`
+	Created    bool   `json:"created"`
+	Appended   bool   `json:"appended"`
+	Opened     bool   `json:"opened,omitempty"`
+}
+
+// periodNotes finds or creates the note for a day, week or month.
+type periodNotes struct {
+	opts   *Options
+	client *bear.Client
+	cfg    DailyConfig
+}
+
+func newDailyCmd(opts *Options) *cobra.Command {
+	var date string
+	var week bool
+	var month bool
+	var text string
+	var header string
+	var open bool
+
+	cmd := &cobra.Command{
+		Use:   "daily [text...]",
+		Short: "Append to today's note, creating it if needed",
+		Long: `Append to the note for a day, week or month, creating it if needed.
+
+The title comes from the [daily] config table: format (default ` + defaultDailyFormat + `),
+week_format (default ` + defaultWeekFormat + `, where {week} is the ISO week) and
+month_format (default ` + defaultMonthFormat + `), all Go time layouts. The note is
+found by searching for that title (the token is required); a missing note is
+created from the template named by daily.template and tagged with daily.tag.
+
+Text comes from the arguments, --text, or stdin. With --header it is added
+under that heading, which is created at the end of the note if missing. With
+no text the note is only ensured to exist.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if week && month {
+				return usageError(cmd, "--week and --month are mutually exclusive")
+			}
+			period := periodDay
+			if week {
+				period = periodWeek
+			} else if month {
+				period = periodMonth
+			}
+			day, err := parseDailyDate(date, time.Now())
+			if err != nil {
+				return usageError(cmd, "%v", err)
+			}
+
+			if len(args) > 0 && text != "" {
+				return usageError(cmd, "text arguments cannot be combined with --text")
+			}
+			if len(args) > 0 {
+				text = strings.Join(args, " ")
+			}
+			if err := ensureNoStdinConflict(opts.TokenStdin, text == "-" || (text == "" && !stdinIsTTY())); err != nil {
+				return &ExitError{Code: ExitUsage, Err: err}
+			}
+			entry, _, err := resolveTextInput(text, false, true)
+			if err != nil {
+				return &ExitError{Code: ExitUsage, Err: err}
+			}
+
+			token, err := maybeRequireToken(opts, true)
+			if err != nil {
+				return writeTokenError(opts, "daily", err)
+			}
+			out := NewOutputter(opts)
+			client, done, err := newSessionClient(opts, token)
+			if err != nil {
+				return usageError(cmd, "%v", err)
+			}
+			defer done()
+
+			notes := &periodNotes{opts: opts, client: client, cfg: opts.Daily}
+			report, err := notes.run(cmd.Context(), period, day, entry, header, open)
+			if err != nil {
+				return writeClientError(out, "daily", err)
+			}
+			out.WriteSuccess(Result{Action: "daily", Data: report})
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&date, "date", "", "Date: YYYY-MM-DD, today, yesterday, tomorrow, or an offset like -1d")
+	cmd.Flags().BoolVar(&week, "week", false, "Use the note for the week containing the date")
+	cmd.Flags().BoolVar(&month, "month", false, "Use the note for the month containing the date")
+	cmd.Flags().StringVar(&text, "text", "", "Text to append (use - for stdin)")
+	cmd.Flags().StringVar(&header, "header", "", "Append under this heading")
+	cmd.Flags().BoolVar(&open, "open", false, "Show the note in Bear")
+	return cmd
+}
+
+func (p *periodNotes) run(ctx context.Context, period string, day time.Time, entry, header string, open bool) (*dailyReport, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	start := periodStart(day, period)
+	report := &dailyReport{Period: period, Date: start.Format("2006-01-02"), Title: periodTitle(p.cfg, period, start)}
+
+	id, created, err := p.ensure(ctx, report.Title, start)
+	if err != nil {
+		return nil, err
+	}
+	report.Identifier = id
+	report.Created = created
+
+	if strings.TrimSpace(entry) != "" {
+		if err := p.appendEntry(ctx, id, header, entry); err != nil {
+			return nil, err
+		}
+		report.Appended = true
+	}
+	if open {
+		callCtx, cancel := withCallTimeout(ctx, p.opts)
+		_, err := p.client.OpenNote(callCtx, bear.OpenNoteOptions{NoteRef: bear.NoteRef{ID: id}, Header: header})
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		report.Opened = true
+	}
+	return report, nil
+}
+
+// ensure returns the identifier of the note titled title, creating it from
+// the configured template when no such note exists.
+func (p *periodNotes) ensure(ctx context.Context, title string, start time.Time) (string, bool, error) {
+	existing, err := findNoteByTitle(ctx, p.opts, p.client, title, p.cfg.Tag)
+	if err != nil {
+		return "", false, err
+	}
+	if existing != nil {
+		return existing.Identifier, false, nil
+	}
+
+	create := bear.CreateOptions{
+		Window: bear.Window{NoShowWindow: true, NoOpen: true},
+		Title:  title,
+	}
+	if p.cfg.Template != "" {
+		tpl, err := findTemplate(p.cfg.Template)
+		if err != nil {
+			return "", false, err
+		}
+		r := newTemplateRenderer(p.opts, map[string]string{"title": title})
+		r.now = func() time.Time { return start }
+		_, body, err := r.render(tpl)
+		if err != nil {
+			return "", false, err
+		}
+		create.Text = strings.TrimRight(body, "\n")
+		create.Tags = append(create.Tags, tpl.Tags...)
+		create.Pin = tpl.Pin
+	}
+	if p.cfg.Tag != "" {
+		create.Tags = append(create.Tags, p.cfg.Tag)
+	}
+
+	callCtx, cancel := withCallTimeout(ctx, p.opts)
+	created, err := p.client.Create(callCtx, create)
+	cancel()
+	if err != nil {
+		return "", false, err
+	}
+	if created.Identifier == "" {
+		return "", false, fmt.Errorf("bear did not return an identifier")
+	}
+	return created.Identifier, true, nil
+}
+
+// appendEntry appends text to the note, under header when given. A missing
+// header is added at the end of the note together with the text.
+func (p *periodNotes) appendEntry(ctx context.Context, id, header, text string) error {
+	add := bear.AddTextOptions{
+		NoteRef: bear.NoteRef{ID: id},
+		Window:  bear.Window{NoShowWindow: true, NoOpen: true},
+		Text:    text,
+		Mode:    bear.ModeAppend,
+		NewLine: true,
+	}
+	if header != "" {
+		callCtx, cancel := withCallTimeout(ctx, p.opts)
+		note, err := p.client.OpenNote(callCtx, bear.OpenNoteOptions{
+			NoteRef: bear.NoteRef{ID: id},
+			Window:  bear.Window{NoShowWindow: true, NoOpen: true},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if idx, _ := findHeader(strings.Split(note.Note.Text, "\n"), header); idx >= 0 {
+			add.Header = header
+		} else {
+			add.Text = "## " + header + "\n" + text
+		}
+	}
+	callCtx, cancel := withCallTimeout(ctx, p.opts)
+	defer cancel()
+	_, err := p.client.AddText(callCtx, add)
+	return err
+}
+
+// findNoteByTitle searches Bear for a note whose title is exactly title,
+// optionally limited to tag. It returns nil when there is none.
+func findNoteByTitle(ctx context.Context, opts *Options, client *bear.Client, title, tag string) (*bear.NoteSummary, error) {
+	callCtx, cancel := withCallTimeout(ctx, opts)
+	defer cancel()
+	res, err := client.Search(callCtx, bear.SearchOptions{Term: title, Tag: tag, NoShowWindow: true})
+	if err != nil {
+		return nil, err
+	}
+	var found *bear.NoteSummary
+	for i := range res.Notes {
+		note := &res.Notes[i]
+		if note.Title != title {
+			continue
+		}
+		if found == nil || note.CreationDate.Before(found.CreationDate) {
+			found = note
+		}
+	}
+	return found, nil
+}
+
+// parseDailyDate accepts YYYY-MM-DD, today, yesterday, tomorrow, or a date
+// offset such as -1d or +1w relative to now.
+func parseDailyDate(value string, now time.Time) (time.Time, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "today":
+		return now, nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	case "tomorrow":
+		return now.AddDate(0, 0, 1), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	if dateOffsetPattern.MatchString(value) {
+		return applyDateOffset(now, value)
+	}
+	return time.Time{}, fmt.Errorf("invalid --date %q (want YYYY-MM-DD, today, yesterday, tomorrow or an offset like -1d)", value)
+}
+
+// periodStart returns midnight on the day, the Monday of its ISO week, or
+// the first of its month.
+func periodStart(t time.Time, period string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch period {
+	case periodWeek:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case periodMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	default:
+		return day
+	}
+}
+
+func periodTitle(cfg DailyConfig, period string, start time.Time) string {
+	switch period {
+	case periodWeek:
+		layout := cfg.WeekFormat
+		if layout == "" {
+			layout = defaultWeekFormat
+		}
+		// Format from the week's Thursday so the year is the ISO year.
+		thursday := start.AddDate(0, 0, 3)
+		_, week := thursday.ISOWeek()
+		return strings.ReplaceAll(thursday.Format(layout), "{week}", fmt.Sprintf("%02d", week))
+	case periodMonth:
+		layout := cfg.MonthFormat
+		if layout == "" {
+			layout = defaultMonthFormat
+		}
+		return start.Format(layout)
+	default:
+		layout := cfg.Format
+		if layout == "" {
+			layout = defaultDailyFormat
+		}
+		return start.Format(layout)
+	}
+}
@@ -0,0 +1,60 @@
+package grizzly
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestPeriodNotesCreateThenAppend(t *testing.T) {
+	fb, client := newFakeSession(t)
+	notes := &periodNotes{opts: &Options{Timeout: 2 * time.Second}, client: client, cfg: DailyConfig{Tag: "journal"}}
+	day := time.Date(2024, 3, 14, 15, 0, 0, 0, time.UTC)
+
+	first, err := notes.run(context.Background(), periodDay, day, "- coffee", "", false)
+	if err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	if !first.Created || !first.Appended || first.Title != "2024-03-14" {
+		t.Fatalf("first = %+v", first)
+	}
+	second, err := notes.run(context.Background(), periodDay, day, "- standup", "Log", false)
+	if err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if second.Created || second.Identifier != first.Identifier {
+		t.Fatalf("second = %+v, want existing %s", second, first.Identifier)
+	}
+	if _, err := notes.run(context.Background(), periodDay, day, "- lunch", "Log", false); err != nil {
+		t.Fatalf("third run: %v", err)
+	}
+
+	values := fakeCall(t, fb, "open-note", url.Values{"id": {first.Identifier}})
+	want := "# 2024-03-14\n#journal\n- coffee\n## Log\n- standup\n- lunch"
+	if got := values.Get("note"); got != want {
+		t.Fatalf("note = %q, want %q", got, want)
+	}
+}
+
+func TestPeriodTitle(t *testing.T) {
+	cases := []struct {
+		period string
+		day    time.Time
+		want   string
+	}{
+		{periodDay, time.Date(2024, 3, 14, 8, 0, 0, 0, time.UTC), "2024-03-14"},
+		{periodWeek, time.Date(2024, 12, 31, 8, 0, 0, 0, time.UTC), "2025-W01"},
+		{periodWeek, time.Date(2021, 1, 3, 8, 0, 0, 0, time.UTC), "2020-W53"},
+		{periodMonth, time.Date(2024, 2, 29, 8, 0, 0, 0, time.UTC), "2024-02"},
+	}
+	for _, tc := range cases {
+		got := periodTitle(DailyConfig{}, tc.period, periodStart(tc.day, tc.period))
+		if got != tc.want {
+			t.Fatalf("periodTitle(%s, %s) = %q, want %q", tc.period, tc.day.Format("2006-01-02"), got, tc.want)
+		}
+	}
+	if got := periodTitle(DailyConfig{Format: "Mon Jan 2"}, periodDay, time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)); got != "Thu Mar 14" {
+		t.Fatalf("custom format = %q", got)
+	}
+}
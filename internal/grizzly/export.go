@@ -0,0 +1,410 @@
+package grizzly
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spf13/cobra"
+
+	"grizzly/pkg/bear"
+)
+
+const exportManifestName = ".grizzly-export.json"
+
+const (
+	namingTitle = "title"
+	namingSlug  = "slug"
+	namingID    = "id"
+
+	collisionSuffix    = "suffix"
+	collisionID        = "id"
+	collisionSkip      = "skip"
+	collisionOverwrite = "overwrite"
+)
+
+type exportConfig struct {
+	Dir         string
+	Term        string
+	Tag         string
+	Naming      string
+	OnCollision string
+	TagFolders  bool
+	Incremental bool
+}
+
+type exportManifest struct {
+	Version int                            `json:"version"`
+	Notes   map[string]exportManifestEntry `json:"notes"`
+}
+
+type exportManifestEntry struct {
+	Path     string    `json:"path"`
+	Wanted   string    `json:"wanted"`
+	Modified time.Time `json:"modified"`
+}
+
+type exportedFile struct {
+	Identifier string `json:"identifier"`
+	Title      string `json:"title"`
+	Path       string `json:"path,omitempty"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+}
+
+type exportReport struct {
+	Dir       string         `json:"dir"`
+	Total     int            `json:"total"`
+	Written   int            `json:"written"`
+	Unchanged int            `json:"unchanged"`
+	Skipped   int            `json:"skipped"`
+	Failed    int            `json:"failed"`
+	Files     []exportedFile `json:"files"`
+}
+
+type exporter struct {
+	opts     *Options
+	client   *bear.Client
+	cfg      exportConfig
+	manifest exportManifest
+	// taken maps relative paths to the identifier that owns them.
+	taken map[string]string
+}
+
+func newExportCmd(opts *Options) *cobra.Command {
+	cfg := exportConfig{}
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   "export <dir>",
+		Short: "Write notes to Markdown files with YAML front matter",
+		Long: `Write notes to Markdown files with YAML front matter.
+
+Notes are selected with --term and/or --tag, or --all. Each note is fetched
+with open-note and written as <title>.md; the front matter records its
+identifier, tags, dates and pin state. A manifest (` + exportManifestName + `)
+in the target directory remembers where each note went, so later runs keep
+file names stable and --incremental can skip unchanged notes.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if all && (cfg.Term != "" || cfg.Tag != "") {
+				return usageError(cmd, "--all cannot be combined with --term or --tag")
+			}
+			if !all && cfg.Term == "" && cfg.Tag == "" {
+				return usageError(cmd, "one of --term, --tag, or --all is required")
+			}
+			switch cfg.Naming {
+			case namingTitle, namingSlug, namingID:
+			default:
+				return usageError(cmd, "invalid --naming: %s", cfg.Naming)
+			}
+			switch cfg.OnCollision {
+			case collisionSuffix, collisionID, collisionSkip, collisionOverwrite:
+			default:
+				return usageError(cmd, "invalid --on-collision: %s", cfg.OnCollision)
+			}
+			dir, err := expandPath(args[0])
+			if err != nil {
+				return &ExitError{Code: ExitUsage, Err: err}
+			}
+			cfg.Dir = dir
+
+			token, err := resolveToken(opts)
+			if err != nil {
+				return writeTokenError(opts, "export", err)
+			}
+			out := NewOutputter(opts)
+			client, done, err := newSessionClient(opts, token)
+			if err != nil {
+				return usageError(cmd, "%v", err)
+			}
+			defer done()
+
+			exp := &exporter{opts: opts, client: client, cfg: cfg}
+			report, err := exp.run(cmd.Context())
+			if err != nil {
+				return writeClientError(out, "export", err)
+			}
+			out.WriteSuccess(Result{Action: "export", Data: report})
+			if report.Failed > 0 {
+				return &ExitError{Code: ExitFailure, Err: fmt.Errorf("%d of %d notes failed to export", report.Failed, report.Total)}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&cfg.Term, "term", "", "Export notes matching this search term")
+	cmd.Flags().StringVar(&cfg.Tag, "tag", "", "Export notes with this tag")
+	cmd.Flags().BoolVar(&all, "all", false, "Export every note")
+	cmd.Flags().StringVar(&cfg.Naming, "naming", namingTitle, "File names: title (sanitized), slug, or id")
+	cmd.Flags().StringVar(&cfg.OnCollision, "on-collision", collisionSuffix, "When two notes map to one file: suffix, id, skip, or overwrite")
+	cmd.Flags().BoolVar(&cfg.TagFolders, "tag-folders", false, "Place each note in a folder named after its first tag")
+	cmd.Flags().BoolVar(&cfg.Incremental, "incremental", false, "Skip notes whose modification date is unchanged since the last export")
+	return cmd
+}
+
+func (e *exporter) run(ctx context.Context) (*exportReport, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := os.MkdirAll(e.cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := e.loadManifest(); err != nil {
+		return nil, err
+	}
+
+	listCtx, cancel := withCallTimeout(ctx, e.opts)
+	list, err := e.client.Search(listCtx, bear.SearchOptions{Term: e.cfg.Term, Tag: e.cfg.Tag, NoShowWindow: true})
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &exportReport{Dir: e.cfg.Dir, Files: []exportedFile{}}
+	for _, summary := range list.Notes {
+		file := e.exportNote(ctx, summary)
+		report.Total++
+		switch file.Status {
+		case "written":
+			report.Written++
+		case "unchanged":
+			report.Unchanged++
+		case "skipped":
+			report.Skipped++
+		default:
+			report.Failed++
+		}
+		report.Files = append(report.Files, file)
+	}
+	if err := e.saveManifest(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (e *exporter) exportNote(ctx context.Context, summary bear.NoteSummary) exportedFile {
+	file := exportedFile{Identifier: summary.Identifier, Title: summary.Title}
+	prev, known := e.manifest.Notes[summary.Identifier]
+	unchanged := known && prev.Modified.Equal(summary.ModificationDate) && !summary.ModificationDate.IsZero()
+	if e.cfg.Incremental && unchanged && prev.Wanted == e.wantedPath(summary.Identifier, summary.Title, summary.Tags) {
+		if _, err := os.Stat(filepath.Join(e.cfg.Dir, prev.Path)); err == nil {
+			file.Path = prev.Path
+			file.Status = "unchanged"
+			return file
+		}
+	}
+
+	callCtx, cancel := withCallTimeout(ctx, e.opts)
+	res, err := e.client.OpenNote(callCtx, bear.OpenNoteOptions{
+		NoteRef: bear.NoteRef{ID: summary.Identifier},
+		Window:  bear.Window{NoShowWindow: true, NoOpen: true},
+	})
+	cancel()
+	if err != nil {
+		return failExport(file, err)
+	}
+	note := &res.Note
+	file.Title = note.Title
+
+	wanted := e.wantedPath(note.Identifier, note.Title, note.Tags)
+	rel, ok := e.allocate(note.Identifier, wanted, prev, known)
+	if !ok {
+		file.Status = "skipped"
+		file.Error = "file name already used"
+		return file
+	}
+	data, err := renderNoteFile(frontMatterFor(note), note.Text)
+	if err != nil {
+		return failExport(file, err)
+	}
+	path := filepath.Join(e.cfg.Dir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return failExport(file, err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return failExport(file, err)
+	}
+	if known && prev.Path != rel && e.taken[prev.Path] == note.Identifier {
+		delete(e.taken, prev.Path)
+		_ = os.Remove(filepath.Join(e.cfg.Dir, prev.Path))
+	}
+	modified := summary.ModificationDate
+	if modified.IsZero() {
+		modified = note.ModificationDate
+	}
+	e.manifest.Notes[note.Identifier] = exportManifestEntry{Path: rel, Wanted: wanted, Modified: modified}
+	file.Path = rel
+	file.Status = "written"
+	return file
+}
+
+func failExport(file exportedFile, err error) exportedFile {
+	file.Status = "failed"
+	file.Error = err.Error()
+	return file
+}
+
+// wantedPath is the relative path a note would get without collisions.
+func (e *exporter) wantedPath(id, title string, tags []string) string {
+	var name string
+	switch e.cfg.Naming {
+	case namingSlug:
+		name = slugify(title)
+	case namingID:
+		name = sanitizeFilename(id)
+	default:
+		name = sanitizeFilename(title)
+	}
+	if name == "" {
+		name = sanitizeFilename(id)
+	}
+	dir := ""
+	if e.cfg.TagFolders && len(tags) > 0 {
+		parts := []string{}
+		for _, part := range strings.Split(tags[0], "/") {
+			if clean := sanitizeFilename(part); clean != "" {
+				parts = append(parts, clean)
+			}
+		}
+		dir = filepath.Join(parts...)
+	}
+	return filepath.Join(dir, name+".md")
+}
+
+// allocate picks a free relative path for a note. A note keeps the path it
+// had last time as long as its wanted path has not changed.
+func (e *exporter) allocate(id, wanted string, prev exportManifestEntry, known bool) (string, bool) {
+	if known && prev.Wanted == wanted && e.owner(prev.Path, id) {
+		e.taken[prev.Path] = id
+		return prev.Path, true
+	}
+	if e.free(wanted, id) {
+		e.taken[wanted] = id
+		return wanted, true
+	}
+	ext := filepath.Ext(wanted)
+	stem := strings.TrimSuffix(wanted, ext)
+	switch e.cfg.OnCollision {
+	case collisionSkip:
+		return "", false
+	case collisionOverwrite:
+		e.taken[wanted] = id
+		return wanted, true
+	case collisionID:
+		candidate := fmt.Sprintf("%s (%s)%s", stem, sanitizeFilename(id), ext)
+		if e.free(candidate, id) {
+			e.taken[candidate] = id
+			return candidate, true
+		}
+		return "", false
+	default:
+		for i := 2; ; i++ {
+			candidate := fmt.Sprintf("%s %d%s", stem, i, ext)
+			if e.free(candidate, id) {
+				e.taken[candidate] = id
+				return candidate, true
+			}
+		}
+	}
+}
+
+func (e *exporter) owner(rel, id string) bool {
+	owner, ok := e.taken[rel]
+	return !ok || owner == id
+}
+
+// free reports whether rel is unclaimed by another note and does not hold a
+// file that the export did not write.
+func (e *exporter) free(rel, id string) bool {
+	key := strings.ToLower(rel)
+	for path, owner := range e.taken {
+		if strings.ToLower(path) == key && owner != id {
+			return false
+		}
+	}
+	if _, ok := e.taken[rel]; ok {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(e.cfg.Dir, rel))
+	return os.IsNotExist(err)
+}
+
+func (e *exporter) loadManifest() error {
+	e.manifest = exportManifest{Version: 1, Notes: map[string]exportManifestEntry{}}
+	e.taken = map[string]string{}
+	data, err := os.ReadFile(filepath.Join(e.cfg.Dir, exportManifestName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := json.Unmarshal(data, &e.manifest); err != nil {
+		return fmt.Errorf("read %s: %w", exportManifestName, err)
+	}
+	if e.manifest.Notes == nil {
+		e.manifest.Notes = map[string]exportManifestEntry{}
+	}
+	for id, entry := range e.manifest.Notes {
+		e.taken[entry.Path] = id
+	}
+	return nil
+}
+
+func (e *exporter) saveManifest() error {
+	data, err := json.MarshalIndent(e.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(e.cfg.Dir, exportManifestName), append(data, '\n'), 0o644)
+}
+
+const maxFilenameRunes = 120
+
+// sanitizeFilename makes a title safe to use as a file name on macOS, Linux
+// and Windows.
+func sanitizeFilename(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			b.WriteRune('-')
+		case unicode.IsControl(r):
+			b.WriteRune(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	clean := strings.Join(strings.Fields(b.String()), " ")
+	clean = strings.Trim(clean, ". ")
+	if runes := []rune(clean); len(runes) > maxFilenameRunes {
+		clean = strings.TrimRight(string(runes[:maxFilenameRunes]), ". ")
+	}
+	return clean
+}
+
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if runes := []rune(slug); len(runes) > maxFilenameRunes {
+		slug = strings.TrimSuffix(string(runes[:maxFilenameRunes]), "-")
+	}
+	return slug
+}
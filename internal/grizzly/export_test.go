@@ -0,0 +1,103 @@
+package grizzly
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"grizzly/pkg/bear"
+)
+
+func newFakeSession(t *testing.T) (*FakeBear, *bear.Client) {
+	t.Helper()
+	fb, err := NewFakeBear("")
+	if err != nil {
+		t.Fatalf("NewFakeBear: %v", err)
+	}
+	shared := &bear.SharedCallbacks{Scheme: "http"}
+	t.Cleanup(func() { shared.Close() })
+	client := &bear.Client{
+		Transport: &bear.URLTransport{Opener: fb, Callbacks: shared},
+		Token:     bear.StaticToken("secret"),
+	}
+	return fb, client
+}
+
+func TestExporterWritesFrontMatterAndSkipsUnchanged(t *testing.T) {
+	fb, client := newFakeSession(t)
+	plan := fakeCall(t, fb, "create", url.Values{"title": {"Plan: Q3"}, "text": {"body"}, "tags": {"work/proj"}}).Get("identifier")
+	fakeCall(t, fb, "create", url.Values{"title": {"Plan- Q3"}, "text": {"clash"}})
+
+	dir := t.TempDir()
+	opts := &Options{Timeout: 2 * time.Second}
+	cfg := exportConfig{Dir: dir, Naming: namingTitle, OnCollision: collisionSuffix, TagFolders: true, Incremental: true}
+	report, err := (&exporter{opts: opts, client: client, cfg: cfg}).run(context.Background())
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if report.Total != 2 || report.Written != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "work", "proj", "Plan- Q3.md"))
+	if err != nil {
+		t.Fatalf("read export: %v", err)
+	}
+	meta, body, err := parseNoteFile(data)
+	if err != nil {
+		t.Fatalf("parseNoteFile: %v", err)
+	}
+	if meta.Identifier != plan || meta.Title != "Plan: Q3" || len(meta.Tags) != 1 || meta.Modified.IsZero() {
+		t.Fatalf("front matter = %+v", meta)
+	}
+	if body != "# Plan: Q3\nbody\n#work/proj\n" {
+		t.Fatalf("body = %q", body)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Plan- Q3.md")); err != nil {
+		t.Fatalf("untagged note: %v", err)
+	}
+
+	report, err = (&exporter{opts: opts, client: client, cfg: cfg}).run(context.Background())
+	if err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if report.Unchanged != 2 || report.Written != 0 {
+		t.Fatalf("second report = %+v", report)
+	}
+
+	cfg.TagFolders = false
+	report, err = (&exporter{opts: opts, client: client, cfg: cfg}).run(context.Background())
+	if err != nil {
+		t.Fatalf("third run: %v", err)
+	}
+	paths := map[string]bool{}
+	for _, f := range report.Files {
+		paths[f.Path] = true
+	}
+	if !paths["Plan- Q3.md"] || !paths["Plan- Q3 2.md"] {
+		t.Fatalf("paths = %v", paths)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "work", "proj", "Plan- Q3.md")); !os.IsNotExist(err) {
+		t.Fatalf("old path kept: %v", err)
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	cases := map[string]string{
+		"a/b:c?":         "a-b-c-",
+		"  ..hidden. ":   "hidden",
+		"tab\there":      "tab here",
+		"CON* <x>|\"y\"": "CON- -x---y-",
+	}
+	for in, want := range cases {
+		if got := sanitizeFilename(in); got != want {
+			t.Errorf("sanitizeFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := slugify("Hello, World: 2024!"); got != "hello-world-2024" {
+		t.Errorf("slugify = %q", got)
+	}
+}
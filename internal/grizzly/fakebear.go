@@ -0,0 +1,731 @@
+package grizzly
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	fakeErrInvalidParameter = "1"
+	fakeErrNoteNotFound     = "2"
+	fakeErrInvalidToken     = "3"
+	fakeErrTagNotFound      = "4"
+	fakeErrHeaderNotFound   = "5"
+	fakeErrNoteLocked       = "6"
+	fakeErrUnknownAction    = "7"
+)
+
+const fakeDateLayout = "2006-01-02T15:04:05Z"
+
+var fakeTagPattern = regexp.MustCompile(`(?:^|\s)#([^\s#]+)`)
+
+type fakeError struct {
+	Code    string
+	Message string
+}
+
+func (e *fakeError) Error() string {
+	return e.Message
+}
+
+type fakeNote struct {
+	Identifier       string    `json:"identifier"`
+	Text             string    `json:"text"`
+	CreationDate     time.Time `json:"creationDate"`
+	ModificationDate time.Time `json:"modificationDate"`
+	Trashed          bool      `json:"trashed,omitempty"`
+	Archived         bool      `json:"archived,omitempty"`
+	Pinned           bool      `json:"pinned,omitempty"`
+	Locked           bool      `json:"locked,omitempty"`
+}
+
+func (n *fakeNote) title() string {
+	first, _, _ := strings.Cut(n.Text, "\n")
+	return strings.TrimSpace(strings.TrimLeft(first, "#"))
+}
+
+func (n *fakeNote) tags() []string {
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, match := range fakeTagPattern.FindAllStringSubmatch(n.Text, -1) {
+		tag := strings.TrimRight(match[1], ".,;:!?")
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func (n *fakeNote) hasTag(name string) bool {
+	for _, tag := range n.tags() {
+		if tag == name || strings.HasPrefix(tag, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+type fakeState struct {
+	Token    string      `json:"token,omitempty"`
+	Selected string      `json:"selected,omitempty"`
+	Notes    []*fakeNote `json:"notes"`
+}
+
+// FakeBear simulates Bear's x-callback-url API against an in-memory note
+// store, optionally persisted as JSON. It satisfies Opener so it can stand in
+// for Bear anywhere a URL would be opened.
+type FakeBear struct {
+	mu     sync.Mutex
+	path   string
+	state  fakeState
+	now    func() time.Time
+	client *http.Client
+}
+
+func NewFakeBear(path string) (*FakeBear, error) {
+	fb := &FakeBear{
+		path:   path,
+		now:    func() time.Time { return time.Now().UTC() },
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	if path == "" {
+		return fb, nil
+	}
+	expanded, err := expandPath(path)
+	if err != nil {
+		return nil, err
+	}
+	fb.path = expanded
+	data, err := os.ReadFile(expanded)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fb, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fb.state); err != nil {
+		return nil, fmt.Errorf("read fake-bear store %s: %w", path, err)
+	}
+	return fb, nil
+}
+
+// SetToken sets the API token the simulator accepts. An empty token accepts
+// any non-empty token.
+func (f *FakeBear) SetToken(token string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.state.Token = token
+	return f.save()
+}
+
+func (f *FakeBear) Open(ctx context.Context, rawURL string) error {
+	callback, err := f.Handle(rawURL)
+	if err != nil {
+		return err
+	}
+	return f.deliver(ctx, callback)
+}
+
+// Handle runs a bear:// URL and returns the x-success or x-error URL Bear
+// would open in response, or "" when the request carried no callback.
+func (f *FakeBear) Handle(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "bear" || u.Host != "x-callback-url" {
+		return "", fmt.Errorf("not a bear x-callback-url: %s", rawURL)
+	}
+	action := strings.TrimPrefix(u.Path, "/")
+	query := u.Query()
+
+	f.mu.Lock()
+	values, err := f.dispatch(action, query)
+	if err == nil {
+		err = f.save()
+	}
+	f.mu.Unlock()
+
+	target := query.Get("x-success")
+	if err != nil {
+		target = query.Get("x-error")
+		var fe *fakeError
+		if !errors.As(err, &fe) {
+			fe = &fakeError{Code: fakeErrInvalidParameter, Message: err.Error()}
+		}
+		values = url.Values{}
+		values.Set("errorCode", fe.Code)
+		values.Set("errorMessage", fe.Message)
+	}
+	if target == "" {
+		return "", nil
+	}
+	return appendCallbackValues(target, values)
+}
+
+func appendCallbackValues(target string, values url.Values) (string, error) {
+	cb, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid callback url: %w", err)
+	}
+	query := cb.Query()
+	for key, vals := range values {
+		for _, val := range vals {
+			query.Add(key, val)
+		}
+	}
+	cb.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+	return cb.String(), nil
+}
+
+// deliver opens a callback URL the way the grizzly callback app does:
+// gzlcb:// is rewritten to http:// and fetched. Other schemes are dropped.
+func (f *FakeBear) deliver(ctx context.Context, callback string) error {
+	if callback == "" {
+		return nil
+	}
+	u, err := url.Parse(callback)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "gzlcb":
+		u.Scheme = "http"
+	case "http", "https":
+	default:
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("deliver callback: %w", err)
+	}
+	return resp.Body.Close()
+}
+
+func (f *FakeBear) save() error {
+	if f.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(f.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+func (f *FakeBear) dispatch(action string, q url.Values) (url.Values, error) {
+	switch action {
+	case "open-note":
+		return f.openNote(q)
+	case "create":
+		return f.create(q)
+	case "add-text":
+		return f.addText(q)
+	case "add-file":
+		return f.addFile(q)
+	case "tags":
+		return f.listTags(q)
+	case "open-tag":
+		names := splitCSV(q.Get("name"))
+		if len(names) == 0 {
+			return nil, &fakeError{Code: fakeErrInvalidParameter, Message: "missing name"}
+		}
+		return f.listNotes(q, func(n *fakeNote) bool {
+			for _, name := range names {
+				if n.hasTag(name) {
+					return true
+				}
+			}
+			return false
+		})
+	case "rename-tag":
+		return f.renameTag(q)
+	case "delete-tag":
+		return f.deleteTag(q)
+	case "trash":
+		return f.moveNote(q, func(n *fakeNote) { n.Trashed = true })
+	case "archive":
+		return f.moveNote(q, func(n *fakeNote) { n.Archived = true })
+	case "untagged":
+		return f.listNotes(q, func(n *fakeNote) bool { return len(n.tags()) == 0 })
+	case "todo":
+		return f.listNotes(q, func(n *fakeNote) bool { return strings.Contains(n.Text, "- [ ]") })
+	case "today":
+		today := f.now().Format("2006-01-02")
+		return f.listNotes(q, func(n *fakeNote) bool { return n.ModificationDate.Format("2006-01-02") == today })
+	case "locked":
+		return f.listNotes(q, func(n *fakeNote) bool { return n.Locked })
+	case "search":
+		tag := q.Get("tag")
+		return f.listNotes(q, func(n *fakeNote) bool { return tag == "" || n.hasTag(tag) })
+	case "grab-url":
+		return f.grabURL(q)
+	default:
+		return nil, &fakeError{Code: fakeErrUnknownAction, Message: fmt.Sprintf("unknown action: %s", action)}
+	}
+}
+
+func (f *FakeBear) checkToken(q url.Values, required bool) (bool, error) {
+	token := q.Get("token")
+	if token == "" {
+		if required {
+			return false, &fakeError{Code: fakeErrInvalidToken, Message: "missing token"}
+		}
+		return false, nil
+	}
+	if f.state.Token != "" && token != f.state.Token {
+		return false, &fakeError{Code: fakeErrInvalidToken, Message: "invalid token"}
+	}
+	return true, nil
+}
+
+func (f *FakeBear) findNote(q url.Values) (*fakeNote, error) {
+	excludeTrashed := q.Get("exclude_trashed") == "yes"
+	if q.Get("selected") == "yes" {
+		if _, err := f.checkToken(q, true); err != nil {
+			return nil, err
+		}
+		if note := f.noteByID(f.state.Selected); note != nil {
+			return note, nil
+		}
+		return nil, &fakeError{Code: fakeErrNoteNotFound, Message: "no note selected"}
+	}
+	if id := q.Get("id"); id != "" {
+		if note := f.noteByID(id); note != nil && !(excludeTrashed && note.Trashed) {
+			return note, nil
+		}
+		return nil, &fakeError{Code: fakeErrNoteNotFound, Message: "note not found"}
+	}
+	if title := q.Get("title"); title != "" {
+		for _, note := range f.state.Notes {
+			if excludeTrashed && note.Trashed {
+				continue
+			}
+			if strings.EqualFold(note.title(), title) {
+				return note, nil
+			}
+		}
+		return nil, &fakeError{Code: fakeErrNoteNotFound, Message: "note not found"}
+	}
+	return nil, &fakeError{Code: fakeErrInvalidParameter, Message: "missing id or title"}
+}
+
+func (f *FakeBear) noteByID(id string) *fakeNote {
+	if id == "" {
+		return nil
+	}
+	for _, note := range f.state.Notes {
+		if note.Identifier == id {
+			return note
+		}
+	}
+	return nil
+}
+
+func (f *FakeBear) openNote(q url.Values) (url.Values, error) {
+	note, err := f.findNote(q)
+	if err != nil {
+		return nil, err
+	}
+	if note.Locked {
+		return nil, &fakeError{Code: fakeErrNoteLocked, Message: "note is locked"}
+	}
+	if q.Get("pin") == "yes" {
+		note.Pinned = true
+	}
+	f.state.Selected = note.Identifier
+	return fakeNoteValues(note), nil
+}
+
+func fakeNoteValues(note *fakeNote) url.Values {
+	values := url.Values{}
+	values.Set("note", note.Text)
+	values.Set("identifier", note.Identifier)
+	values.Set("title", note.title())
+	values.Set("tags", mustJSON(note.tags()))
+	values.Set("is_trashed", yesNo(note.Trashed))
+	values.Set("pin", yesNo(note.Pinned))
+	values.Set("creationDate", note.CreationDate.Format(fakeDateLayout))
+	values.Set("modificationDate", note.ModificationDate.Format(fakeDateLayout))
+	return values
+}
+
+func (f *FakeBear) create(q url.Values) (url.Values, error) {
+	var body []string
+	if title := q.Get("title"); title != "" {
+		body = append(body, "# "+title)
+	}
+	if text := q.Get("text"); text != "" {
+		body = append(body, f.stamp(q, text))
+	}
+	if file := q.Get("file"); file != "" {
+		name := q.Get("filename")
+		if name == "" {
+			return nil, &fakeError{Code: fakeErrInvalidParameter, Message: "missing filename"}
+		}
+		body = append(body, attachmentMarkdown(name))
+	}
+	if tags := splitCSV(q.Get("tags")); len(tags) > 0 {
+		body = append(body, formatTagLine(tags))
+	}
+	if len(body) == 0 {
+		return nil, &fakeError{Code: fakeErrInvalidParameter, Message: "note is empty"}
+	}
+	note := f.newNote(strings.Join(body, "\n"))
+	note.Pinned = q.Get("pin") == "yes"
+	values := url.Values{}
+	values.Set("identifier", note.Identifier)
+	values.Set("title", note.title())
+	return values, nil
+}
+
+func (f *FakeBear) grabURL(q url.Values) (url.Values, error) {
+	page := q.Get("url")
+	if page == "" {
+		return nil, &fakeError{Code: fakeErrInvalidParameter, Message: "missing url"}
+	}
+	text := "# " + page + "\n" + page
+	if tags := splitCSV(q.Get("tags")); len(tags) > 0 {
+		text += "\n" + formatTagLine(tags)
+	}
+	note := f.newNote(text)
+	note.Pinned = q.Get("pin") == "yes"
+	values := url.Values{}
+	values.Set("identifier", note.Identifier)
+	values.Set("title", note.title())
+	return values, nil
+}
+
+func (f *FakeBear) newNote(text string) *fakeNote {
+	now := f.now()
+	note := &fakeNote{
+		Identifier:       newFakeIdentifier(),
+		Text:             text,
+		CreationDate:     now,
+		ModificationDate: now,
+	}
+	f.state.Notes = append(f.state.Notes, note)
+	f.state.Selected = note.Identifier
+	return note
+}
+
+func (f *FakeBear) stamp(q url.Values, text string) string {
+	if q.Get("timestamp") == "yes" {
+		return f.now().Format("2006-01-02 15:04") + " " + text
+	}
+	return text
+}
+
+func (f *FakeBear) addText(q url.Values) (url.Values, error) {
+	note, err := f.findNote(q)
+	if err != nil {
+		return nil, err
+	}
+	text := q.Get("text")
+	if text == "" {
+		return nil, &fakeError{Code: fakeErrInvalidParameter, Message: "missing text"}
+	}
+	if tags := splitCSV(q.Get("tags")); len(tags) > 0 {
+		text += "\n" + formatTagLine(tags)
+	}
+	if err := f.insert(note, f.stamp(q, text), q); err != nil {
+		return nil, err
+	}
+	values := url.Values{}
+	values.Set("note", note.Text)
+	values.Set("title", note.title())
+	return values, nil
+}
+
+func (f *FakeBear) addFile(q url.Values) (url.Values, error) {
+	note, err := f.findNote(q)
+	if err != nil {
+		return nil, err
+	}
+	name := q.Get("filename")
+	if q.Get("file") == "" || name == "" {
+		return nil, &fakeError{Code: fakeErrInvalidParameter, Message: "missing file or filename"}
+	}
+	q.Set("new_line", "yes")
+	if err := f.insert(note, attachmentMarkdown(name), q); err != nil {
+		return nil, err
+	}
+	return url.Values{}, nil
+}
+
+func (f *FakeBear) insert(note *fakeNote, text string, q url.Values) error {
+	if note.Locked {
+		return &fakeError{Code: fakeErrNoteLocked, Message: "note is locked"}
+	}
+	mode := q.Get("mode")
+	if mode == "" {
+		mode = "append"
+	}
+	newLine := q.Get("new_line") == "yes"
+	lines := strings.Split(note.Text, "\n")
+
+	if mode == "replace_all" {
+		note.Text = text
+		note.ModificationDate = f.now()
+		return nil
+	}
+
+	start, end := 1, len(lines)
+	if header := q.Get("header"); header != "" {
+		idx, level := findHeader(lines, header)
+		if idx < 0 {
+			return &fakeError{Code: fakeErrHeaderNotFound, Message: fmt.Sprintf("header not found: %s", header)}
+		}
+		start, end = idx+1, len(lines)
+		for i := start; i < len(lines); i++ {
+			if l := headingLevel(lines[i]); l > 0 && l <= level {
+				end = i
+				break
+			}
+		}
+	}
+	for end > start && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+
+	section := strings.Join(lines[start:end], "\n")
+	switch mode {
+	case "append":
+		if section != "" && newLine {
+			section += "\n"
+		}
+		section += text
+	case "prepend":
+		if section != "" {
+			section = text + "\n" + section
+		} else {
+			section = text
+		}
+	case "replace":
+		section = text
+	default:
+		return &fakeError{Code: fakeErrInvalidParameter, Message: fmt.Sprintf("invalid mode: %s", mode)}
+	}
+
+	out := append([]string{}, lines[:start]...)
+	out = append(out, strings.Split(section, "\n")...)
+	out = append(out, lines[end:]...)
+	note.Text = strings.Join(out, "\n")
+	note.ModificationDate = f.now()
+	return nil
+}
+
+func findHeader(lines []string, header string) (int, int) {
+	for i, line := range lines {
+		level := headingLevel(line)
+		if level == 0 {
+			continue
+		}
+		if strings.EqualFold(strings.TrimSpace(line[level:]), strings.TrimSpace(header)) {
+			return i, level
+		}
+	}
+	return -1, 0
+}
+
+func headingLevel(line string) int {
+	level := 0
+	for level < len(line) && level < 6 && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level >= len(line) || line[level] != ' ' {
+		return 0
+	}
+	return level
+}
+
+func (f *FakeBear) listTags(q url.Values) (url.Values, error) {
+	if _, err := f.checkToken(q, true); err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	names := []string{}
+	for _, note := range f.state.Notes {
+		if note.Trashed {
+			continue
+		}
+		for _, tag := range note.tags() {
+			if !seen[tag] {
+				seen[tag] = true
+				names = append(names, tag)
+			}
+		}
+	}
+	sort.Strings(names)
+	tags := make([]map[string]string, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, map[string]string{"name": name})
+	}
+	values := url.Values{}
+	values.Set("tags", mustJSON(tags))
+	return values, nil
+}
+
+func (f *FakeBear) listNotes(q url.Values, match func(*fakeNote) bool) (url.Values, error) {
+	authorized, err := f.checkToken(q, false)
+	if err != nil {
+		return nil, err
+	}
+	values := url.Values{}
+	if !authorized {
+		return values, nil
+	}
+	term := strings.ToLower(firstNonEmpty(q.Get("term"), q.Get("search")))
+	notes := []map[string]any{}
+	for _, note := range f.state.Notes {
+		if note.Trashed || note.Archived || !match(note) {
+			continue
+		}
+		if term != "" && !strings.Contains(strings.ToLower(note.Text), term) {
+			continue
+		}
+		notes = append(notes, map[string]any{
+			"title":            note.title(),
+			"identifier":       note.Identifier,
+			"tags":             note.tags(),
+			"creationDate":     note.CreationDate.Format(fakeDateLayout),
+			"modificationDate": note.ModificationDate.Format(fakeDateLayout),
+			"pin":              yesNo(note.Pinned),
+		})
+	}
+	values.Set("notes", mustJSON(notes))
+	return values, nil
+}
+
+func (f *FakeBear) renameTag(q url.Values) (url.Values, error) {
+	name, newName := q.Get("name"), q.Get("new_name")
+	if name == "" || newName == "" {
+		return nil, &fakeError{Code: fakeErrInvalidParameter, Message: "missing name or new_name"}
+	}
+	if !f.rewriteTag(name, "#"+strings.ReplaceAll(newName, "$", "$$")) {
+		return nil, &fakeError{Code: fakeErrTagNotFound, Message: fmt.Sprintf("tag not found: %s", name)}
+	}
+	return url.Values{}, nil
+}
+
+func (f *FakeBear) deleteTag(q url.Values) (url.Values, error) {
+	name := q.Get("name")
+	if name == "" {
+		return nil, &fakeError{Code: fakeErrInvalidParameter, Message: "missing name"}
+	}
+	if !f.rewriteTag(name, "") {
+		return nil, &fakeError{Code: fakeErrTagNotFound, Message: fmt.Sprintf("tag not found: %s", name)}
+	}
+	return url.Values{}, nil
+}
+
+func (f *FakeBear) rewriteTag(name, replacement string) bool {
+	pattern := regexp.MustCompile(`(^|\s)#` + regexp.QuoteMeta(name) + `(/[^\s#]*)?($|[\s.,;:!?])`)
+	template := "${1}${3}"
+	if replacement != "" {
+		template = "${1}" + replacement + "${2}${3}"
+	}
+	found := false
+	for _, note := range f.state.Notes {
+		if !note.hasTag(name) {
+			continue
+		}
+		found = true
+		note.Text = pattern.ReplaceAllString(note.Text, template)
+		note.ModificationDate = f.now()
+	}
+	return found
+}
+
+func (f *FakeBear) moveNote(q url.Values, apply func(*fakeNote)) (url.Values, error) {
+	id := q.Get("id")
+	if id == "" {
+		if q.Get("search") == "" {
+			return nil, &fakeError{Code: fakeErrInvalidParameter, Message: "missing id or search"}
+		}
+		// Bear only shows the search results; there is no window to simulate.
+		return nil, &fakeError{Code: fakeErrInvalidParameter, Message: "search without id is not simulated"}
+	}
+	note := f.noteByID(id)
+	if note == nil {
+		return nil, &fakeError{Code: fakeErrNoteNotFound, Message: "note not found"}
+	}
+	apply(note)
+	note.ModificationDate = f.now()
+	return url.Values{}, nil
+}
+
+func attachmentMarkdown(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".heic", ".webp":
+		return fmt.Sprintf("![](%s)", name)
+	default:
+		return fmt.Sprintf("[%s](%s)", name, name)
+	}
+}
+
+func formatTagLine(tags []string) string {
+	parts := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		parts = append(parts, "#"+tag)
+	}
+	return strings.Join(parts, " ")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "yes"
+	}
+	return "no"
+}
+
+func mustJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+func newFakeIdentifier() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	h := strings.ToUpper(hex.EncodeToString(buf))
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:32])
+}
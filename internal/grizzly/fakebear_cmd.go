@@ -0,0 +1,147 @@
+package grizzly
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newFakeBearCmd(opts *Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fake-bear",
+		Short: "Run a local Bear simulator for testing and demos",
+	}
+	cmd.AddCommand(newFakeBearServeCmd(opts))
+	cmd.AddCommand(newFakeBearOpenCmd(opts))
+	return cmd
+}
+
+func newFakeBearServeCmd(opts *Options) *cobra.Command {
+	var listen string
+	var store string
+	var token string
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the simulator for the http opener",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fb, err := loadFakeBear(opts, store, token, cmd.Flags().Changed("token"))
+			if err != nil {
+				return &ExitError{Code: ExitFailure, Err: err}
+			}
+			ln, err := net.Listen("tcp", listen)
+			if err != nil {
+				return &ExitError{Code: ExitFailure, Err: err}
+			}
+
+			server := &http.Server{Handler: fb.handler(opts)}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				defer cancel()
+				_ = server.Shutdown(shutdownCtx)
+			}()
+
+			fmt.Fprintf(os.Stderr, "fake-bear listening on http://%s/open\n", ln.Addr())
+			if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return &ExitError{Code: ExitFailure, Err: err}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&listen, "listen", "127.0.0.1:8787", "Address to listen on")
+	cmd.Flags().StringVar(&store, "store", "", "JSON note store (default: fake_bear_store, or in-memory)")
+	cmd.Flags().StringVar(&token, "token", "", "API token the simulator accepts (empty accepts any)")
+	return cmd
+}
+
+func newFakeBearOpenCmd(opts *Options) *cobra.Command {
+	var store string
+	var token string
+
+	cmd := &cobra.Command{
+		Use:   "open <url>",
+		Short: "Handle a single bear:// URL and deliver its callback",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fb, err := loadFakeBear(opts, store, token, cmd.Flags().Changed("token"))
+			if err != nil {
+				return &ExitError{Code: ExitFailure, Err: err}
+			}
+			callback, err := fb.Handle(args[0])
+			if err != nil {
+				return usageError(cmd, "%v", err)
+			}
+			if callback != "" {
+				fmt.Fprintln(os.Stdout, callback)
+			}
+			if err := fb.deliver(cmd.Context(), callback); err != nil {
+				return &ExitError{Code: ExitCallback, Err: err}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&store, "store", "", "JSON note store (default: fake_bear_store, or in-memory)")
+	cmd.Flags().StringVar(&token, "token", "", "API token the simulator accepts (empty accepts any)")
+	return cmd
+}
+
+func loadFakeBear(opts *Options, store string, token string, tokenSet bool) (*FakeBear, error) {
+	if store == "" {
+		store = opts.FakeBearStore
+	}
+	fb, err := NewFakeBear(store)
+	if err != nil {
+		return nil, err
+	}
+	if tokenSet {
+		if err := fb.SetToken(token); err != nil {
+			return nil, err
+		}
+	}
+	return fb, nil
+}
+
+func (f *FakeBear) handler(opts *Options) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/open", func(w http.ResponseWriter, r *http.Request) {
+		rawURL := r.FormValue("url")
+		if opts.Verbose {
+			fmt.Fprintf(os.Stderr, "fake-bear: %s\n", rawURL)
+		}
+		callback, err := f.Handle(rawURL)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := f.deliver(r.Context(), callback); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/notes", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		data, err := json.Marshal(f.state.Notes)
+		f.mu.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	})
+	return mux
+}
@@ -0,0 +1,114 @@
+package grizzly
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func fakeCall(t *testing.T, fb *FakeBear, action string, params url.Values) url.Values {
+	t.Helper()
+	params.Set("x-success", "gzlcb://127.0.0.1:1/success")
+	params.Set("x-error", "gzlcb://127.0.0.1:1/error")
+	callback, err := fb.Handle(BuildURL(action, params))
+	if err != nil {
+		t.Fatalf("Handle(%s): %v", action, err)
+	}
+	u, err := url.Parse(callback)
+	if err != nil {
+		t.Fatalf("parse callback: %v", err)
+	}
+	values := u.Query()
+	if u.Path == "/error" {
+		values.Set("_error", "yes")
+	}
+	return values
+}
+
+func TestFakeBearAddTextUnderHeader(t *testing.T) {
+	fb, err := NewFakeBear("")
+	if err != nil {
+		t.Fatalf("NewFakeBear: %v", err)
+	}
+	created := fakeCall(t, fb, "create", url.Values{"title": {"Plan"}, "text": {"## Todo\n- one\n## Done\n- zero"}})
+	id := created.Get("identifier")
+	if id == "" || created.Get("title") != "Plan" {
+		t.Fatalf("create = %v", created)
+	}
+
+	fakeCall(t, fb, "add-text", url.Values{"id": {id}, "header": {"Todo"}, "text": {"- two"}, "new_line": {"yes"}})
+	fakeCall(t, fb, "add-text", url.Values{"id": {id}, "header": {"Done"}, "text": {"- first"}, "mode": {"prepend"}})
+	got := fakeCall(t, fb, "open-note", url.Values{"id": {id}}).Get("note")
+	want := "# Plan\n## Todo\n- one\n- two\n## Done\n- first\n- zero"
+	if got != want {
+		t.Fatalf("note = %q, want %q", got, want)
+	}
+
+	fakeCall(t, fb, "add-text", url.Values{"title": {"plan"}, "text": {"# New"}, "mode": {"replace_all"}})
+	if got := fakeCall(t, fb, "open-note", url.Values{"id": {id}}).Get("title"); got != "New" {
+		t.Fatalf("title after replace_all = %q", got)
+	}
+}
+
+func TestFakeBearErrorsAndTokens(t *testing.T) {
+	fb, err := NewFakeBear("")
+	if err != nil {
+		t.Fatalf("NewFakeBear: %v", err)
+	}
+	if err := fb.SetToken("secret"); err != nil {
+		t.Fatalf("SetToken: %v", err)
+	}
+	fakeCall(t, fb, "create", url.Values{"title": {"A"}, "tags": {"work,home"}})
+
+	res := fakeCall(t, fb, "open-note", url.Values{"id": {"missing"}})
+	if res.Get("_error") != "yes" || res.Get("errorCode") != fakeErrNoteNotFound {
+		t.Fatalf("open-note missing = %v", res)
+	}
+	res = fakeCall(t, fb, "tags", url.Values{"token": {"wrong"}})
+	if res.Get("errorCode") != fakeErrInvalidToken {
+		t.Fatalf("tags with bad token = %v", res)
+	}
+	res = fakeCall(t, fb, "tags", url.Values{"token": {"secret"}})
+	if res.Get("tags") != `[{"name":"home"},{"name":"work"}]` {
+		t.Fatalf("tags = %q", res.Get("tags"))
+	}
+	res = fakeCall(t, fb, "search", url.Values{"tag": {"work"}})
+	if res.Get("notes") != "" {
+		t.Fatalf("search without token returned notes: %v", res)
+	}
+	for _, action := range []string{"trash", "archive"} {
+		res = fakeCall(t, fb, action, url.Values{"search": {"A"}})
+		if res.Get("errorCode") != fakeErrInvalidParameter {
+			t.Fatalf("%s by search = %v", action, res)
+		}
+	}
+}
+
+func TestFakeBearDeliversToCallbackServer(t *testing.T) {
+	fb, err := NewFakeBear("")
+	if err != nil {
+		t.Fatalf("NewFakeBear: %v", err)
+	}
+	server, err := StartCallbackServer()
+	if err != nil {
+		t.Fatalf("StartCallbackServer: %v", err)
+	}
+	params := url.Values{}
+	params.Set("title", "Hello")
+	params.Set("x-success", server.SuccessURL)
+	params.Set("x-error", server.ErrorURL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := fb.Open(ctx, BuildURL("create", params)); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	res, err := server.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if !res.Success || res.Values.Get("title") != "Hello" {
+		t.Fatalf("callback = %#v", res)
+	}
+}
@@ -0,0 +1,362 @@
+package grizzly
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"grizzly/pkg/bear"
+)
+
+// Formatter writes a result's records in one output format.
+type Formatter interface {
+	Format(w io.Writer, rs recordSet) error
+}
+
+// recordSet is a result as records keyed by JSON field name. Fields selects
+// and orders the columns; List is set for note and tag lists, even when they
+// hold a single record.
+type recordSet struct {
+	Records []map[string]any
+	Fields  []string
+	List    bool
+}
+
+// formatFactory builds a Formatter from the text after "=" in
+// --format name=arg; arg is empty when there is none.
+type formatFactory func(arg string) (Formatter, error)
+
+var formats = map[string]formatFactory{}
+
+func registerFormat(name string, factory formatFactory) {
+	formats[name] = factory
+}
+
+func init() {
+	registerFormat("table", noArgFormat("table", tableFormat{}))
+	registerFormat("csv", noArgFormat("csv", delimitedFormat{comma: ','}))
+	registerFormat("tsv", noArgFormat("tsv", delimitedFormat{comma: '\t'}))
+	registerFormat("yaml", noArgFormat("yaml", yamlFormat{}))
+	registerFormat("ndjson", noArgFormat("ndjson", ndjsonFormat{}))
+	registerFormat("template", newTemplateFormat)
+}
+
+func noArgFormat(name string, f Formatter) formatFactory {
+	return func(arg string) (Formatter, error) {
+		if arg != "" {
+			return nil, fmt.Errorf("--format %s takes no argument", name)
+		}
+		return f, nil
+	}
+}
+
+// formatNames lists the --format values, including the built-in json, plain
+// and human modes.
+func formatNames() []string {
+	names := []string{"json", "plain", "human"}
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names[3:])
+	return names
+}
+
+func lookupFormat(spec string) (Formatter, error) {
+	name, arg, _ := strings.Cut(spec, "=")
+	factory, ok := formats[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q (want %s)", name, strings.Join(formatNames(), ", "))
+	}
+	return factory(arg)
+}
+
+// report is a command result that renders itself, so a new report type
+// needs no case in formatRecords or writeHuman.
+type report interface {
+	// records returns the report as records for --format.
+	records() recordSet
+	// writeHuman writes the report for people.
+	writeHuman(w io.Writer, style termStyle)
+}
+
+// formatRecords turns a result into records with their default field order.
+// Note and tag lists yield one record per item, reports their own records.
+func formatRecords(res Result) recordSet {
+	switch data := res.Data.(type) {
+	case report:
+		return data.records()
+	case nil:
+		rs := recordSet{Records: []map[string]any{{"ok": true, "action": res.Action}}, Fields: []string{"ok", "action"}}
+		if res.URL != "" {
+			rs.Records[0]["url"] = res.URL
+			rs.Fields = append(rs.Fields, "url")
+		}
+		return rs
+	case *bear.NotesResult:
+		rs := recordSet{Fields: jsonFieldOrder(bear.NoteSummary{}), List: true}
+		for _, note := range data.Notes {
+			rs.Records = append(rs.Records, dataFields(note))
+		}
+		return rs
+	case *bear.TagsResult:
+		rs := recordSet{Fields: jsonFieldOrder(bear.Tag{}), List: true}
+		for _, tag := range data.Tags {
+			rs.Records = append(rs.Records, dataFields(tag))
+		}
+		return rs
+	case map[string]any:
+		keys := make([]string, 0, len(data))
+		for key := range data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return recordSet{Records: []map[string]any{data}, Fields: keys}
+	default:
+		return singleRecord(data)
+	}
+}
+
+// singleRecord is v as one record in its JSON field order.
+func singleRecord(v any) recordSet {
+	return recordSet{Records: []map[string]any{dataFields(v)}, Fields: jsonFieldOrder(v)}
+}
+
+// listRecords is items as one record each, with the fields of item.
+func listRecords[T any](items []T, item T) recordSet {
+	rs := recordSet{Fields: jsonFieldOrder(item), List: true}
+	for _, v := range items {
+		rs.Records = append(rs.Records, dataFields(v))
+	}
+	return rs
+}
+
+// jsonFieldOrder returns the top-level keys of v's JSON object in the order
+// encoding/json writes them.
+func jsonFieldOrder(v any) []string {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return keys
+		}
+		keys = append(keys, tok.(string))
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return keys
+		}
+	}
+	return keys
+}
+
+// selectFields applies --fields, matching names case-insensitively. Unknown
+// fields are kept and render empty.
+func (rs recordSet) selectFields(requested []string) recordSet {
+	if len(requested) == 0 {
+		return rs
+	}
+	fields := make([]string, 0, len(requested))
+	for _, want := range requested {
+		name := want
+		for _, have := range rs.Fields {
+			if strings.EqualFold(have, want) {
+				name = have
+				break
+			}
+		}
+		fields = append(fields, name)
+	}
+	rs.Fields = fields
+	return rs
+}
+
+// cellText renders a value for table, csv and tsv cells.
+func cellText(v any) string {
+	switch typed := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return typed
+	case []any:
+		parts := make([]string, len(typed))
+		for i, item := range typed {
+			parts[i] = cellText(item)
+		}
+		return strings.Join(parts, ",")
+	case map[string]any:
+		return formatJSONLine(typed)
+	default:
+		return fmt.Sprintf("%v", typed)
+	}
+}
+
+type tableFormat struct{}
+
+func (tableFormat) Format(w io.Writer, rs recordSet) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := make([]string, len(rs.Fields))
+	for i, field := range rs.Fields {
+		header[i] = strings.ToUpper(field)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, record := range rs.Records {
+		row := make([]string, len(rs.Fields))
+		for i, field := range rs.Fields {
+			row[i] = escapePlain(cellText(record[field]))
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+type delimitedFormat struct {
+	comma rune
+}
+
+func (f delimitedFormat) Format(w io.Writer, rs recordSet) error {
+	if f.comma == '\t' {
+		// TSV has no quoting; escape tabs and newlines like plain output.
+		fmt.Fprintln(w, strings.Join(rs.Fields, "\t"))
+		for _, record := range rs.Records {
+			row := make([]string, len(rs.Fields))
+			for i, field := range rs.Fields {
+				row[i] = escapePlain(cellText(record[field]))
+			}
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		return nil
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = f.comma
+	if err := cw.Write(rs.Fields); err != nil {
+		return err
+	}
+	for _, record := range rs.Records {
+		row := make([]string, len(rs.Fields))
+		for i, field := range rs.Fields {
+			row[i] = cellText(record[field])
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type yamlFormat struct{}
+
+func (yamlFormat) Format(w io.Writer, rs recordSet) error {
+	nodes := make([]*yaml.Node, 0, len(rs.Records))
+	for _, record := range rs.Records {
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, field := range rs.Fields {
+			value := &yaml.Node{}
+			if err := value.Encode(record[field]); err != nil {
+				return err
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field}, value)
+		}
+		nodes = append(nodes, node)
+	}
+	doc := &yaml.Node{Kind: yaml.SequenceNode, Content: nodes}
+	if !rs.List && len(nodes) == 1 {
+		doc = nodes[0]
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+type ndjsonFormat struct{}
+
+func (ndjsonFormat) Format(w io.Writer, rs recordSet) error {
+	for _, record := range rs.Records {
+		var b bytes.Buffer
+		b.WriteByte('{')
+		for i, field := range rs.Fields {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			key, _ := json.Marshal(field)
+			value, err := json.Marshal(record[field])
+			if err != nil {
+				return err
+			}
+			b.Write(key)
+			b.WriteByte(':')
+			b.Write(value)
+		}
+		b.WriteString("}\n")
+		if _, err := w.Write(b.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templateFormat executes a Go template once per record, with the record's
+// fields as the dot: --format 'template={{.title}} {{.identifier}}'.
+type templateFormat struct {
+	tmpl *template.Template
+}
+
+func newTemplateFormat(arg string) (Formatter, error) {
+	if arg == "" {
+		return nil, fmt.Errorf("--format template requires a template: template=<go-template>")
+	}
+	tmpl, err := template.New("format").Funcs(template.FuncMap{
+		"join": func(sep string, v any) string {
+			items, ok := v.([]any)
+			if !ok {
+				return cellText(v)
+			}
+			parts := make([]string, len(items))
+			for i, item := range items {
+				parts[i] = cellText(item)
+			}
+			return strings.Join(parts, sep)
+		},
+		"json":  formatJSONLine,
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+	}).Parse(arg)
+	if err != nil {
+		return nil, fmt.Errorf("--format template: %w", err)
+	}
+	return templateFormat{tmpl: tmpl}, nil
+}
+
+func (f templateFormat) Format(w io.Writer, rs recordSet) error {
+	for _, record := range rs.Records {
+		var b bytes.Buffer
+		if err := f.tmpl.Execute(&b, record); err != nil {
+			return err
+		}
+		if !bytes.HasSuffix(b.Bytes(), []byte("\n")) {
+			b.WriteByte('\n')
+		}
+		if _, err := w.Write(b.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package grizzly
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"grizzly/pkg/bear"
+)
+
+func TestOutputFormats(t *testing.T) {
+	notes := &bear.NotesResult{Notes: []bear.NoteSummary{
+		{Identifier: "1", Title: "Plan, v2", Tags: []string{"work", "q3"}, ModificationDate: time.Date(2024, 3, 14, 9, 0, 0, 0, time.UTC)},
+		{Identifier: "2", Title: "Trip\tnotes"},
+	}}
+	cases := []struct {
+		format string
+		fields []string
+		data   any
+		want   string
+	}{
+		{"csv", []string{"title", "TAGS", "identifier"}, notes, "title,tags,identifier\n\"Plan, v2\",\"work,q3\",1\nTrip\tnotes,,2\n"},
+		{"tsv", []string{"identifier", "title"}, notes, "identifier\ttitle\n1\tPlan, v2\n2\tTrip\\tnotes\n"},
+		{"table", []string{"identifier", "title"}, notes, "IDENTIFIER  TITLE\n1           Plan, v2\n2           Trip\\tnotes\n"},
+		{"ndjson", []string{"identifier", "pinned", "missing"}, notes, "{\"identifier\":\"1\",\"pinned\":false,\"missing\":null}\n{\"identifier\":\"2\",\"pinned\":false,\"missing\":null}\n"},
+		{"yaml", []string{"identifier", "tags"}, &bear.NotesResult{Notes: notes.Notes[:1]}, "- identifier: \"1\"\n  tags:\n    - work\n    - q3\n"},
+		{"yaml", []string{"title", "identifier"}, &bear.Note{Identifier: "1", Title: "Plan"}, "title: Plan\nidentifier: \"1\"\n"},
+		{`template={{.title}} [{{join " " .tags}}]`, nil, notes, "Plan, v2 [work q3]\nTrip\tnotes []\n"},
+		{"ndjson", nil, &bear.TagsResult{Tags: []bear.Tag{{Name: "work"}}}, "{\"name\":\"work\"}\n"},
+		{"csv", []string{"key", "value"}, &configListReport{Settings: []configSetting{{Key: "timeout", Value: "5s"}, {Key: "opener", Value: "fake"}}}, "key,value\ntimeout,5s\nopener,fake\n"},
+	}
+	for _, tc := range cases {
+		var buf bytes.Buffer
+		out := &Outputter{opts: &Options{Format: tc.format, Fields: tc.fields}, stdout: &buf, stderr: &buf}
+		out.WriteSuccess(Result{Action: "search", Data: tc.data})
+		if buf.String() != tc.want {
+			t.Fatalf("--format %s:\n got %q\nwant %q", tc.format, buf.String(), tc.want)
+		}
+	}
+}
+
+func TestLookupFormat(t *testing.T) {
+	for _, spec := range []string{"table", "csv", "tsv", "yaml", "ndjson", "template={{.title}}"} {
+		if _, err := lookupFormat(spec); err != nil {
+			t.Fatalf("lookupFormat(%q): %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"xml", "template", "template={{.title", "csv=x"} {
+		if _, err := lookupFormat(spec); err == nil {
+			t.Fatalf("lookupFormat(%q) expected error", spec)
+		}
+	}
+}
+
+func TestOutputReportHuman(t *testing.T) {
+	var buf bytes.Buffer
+	out := &Outputter{opts: &Options{}, stdout: &buf, stderr: &buf}
+	out.WriteSuccess(Result{Action: "config-get", Data: &configSetting{Key: "timeout", Value: "5s", Origin: "user"}})
+	if buf.String() != "5s\n" {
+		t.Fatalf("human output = %q", buf.String())
+	}
+}
@@ -0,0 +1,435 @@
+package grizzly
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"grizzly/pkg/bear"
+)
+
+const importManifestName = ".grizzly-import.json"
+
+// dryRunNoteID stands in for the identifier Bear would return when URLs are
+// only printed.
+const dryRunNoteID = "NEW-NOTE-ID"
+
+type importManifest struct {
+	Version int                            `json:"version"`
+	Files   map[string]importManifestEntry `json:"files"`
+}
+
+type importManifestEntry struct {
+	Identifier string    `json:"identifier"`
+	Title      string    `json:"title,omitempty"`
+	SHA256     string    `json:"sha256"`
+	Imported   time.Time `json:"imported"`
+}
+
+type importedFile struct {
+	Path        string   `json:"path"`
+	Status      string   `json:"status"`
+	Identifier  string   `json:"identifier,omitempty"`
+	Title       string   `json:"title,omitempty"`
+	Attachments int      `json:"attachments,omitempty"`
+	URLs        []string `json:"urls,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+type importReport struct {
+	Dir     string         `json:"dir"`
+	DryRun  bool           `json:"dry_run,omitempty"`
+	Total   int            `json:"total"`
+	Created int            `json:"created"`
+	Skipped int            `json:"skipped"`
+	Failed  int            `json:"failed"`
+	Files   []importedFile `json:"files"`
+}
+
+// importFileFields orders the columns of the per-file records; encoding
+// importedFile{} would drop the empty optional fields.
+var importFileFields = []string{"path", "status", "identifier", "title", "attachments", "urls", "error"}
+
+func (r *importReport) records() recordSet {
+	rs := listRecords(r.Files, importedFile{})
+	rs.Fields = importFileFields
+	return rs
+}
+
+// writeHuman writes a table with one row per file, the URLs of a dry run and
+// a summary line.
+func (r *importReport) writeHuman(w io.Writer, style termStyle) {
+	rows := make([][]tableCell, len(r.Files))
+	for i, file := range r.Files {
+		status := tableCell{text: file.Status}
+		note := tableCell{text: file.Identifier}
+		if file.Error != "" {
+			status.style = func(s string) string { return style.paint(ansiRed, s) }
+			note = tableCell{text: file.Error, style: status.style}
+		}
+		rows[i] = []tableCell{{text: file.Path}, status, {text: file.Title}, note}
+	}
+	style.writeTable(w, []string{"FILE", "STATUS", "TITLE", "NOTE"}, rows, []int{0, 2, 3})
+	for _, file := range r.Files {
+		for _, u := range file.URLs {
+			fmt.Fprintln(w, u)
+		}
+	}
+	fmt.Fprintf(w, "%d created, %d skipped, %d failed\n", r.Created, r.Skipped, r.Failed)
+}
+
+type importer struct {
+	opts        *Options
+	client      *bear.Client
+	dir         string
+	tags        []string
+	attachments bool
+	manifest    importManifest
+}
+
+func newImportCmd(opts *Options) *cobra.Command {
+	var tags []string
+	var tagsCSV string
+	var noAttachments bool
+
+	cmd := &cobra.Command{
+		Use:   "import <dir>",
+		Short: "Create notes from a directory of Markdown files",
+		Long: `Create one note per Markdown file under a directory.
+
+Optional YAML front matter supplies title, tags and pinned. Relative links to
+local files inside the directory (![](img.png), [spec](spec.pdf)) are removed
+from the text and attached with add-file. A manifest (` + importManifestName + `) in the directory
+maps each file to the note Bear created, so re-runs skip files that were
+already imported. With --dry-run the URLs are listed and nothing is opened.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tagParam, err := mergeTags(tags, tagsCSV)
+			if err != nil {
+				return &ExitError{Code: ExitUsage, Err: err}
+			}
+			dir, err := expandPath(args[0])
+			if err != nil {
+				return &ExitError{Code: ExitUsage, Err: err}
+			}
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				return usageError(cmd, "not a directory: %s", args[0])
+			}
+
+			out := NewOutputter(opts)
+			var client *bear.Client
+			if opts.DryRun {
+				client, err = newClient(opts, "")
+			} else {
+				var done func()
+				client, done, err = newSessionClient(opts, "")
+				if done != nil {
+					defer done()
+				}
+			}
+			if err != nil {
+				return usageError(cmd, "%v", err)
+			}
+
+			imp := &importer{opts: opts, client: client, dir: dir, tags: splitCSV(tagParam), attachments: !noAttachments}
+			report, err := imp.run(cmd.Context())
+			if err != nil {
+				return writeClientError(out, "import", err)
+			}
+			out.WriteSuccess(Result{Action: "import", Data: report})
+			if report.Failed > 0 {
+				return &ExitError{Code: ExitFailure, Err: fmt.Errorf("%d of %d files failed to import", report.Failed, report.Total)}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringArrayVar(&tags, "tag", nil, "Tag to add to every note (repeatable)")
+	cmd.Flags().StringVar(&tagsCSV, "tags", "", "Comma-separated tags to add to every note")
+	cmd.Flags().BoolVar(&noAttachments, "no-attachments", false, "Leave local file links in the text instead of attaching them")
+	return cmd
+}
+
+func (im *importer) run(ctx context.Context) (*importReport, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := im.loadManifest(); err != nil {
+		return nil, err
+	}
+	files, err := markdownFiles(im.dir)
+	if err != nil {
+		return nil, err
+	}
+	report := &importReport{Dir: im.dir, DryRun: im.opts.DryRun, Files: []importedFile{}}
+	for _, rel := range files {
+		file := im.importFile(ctx, rel)
+		report.Total++
+		switch file.Status {
+		case "created", "dry-run":
+			report.Created++
+		case "skipped":
+			report.Skipped++
+		default:
+			report.Failed++
+		}
+		report.Files = append(report.Files, file)
+	}
+	return report, nil
+}
+
+// markdownFiles lists .md and .markdown files under dir, skipping hidden
+// files and directories.
+func markdownFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !isMarkdownFile(path) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+func isMarkdownFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return true
+	default:
+		return false
+	}
+}
+
+func (im *importer) importFile(ctx context.Context, rel string) importedFile {
+	file := importedFile{Path: rel}
+	if entry, ok := im.manifest.Files[rel]; ok {
+		file.Status = "skipped"
+		file.Identifier = entry.Identifier
+		file.Title = entry.Title
+		return file
+	}
+
+	path := filepath.Join(im.dir, filepath.FromSlash(rel))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return failImport(file, err)
+	}
+	meta, body, err := parseNoteFile(data)
+	if err != nil {
+		return failImport(file, err)
+	}
+	var attachments []string
+	if im.attachments {
+		body, attachments = extractAttachments(body, filepath.Dir(path), im.dir)
+	}
+
+	text := fullNoteText(rel, meta, body)
+	file.Title = noteTitle(text)
+	create := bear.CreateOptions{
+		Window: bear.Window{NoShowWindow: true, NoOpen: true},
+		Text:   text,
+		Tags:   tagsNotInText(text, append(append([]string{}, meta.Tags...), im.tags...)),
+		Pin:    meta.Pinned,
+	}
+
+	callCtx, cancel := withCallTimeout(ctx, im.opts)
+	created, err := im.client.Create(callCtx, create)
+	cancel()
+	if err != nil {
+		return failImport(file, err)
+	}
+	file.URLs = append(file.URLs, bear.RedactURL(created.URL))
+	file.Identifier = created.Identifier
+	if im.opts.DryRun {
+		file.Identifier = dryRunNoteID
+	}
+	if file.Identifier == "" {
+		return failImport(file, fmt.Errorf("bear did not return an identifier"))
+	}
+
+	var attachErr error
+	for _, attachment := range attachments {
+		encoded, name, _, err := loadFileParam(attachment, "")
+		if err == nil {
+			callCtx, cancel := withCallTimeout(ctx, im.opts)
+			var resp *bear.Response
+			resp, err = im.client.AddFile(callCtx, bear.AddFileOptions{
+				NoteRef: bear.NoteRef{ID: file.Identifier},
+				Window:  bear.Window{NoShowWindow: true, NoOpen: true},
+				File:    bear.File{Name: name, Base64: encoded},
+				Mode:    bear.ModeAppend,
+			})
+			cancel()
+			if err == nil {
+				file.URLs = append(file.URLs, bear.RedactURL(resp.URL))
+				file.Attachments++
+				continue
+			}
+		}
+		if attachErr == nil {
+			attachErr = fmt.Errorf("attach %s: %w", filepath.Base(attachment), err)
+		}
+	}
+
+	if im.opts.DryRun {
+		file.Status = "dry-run"
+		return file
+	}
+	file.URLs = nil
+	sum := sha256.Sum256(data)
+	im.manifest.Files[rel] = importManifestEntry{
+		Identifier: file.Identifier,
+		Title:      file.Title,
+		SHA256:     hex.EncodeToString(sum[:]),
+		Imported:   time.Now().UTC(),
+	}
+	if err := im.saveManifest(); err != nil {
+		return failImport(file, err)
+	}
+	if attachErr != nil {
+		// The note exists and is in the manifest; report the missing
+		// attachment without creating a duplicate on the next run.
+		return failImport(file, attachErr)
+	}
+	file.Status = "created"
+	return file
+}
+
+func failImport(file importedFile, err error) importedFile {
+	file.Status = "failed"
+	file.Error = err.Error()
+	return file
+}
+
+// noteTitle returns the text of a leading "# " heading, which Bear uses as
+// the note title.
+func noteTitle(body string) string {
+	first, _, _ := strings.Cut(strings.TrimLeft(body, "\n"), "\n")
+	if title, ok := strings.CutPrefix(first, "# "); ok {
+		return strings.TrimSpace(title)
+	}
+	return ""
+}
+
+// fullNoteText returns the note text for a file: its body, headed by
+// "# title" from the front matter or file name when the body has no heading.
+func fullNoteText(rel string, meta noteFrontMatter, body string) string {
+	body = strings.TrimRight(strings.TrimLeft(body, "\n"), "\n")
+	if noteTitle(body) != "" {
+		return body
+	}
+	title := meta.Title
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(rel), filepath.Ext(rel))
+	}
+	if body == "" {
+		return "# " + title
+	}
+	return "# " + title + "\n" + body
+}
+
+var markdownLinkPattern = regexp.MustCompile(`!?\[[^\]]*\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+
+// extractAttachments removes links to local, non-Markdown files relative to
+// baseDir and inside rootDir, and returns their paths.
+func extractAttachments(body, baseDir, rootDir string) (string, []string) {
+	var paths []string
+	seen := map[string]bool{}
+	out := markdownLinkPattern.ReplaceAllStringFunc(body, func(link string) string {
+		target := markdownLinkPattern.FindStringSubmatch(link)[1]
+		path, ok := localLinkTarget(target, baseDir, rootDir)
+		if !ok {
+			return link
+		}
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+		return ""
+	})
+	return out, paths
+}
+
+// localLinkTarget resolves a relative link to a regular file. Links that
+// lead outside rootDir, through ".." or a symlink, are left alone so a
+// Markdown file cannot upload arbitrary local files.
+func localLinkTarget(target, baseDir, rootDir string) (string, bool) {
+	u, err := url.Parse(strings.Trim(target, "<>"))
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" || strings.HasPrefix(u.Path, "/") {
+		return "", false
+	}
+	path := filepath.Join(baseDir, filepath.FromSlash(u.Path))
+	if isMarkdownFile(path) {
+		return "", false
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", false
+	}
+	root, err := filepath.EvalSymlinks(rootDir)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	info, err := os.Stat(resolved)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	return path, true
+}
+
+func (im *importer) loadManifest() error {
+	im.manifest = importManifest{Version: 1, Files: map[string]importManifestEntry{}}
+	data, err := os.ReadFile(filepath.Join(im.dir, importManifestName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := json.Unmarshal(data, &im.manifest); err != nil {
+		return fmt.Errorf("read %s: %w", importManifestName, err)
+	}
+	if im.manifest.Files == nil {
+		im.manifest.Files = map[string]importManifestEntry{}
+	}
+	return nil
+}
+
+func (im *importer) saveManifest() error {
+	data, err := json.MarshalIndent(im.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(im.dir, importManifestName), append(data, '\n'), 0o644)
+}
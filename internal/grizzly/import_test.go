@@ -0,0 +1,109 @@
+package grizzly
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImporterCreatesNotesOnce(t *testing.T) {
+	fb, client := newFakeSession(t)
+	dir := t.TempDir()
+	writeFile := func(rel, content string) {
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("trip.md", "---\ntitle: Trip\ntags: [travel]\npinned: true\n---\nPacking\n![map](img/map.png)\n[site](https://example.com)\n")
+	writeFile("img/map.png", "PNG")
+	writeFile("notes/plain.md", "# Plain\nbody\n")
+	writeFile(".hidden/skip.md", "# Hidden\n")
+	writeFile("readme.txt", "not markdown")
+	writeFile("tagged.md", "---\ntags: [travel]\n---\n# Tagged\n#travel\n")
+
+	opts := &Options{Timeout: 2 * time.Second}
+	report, err := (&importer{opts: opts, client: client, dir: dir, tags: []string{"imported"}, attachments: true}).run(context.Background())
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if report.Total != 3 || report.Created != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	trip := fakeCall(t, fb, "open-note", url.Values{"title": {"Trip"}})
+	if trip.Get("pin") != "yes" || !strings.Contains(trip.Get("tags"), "imported") {
+		t.Fatalf("trip = %v", trip)
+	}
+	note := trip.Get("note")
+	if strings.Contains(note, "img/map.png") || !strings.Contains(note, "map.png") || !strings.Contains(note, "https://example.com") {
+		t.Fatalf("note = %q", note)
+	}
+	if got := fakeCall(t, fb, "open-note", url.Values{"title": {"Plain"}}).Get("note"); !strings.HasPrefix(got, "# Plain\nbody") {
+		t.Fatalf("plain = %q", got)
+	}
+	if got := fakeCall(t, fb, "open-note", url.Values{"title": {"Tagged"}}).Get("note"); strings.Count(got, "#travel") != 1 || !strings.Contains(got, "#imported") {
+		t.Fatalf("tagged = %q", got)
+	}
+
+	report, err = (&importer{opts: opts, client: client, dir: dir, attachments: true}).run(context.Background())
+	if err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if report.Skipped != 3 || report.Created != 0 {
+		t.Fatalf("second report = %+v", report)
+	}
+
+	// Table formats and human output give one row per file.
+	var buf bytes.Buffer
+	out := &Outputter{opts: &Options{Format: "csv", Fields: []string{"path", "status"}}, stdout: &buf, stderr: &buf}
+	out.WriteSuccess(Result{Action: "import", Data: report})
+	if want := "path,status\nnotes/plain.md,skipped\ntagged.md,skipped\ntrip.md,skipped\n"; buf.String() != want {
+		t.Fatalf("csv = %q", buf.String())
+	}
+	buf.Reset()
+	out = &Outputter{opts: &Options{}, stdout: &buf, stderr: &buf}
+	out.WriteSuccess(Result{Action: "import", Data: report})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "FILE") || !strings.HasPrefix(lines[3], "trip.md") || lines[4] != "0 created, 3 skipped, 0 failed" {
+		t.Fatalf("human = %q", buf.String())
+	}
+}
+
+func TestExtractAttachmentsStaysInRoot(t *testing.T) {
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "id_rsa")
+	if err := os.WriteFile(secret, []byte("key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(t.TempDir(), "notes")
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "sub", "ok.png"), []byte("PNG"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(root, "sub", "link.png")); err != nil {
+		t.Fatal(err)
+	}
+	rel, err := filepath.Rel(filepath.Join(root, "sub"), secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := "[key](" + filepath.ToSlash(rel) + ")\n[link](link.png)\n![ok](../sub/ok.png)\n"
+	out, paths := extractAttachments(body, filepath.Join(root, "sub"), root)
+	if len(paths) != 1 || filepath.Base(paths[0]) != "ok.png" {
+		t.Fatalf("paths = %q", paths)
+	}
+	if !strings.Contains(out, "[key](") || !strings.Contains(out, "[link](link.png)") {
+		t.Fatalf("body = %q", out)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"golang.org/x/term"
@@ -71,6 +72,9 @@ func readTokenFromFile(path string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if err := checkTokenFileMode(expanded); err != nil {
+		return "", err
+	}
 	data, err := os.ReadFile(expanded)
 	if err != nil {
 		return "", err
@@ -78,6 +82,21 @@ func readTokenFromFile(path string) (string, error) {
 	return strings.TrimSpace(string(data)), nil
 }
 
+// checkTokenFileMode refuses token files that other users can read.
+func checkTokenFileMode(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0o004 != 0 {
+		return fmt.Errorf("token file %s is readable by other users; run: chmod 600 %s", path, path)
+	}
+	return nil
+}
+
 func readTokenFromStdin() (string, error) {
 	reader := bufio.NewReader(os.Stdin)
 	line, err := reader.ReadString('\n')
@@ -87,6 +106,17 @@ func readTokenFromStdin() (string, error) {
 	return strings.TrimSpace(line), nil
 }
 
+// promptToken reads the token from the terminal without echoing it.
+func promptToken() (string, error) {
+	fmt.Fprint(os.Stderr, "Bear API token: ")
+	data, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
 func confirmPrompt(msg string) (bool, error) {
 	fmt.Fprint(os.Stderr, msg)
 	reader := bufio.NewReader(os.Stdin)
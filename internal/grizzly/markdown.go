@@ -0,0 +1,507 @@
+package grizzly
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ANSI codes for rendered Markdown.
+const (
+	mdHeading   = "1;34"
+	mdTitle     = "1;4;34"
+	mdBold      = "1"
+	mdItalic    = "3"
+	mdStrike    = "9"
+	mdHighlight = "30;43"
+	mdCode      = "33"
+	mdTag       = "36"
+	mdLink      = "4;34"
+	mdMuted     = "2"
+	mdKeyword   = "35"
+	mdString    = "32"
+	mdNumber    = "36"
+)
+
+var (
+	mdHeadingLine   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	mdRuleLine      = regexp.MustCompile(`^(?:-\s*){3,}$|^(?:\*\s*){3,}$|^(?:_\s*){3,}$`)
+	mdChecklistLine = regexp.MustCompile(`^(\s*)[-*+]\s+\[([ xX])\]\s*(.*)$`)
+	mdBulletLine    = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdOrderedLine   = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	mdQuoteLine     = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	mdTableDivider  = regexp.MustCompile(`^\s*:?-+:?\s*$`)
+)
+
+// mdSpan is a run of inline text with the ANSI codes and link target it is
+// drawn with.
+type mdSpan struct {
+	text  string
+	codes []string
+	link  string
+}
+
+type mdRenderer struct {
+	style termStyle
+	b     strings.Builder
+	blank bool
+}
+
+// renderMarkdown renders Bear-flavored Markdown for the terminal, wrapping
+// text to the style's width. Bear keeps single line breaks, so every source
+// line is its own paragraph.
+func renderMarkdown(text string, style termStyle) string {
+	r := &mdRenderer{style: style, blank: true}
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence := trimmed[:3]
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			r.codeBlock(strings.TrimSpace(trimmed[3:]), code)
+		case trimmed == "":
+			if !r.blank {
+				r.b.WriteString("\n")
+				r.blank = true
+			}
+		case mdHeadingLine.MatchString(trimmed):
+			m := mdHeadingLine.FindStringSubmatch(trimmed)
+			code := mdHeading
+			if len(m[1]) == 1 {
+				code = mdTitle
+			}
+			if !r.blank {
+				r.b.WriteString("\n")
+			}
+			r.wrap(mdSpan{}, mdSpan{}, parseInline(m[2], []string{code}, ""))
+			r.b.WriteString("\n")
+			r.blank = true
+		case mdRuleLine.MatchString(trimmed):
+			r.line(mdSpan{text: strings.Repeat("─", max(3, r.style.width)), codes: []string{mdMuted}})
+		case strings.HasPrefix(trimmed, "|") && i+1 < len(lines) && isTableDivider(lines[i+1]):
+			rows := [][]string{splitTableRow(line)}
+			aligns := tableAligns(lines[i+1])
+			for i += 2; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				rows = append(rows, splitTableRow(lines[i]))
+			}
+			i--
+			r.table(rows, aligns)
+		case mdChecklistLine.MatchString(line):
+			m := mdChecklistLine.FindStringSubmatch(line)
+			indent := strings.Repeat(" ", len(m[1]))
+			box, codes := "☐ ", []string(nil)
+			if m[2] != " " {
+				box, codes = "☑ ", []string{mdMuted, mdStrike}
+			}
+			r.wrap(mdSpan{text: indent + box}, mdSpan{text: indent + "  "}, parseInline(m[3], codes, ""))
+		case mdBulletLine.MatchString(line) && !mdRuleLine.MatchString(trimmed):
+			m := mdBulletLine.FindStringSubmatch(line)
+			indent := strings.Repeat(" ", len(m[1]))
+			r.wrap(mdSpan{text: indent + "• "}, mdSpan{text: indent + "  "}, parseInline(m[2], nil, ""))
+		case mdOrderedLine.MatchString(line):
+			m := mdOrderedLine.FindStringSubmatch(line)
+			indent := strings.Repeat(" ", len(m[1]))
+			r.wrap(mdSpan{text: indent + m[2] + " "}, mdSpan{text: indent + strings.Repeat(" ", len(m[2])+1)}, parseInline(m[3], nil, ""))
+		case mdQuoteLine.MatchString(line):
+			m := mdQuoteLine.FindStringSubmatch(line)
+			bar := mdSpan{text: "│ ", codes: []string{mdMuted}}
+			r.wrap(bar, bar, parseInline(m[1], []string{mdItalic}, ""))
+		default:
+			r.wrap(mdSpan{}, mdSpan{}, parseInline(trimmed, nil, ""))
+		}
+	}
+	return r.b.String()
+}
+
+func (r *mdRenderer) render(span mdSpan) string {
+	text := span.text
+	if len(span.codes) > 0 {
+		text = r.style.paint(strings.Join(span.codes, ";"), text)
+	}
+	if span.link != "" {
+		text = r.style.link(span.link, text)
+	}
+	return text
+}
+
+func (r *mdRenderer) line(spans ...mdSpan) {
+	for _, span := range spans {
+		r.b.WriteString(r.render(span))
+	}
+	r.b.WriteString("\n")
+	r.blank = false
+}
+
+// wrap writes spans as words, breaking lines at the terminal width. first
+// prefixes the first line and rest the continuation lines.
+func (r *mdRenderer) wrap(first, rest mdSpan, spans []mdSpan) {
+	var words [][]mdSpan
+	var word []mdSpan
+	for _, span := range spans {
+		start := 0
+		for i, c := range span.text {
+			if c != ' ' {
+				continue
+			}
+			if i > start {
+				word = append(word, mdSpan{text: span.text[start:i], codes: span.codes, link: span.link})
+			}
+			if len(word) > 0 {
+				words = append(words, word)
+				word = nil
+			}
+			start = i + 1
+		}
+		if start < len(span.text) {
+			word = append(word, mdSpan{text: span.text[start:], codes: span.codes, link: span.link})
+		}
+	}
+	if len(word) > 0 {
+		words = append(words, word)
+	}
+
+	line := []mdSpan{first}
+	width := utf8.RuneCountInString(first.text)
+	empty := true
+	for _, w := range words {
+		ww := 0
+		for _, frag := range w {
+			ww += utf8.RuneCountInString(frag.text)
+		}
+		if !empty && r.style.width > 0 && width+1+ww > r.style.width {
+			r.line(line...)
+			line = []mdSpan{rest}
+			width = utf8.RuneCountInString(rest.text)
+			empty = true
+		}
+		if !empty {
+			line = append(line, mdSpan{text: " "})
+			width++
+		}
+		line = append(line, w...)
+		width += ww
+		empty = false
+	}
+	r.line(line...)
+}
+
+// parseInline splits a line into styled spans: **bold**, __bold__, *italic*,
+// _italic_, ~~strike~~, ==highlight==, `code`, [links](url), [[wiki links]],
+// images and #tags.
+func parseInline(s string, codes []string, link string) []mdSpan {
+	var spans []mdSpan
+	var buf strings.Builder
+	flush := func() {
+		if buf.Len() > 0 {
+			spans = append(spans, mdSpan{text: buf.String(), codes: codes, link: link})
+			buf.Reset()
+		}
+	}
+	with := func(code string) []string {
+		return append(append([]string(nil), codes...), code)
+	}
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		prevSpace := i == 0 || s[i-1] == ' ' || s[i-1] == '\t' || s[i-1] == '('
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && unicode.IsPunct(rune(rest[1])):
+			buf.WriteByte(rest[1])
+			i += 2
+			continue
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end > 0 {
+				flush()
+				spans = append(spans, mdSpan{text: rest[1 : 1+end], codes: with(mdCode), link: link})
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(rest, "[["):
+			if end := strings.Index(rest, "]]"); end > 2 {
+				flush()
+				title := rest[2:end]
+				spans = append(spans, mdSpan{text: title, codes: with(mdLink), link: "bear://x-callback-url/open-note?title=" + url.QueryEscape(title)})
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(rest, "!["):
+			if text, _, n := parseMarkdownLink(rest[1:]); n > 0 {
+				flush()
+				if text == "" {
+					text = "image"
+				}
+				spans = append(spans, mdSpan{text: "[" + text + "]", codes: with(mdMuted), link: link})
+				i += n + 1
+				continue
+			}
+		case rest[0] == '[':
+			if text, target, n := parseMarkdownLink(rest); n > 0 {
+				flush()
+				spans = append(spans, parseInline(text, with(mdLink), target)...)
+				i += n
+				continue
+			}
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__") || strings.HasPrefix(rest, "~~") || strings.HasPrefix(rest, "=="):
+			delim := rest[:2]
+			if end := strings.Index(rest[2:], delim); end > 0 && rest[2] != ' ' && (delim != "__" || prevSpace) {
+				flush()
+				code := map[string]string{"**": mdBold, "__": mdBold, "~~": mdStrike, "==": mdHighlight}[delim]
+				spans = append(spans, parseInline(rest[2:2+end], with(code), link)...)
+				i += end + 4
+				continue
+			}
+		case rest[0] == '*' || rest[0] == '_':
+			if end := strings.IndexByte(rest[1:], rest[0]); end > 0 && rest[1] != ' ' && rest[end] != ' ' && (rest[0] == '*' || prevSpace) {
+				flush()
+				spans = append(spans, parseInline(rest[1:1+end], with(mdItalic), link)...)
+				i += end + 2
+				continue
+			}
+		case rest[0] == '#' && prevSpace:
+			if n := tagLength(rest); n > 0 {
+				flush()
+				spans = append(spans, mdSpan{text: rest[:n], codes: with(mdTag), link: link})
+				i += n
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(rest)
+		buf.WriteString(rest[:size])
+		i += size
+	}
+	flush()
+	return spans
+}
+
+// parseMarkdownLink parses "[text](target)" at the start of s and returns
+// the number of bytes consumed, or 0.
+func parseMarkdownLink(s string) (text, target string, n int) {
+	closeText := strings.Index(s, "](")
+	if !strings.HasPrefix(s, "[") || closeText < 0 {
+		return "", "", 0
+	}
+	closeTarget := strings.IndexByte(s[closeText+2:], ')')
+	if closeTarget < 0 {
+		return "", "", 0
+	}
+	return s[1:closeText], s[closeText+2 : closeText+2+closeTarget], closeText + 3 + closeTarget
+}
+
+// tagLength returns the length of the Bear tag at the start of s: #tag,
+// #nested/tag or #multi word tag#.
+func tagLength(s string) int {
+	if len(s) < 2 || s[1] == ' ' || s[1] == '#' {
+		return 0
+	}
+	single := strings.IndexAny(s, " \t")
+	if single < 0 {
+		single = len(s)
+	}
+	if close := strings.IndexByte(s[1:], '#'); close > 0 {
+		end := close + 2
+		if s[end-2] != ' ' && (end == len(s) || s[end] == ' ') && end > single {
+			return end
+		}
+	}
+	word := strings.TrimRight(s[:single], ".,;:!?)")
+	if len(word) < 2 {
+		return 0
+	}
+	return len(word)
+}
+
+func isTableDivider(line string) bool {
+	if !strings.Contains(line, "-") {
+		return false
+	}
+	for _, cell := range splitTableRow(line) {
+		if !mdTableDivider.MatchString(cell) {
+			return false
+		}
+	}
+	return true
+}
+
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+	cells := strings.Split(line, "|")
+	for i, cell := range cells {
+		cells[i] = strings.TrimSpace(cell)
+	}
+	return cells
+}
+
+// tableAligns reads column alignment from a divider row: 'l', 'r' or 'c'.
+func tableAligns(divider string) []byte {
+	cells := splitTableRow(divider)
+	aligns := make([]byte, len(cells))
+	for i, cell := range cells {
+		left, right := strings.HasPrefix(cell, ":"), strings.HasSuffix(cell, ":")
+		switch {
+		case left && right:
+			aligns[i] = 'c'
+		case right:
+			aligns[i] = 'r'
+		default:
+			aligns[i] = 'l'
+		}
+	}
+	return aligns
+}
+
+func (r *mdRenderer) table(rows [][]string, aligns []byte) {
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+	cells := make([][][]mdSpan, len(rows))
+	widths := make([]int, cols)
+	for i, row := range rows {
+		cells[i] = make([][]mdSpan, cols)
+		for j := 0; j < cols; j++ {
+			var codes []string
+			if i == 0 {
+				codes = []string{mdBold}
+			}
+			if j < len(row) {
+				cells[i][j] = parseInline(row[j], codes, "")
+			}
+			widths[j] = max(widths[j], spansWidth(cells[i][j]))
+		}
+	}
+	bar := mdSpan{text: " │ ", codes: []string{mdMuted}}
+	for i, row := range cells {
+		var line []mdSpan
+		for j, cell := range row {
+			if j > 0 {
+				line = append(line, bar)
+			}
+			pad := widths[j] - spansWidth(cell)
+			align := byte('l')
+			if j < len(aligns) {
+				align = aligns[j]
+			}
+			left := 0
+			switch align {
+			case 'r':
+				left = pad
+			case 'c':
+				left = pad / 2
+			}
+			line = append(line, mdSpan{text: strings.Repeat(" ", left)})
+			line = append(line, cell...)
+			if j < len(row)-1 {
+				line = append(line, mdSpan{text: strings.Repeat(" ", pad-left)})
+			}
+		}
+		r.line(line...)
+		if i == 0 {
+			parts := make([]string, cols)
+			for j, width := range widths {
+				parts[j] = strings.Repeat("─", width)
+			}
+			r.line(mdSpan{text: strings.Join(parts, "─┼─"), codes: []string{mdMuted}})
+		}
+	}
+}
+
+func spansWidth(spans []mdSpan) int {
+	width := 0
+	for _, span := range spans {
+		width += utf8.RuneCountInString(span.text)
+	}
+	return width
+}
+
+var mdKeywords = map[string]bool{}
+
+func init() {
+	for _, kw := range strings.Fields(`
+		func package import return if else for range switch case default break continue
+		var const type struct interface map chan go defer select
+		function let class new this extends export from async await try catch finally throw typeof
+		def lambda elif while in is not and or pass yield with as raise except None True False self
+		fn mut impl pub use mod match enum trait where
+		do done then fi esac echo local
+		true false null nil undefined`) {
+		mdKeywords[kw] = true
+	}
+}
+
+// codeBlock writes a fenced block indented, with basic highlighting of
+// keywords, strings, numbers and comments. Code is never wrapped.
+func (r *mdRenderer) codeBlock(lang string, lines []string) {
+	hashComments := map[string]bool{"": true, "sh": true, "bash": true, "zsh": true, "shell": true, "python": true, "py": true, "ruby": true, "rb": true, "yaml": true, "yml": true, "toml": true, "perl": true, "r": true}[strings.ToLower(lang)]
+	for _, code := range lines {
+		line := []mdSpan{{text: "  "}}
+		line = append(line, highlightCode(strings.ReplaceAll(code, "\t", "    "), hashComments)...)
+		r.line(line...)
+	}
+	r.blank = false
+}
+
+func highlightCode(code string, hashComments bool) []mdSpan {
+	var spans []mdSpan
+	plain := func(text string) {
+		if text != "" {
+			spans = append(spans, mdSpan{text: text})
+		}
+	}
+	start := 0
+	for i := 0; i < len(code); {
+		c := code[i]
+		switch {
+		case strings.HasPrefix(code[i:], "//") || (c == '#' && hashComments && (i == 0 || code[i-1] == ' ' || code[i-1] == '\t')):
+			plain(code[start:i])
+			spans = append(spans, mdSpan{text: code[i:], codes: []string{mdMuted}})
+			return spans
+		case c == '"' || c == '\'' || c == '`':
+			end := i + 1
+			for end < len(code) && code[end] != c {
+				if code[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(code))
+			plain(code[start:i])
+			spans = append(spans, mdSpan{text: code[i:end], codes: []string{mdString}})
+			i, start = end, end
+			continue
+		case isCodeWordByte(c) && (i == 0 || !isCodeWordByte(code[i-1])):
+			end := i
+			for end < len(code) && isCodeWordByte(code[end]) {
+				end++
+			}
+			word := code[i:end]
+			var codes []string
+			switch {
+			case mdKeywords[word]:
+				codes = []string{mdKeyword}
+			case c >= '0' && c <= '9':
+				codes = []string{mdNumber}
+			}
+			if codes != nil {
+				plain(code[start:i])
+				spans = append(spans, mdSpan{text: word, codes: codes})
+				start = end
+			}
+			i = end
+			continue
+		}
+		i++
+	}
+	plain(code[start:])
+	return spans
+}
+
+func isCodeWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,67 @@
+package grizzly
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdownLayout(t *testing.T) {
+	text := "# Plan\n\nSome **bold** and ==marked== text with #tag, [[Other note]] and [a link](https://example.com).\n\n" +
+		"## Tasks\n- [ ] an open task that is long enough to wrap\n- [x] done\n  - nested\n1. first\n> quoted\n" +
+		"| name | n |\n|------|--:|\n| a | 1 |\n| bee | 20 |\n" +
+		"```go\n\treturn 1\n```\n"
+	want := "Plan\n\n" +
+		"Some bold and marked text with #tag,\n" +
+		"Other note and a link.\n\n" +
+		"Tasks\n\n" +
+		"☐ an open task that is long enough to\n" +
+		"  wrap\n" +
+		"☑ done\n" +
+		"  • nested\n" +
+		"1. first\n" +
+		"│ quoted\n" +
+		"name │  n\n" +
+		"─────┼───\n" +
+		"a    │  1\n" +
+		"bee  │ 20\n" +
+		"      return 1\n"
+	if got := renderMarkdown(text, termStyle{width: 40}); got != want {
+		t.Fatalf("render:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestRenderMarkdownStyles(t *testing.T) {
+	got := renderMarkdown("**b** ==h== ~~s~~ `c` #work [[Note A]]\n```go\nfunc f() // x\n```", termStyle{color: true, width: 80})
+	for _, want := range []string{
+		"\x1b[1mb\x1b[0m",
+		"\x1b[30;43mh\x1b[0m",
+		"\x1b[9ms\x1b[0m",
+		"\x1b[33mc\x1b[0m",
+		"\x1b[36m#work\x1b[0m",
+		"\x1b]8;;bear://x-callback-url/open-note?title=Note+A\x1b\\\x1b[4;34mNote\x1b[0m\x1b]8;;\x1b\\",
+		"\x1b[35mfunc\x1b[0m",
+		"\x1b[2m// x\x1b[0m",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("styled output missing %q:\n%q", want, got)
+		}
+	}
+}
+
+func TestTagLength(t *testing.T) {
+	cases := map[string]int{
+		"#work":             5,
+		"#work, more":       5,
+		"#a/b/c end":        6,
+		"#multi word# rest": 12,
+		"#a then text #b":   2,
+		"# heading":         0,
+		"##":                0,
+		"#":                 0,
+	}
+	for in, want := range cases {
+		if got := tagLength(in); got != want {
+			t.Fatalf("tagLength(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package grizzly
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"grizzly/pkg/bear"
+)
+
+// noteFrontMatter is the YAML header of an exported note file.
+type noteFrontMatter struct {
+	Identifier string    `yaml:"identifier,omitempty"`
+	Title      string    `yaml:"title,omitempty"`
+	Tags       []string  `yaml:"tags,omitempty"`
+	Created    time.Time `yaml:"created,omitempty"`
+	Modified   time.Time `yaml:"modified,omitempty"`
+	Pinned     bool      `yaml:"pinned,omitempty"`
+}
+
+func frontMatterFor(note *bear.Note) noteFrontMatter {
+	return noteFrontMatter{
+		Identifier: note.Identifier,
+		Title:      note.Title,
+		Tags:       note.Tags,
+		Created:    note.CreationDate,
+		Modified:   note.ModificationDate,
+		Pinned:     note.Pinned,
+	}
+}
+
+// renderNoteFile writes front matter followed by the note's Markdown.
+func renderNoteFile(meta noteFrontMatter, body string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(meta); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteString("---\n")
+	buf.WriteString(body)
+	if body != "" && !strings.HasSuffix(body, "\n") {
+		buf.WriteString("\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// parseNoteFile splits a Markdown file into front matter and body. Files
+// without front matter yield an empty header.
+func parseNoteFile(data []byte) (noteFrontMatter, string, error) {
+	var meta noteFrontMatter
+	header, body, err := splitFrontMatter(data)
+	if err != nil {
+		return meta, "", err
+	}
+	if err := yaml.Unmarshal([]byte(header), &meta); err != nil {
+		return meta, "", fmt.Errorf("front matter: %w", err)
+	}
+	return meta, body, nil
+}
+
+// splitFrontMatter returns the YAML between leading "---" lines and the text
+// after it.
+func splitFrontMatter(data []byte) (string, string, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if !strings.HasPrefix(text, "---\n") {
+		return "", text, nil
+	}
+	rest := text[len("---\n"):]
+	if strings.HasPrefix(rest, "---\n") {
+		return "", rest[len("---\n"):], nil
+	}
+	if end := strings.Index(rest, "\n---\n"); end >= 0 {
+		return rest[:end+1], rest[end+len("\n---\n"):], nil
+	}
+	if strings.HasSuffix(rest, "\n---") {
+		return rest[:len(rest)-len("---")], "", nil
+	}
+	return "", "", fmt.Errorf("unterminated front matter")
+}
+
+// tagsNotInText returns tags, without duplicates, that text does not already
+// contain as #tag (or #multi word tag#). Bear appends the tags parameter to
+// the text even when the tags are there.
+func tagsNotInText(text string, tags []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		end := `(?:#|$|[\s.,;:!?])`
+		if strings.ContainsAny(tag, " \t") {
+			end = `#`
+		}
+		if !regexp.MustCompile(`(?:^|\s)#` + regexp.QuoteMeta(tag) + end).MatchString(text) {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
@@ -1,15 +1,181 @@
 package grizzly
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
 	"os/exec"
 	"runtime"
+	"strings"
+	"time"
+
+	"grizzly/pkg/bear"
+)
+
+const (
+	OpenerAuto    = "auto"
+	OpenerOpen    = "open"
+	OpenerXDG     = "xdg-open"
+	OpenerCommand = "command"
+	OpenerFile    = "file"
+	OpenerHTTP    = "http"
+	OpenerFake    = "fake"
 )
 
-func openURL(url string) error {
-	if runtime.GOOS != "darwin" {
-		return fmt.Errorf("grizzly requires macOS (darwin) to open Bear URLs")
+type Opener = bear.Opener
+
+func newOpener(opts *Options) (Opener, error) {
+	if err := checkOpener(opts); err != nil {
+		return nil, err
+	}
+	switch kind := openerKind(opts); kind {
+	case OpenerCommand:
+		argv, _ := splitCommandLine(opts.OpenerCommand)
+		return &execOpener{argv: argv}, nil
+	case OpenerFile:
+		return &fileOpener{path: opts.OpenerFile}, nil
+	case OpenerHTTP:
+		endpoint, _ := url.Parse(opts.OpenerURL)
+		return &httpOpener{endpoint: endpoint.String(), client: &http.Client{Timeout: 10 * time.Second}}, nil
+	case OpenerFake:
+		return NewFakeBear(opts.FakeBearStore)
+	default:
+		return &execOpener{argv: []string{kind, "{url}"}}, nil
+	}
+}
+
+// checkOpener reports an unknown opener or missing opener settings without
+// building the opener, so the fake store is not read on every command.
+func checkOpener(opts *Options) error {
+	switch kind := openerKind(opts); kind {
+	case OpenerOpen, OpenerXDG, OpenerFake:
+		return nil
+	case OpenerCommand:
+		argv, err := splitCommandLine(opts.OpenerCommand)
+		if err != nil {
+			return fmt.Errorf("opener_command: %v", err)
+		}
+		if len(argv) == 0 {
+			return fmt.Errorf("opener %q requires opener_command", kind)
+		}
+	case OpenerFile:
+		if opts.OpenerFile == "" {
+			return fmt.Errorf("opener %q requires opener_file", kind)
+		}
+	case OpenerHTTP:
+		if opts.OpenerURL == "" {
+			return fmt.Errorf("opener %q requires opener_url", kind)
+		}
+		endpoint, err := url.Parse(opts.OpenerURL)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+			return fmt.Errorf("invalid opener_url: %s", opts.OpenerURL)
+		}
+	default:
+		return fmt.Errorf("unknown opener: %s", kind)
+	}
+	return nil
+}
+
+func openerKind(opts *Options) string {
+	kind := strings.TrimSpace(opts.Opener)
+	if kind == "" || kind == OpenerAuto {
+		return defaultOpener()
+	}
+	return kind
+}
+
+func defaultOpener() string {
+	if runtime.GOOS == "darwin" {
+		return OpenerOpen
+	}
+	return OpenerXDG
+}
+
+// execOpener runs argv without a shell, substituting {url} or appending the URL.
+type execOpener struct {
+	argv []string
+}
+
+func (e *execOpener) Open(ctx context.Context, rawURL string) error {
+	args := expandOpenerArgs(e.argv, rawURL)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %w: %s", args[0], err, msg)
+		}
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	return nil
+}
+
+func expandOpenerArgs(argv []string, rawURL string) []string {
+	args := make([]string, 0, len(argv)+1)
+	replaced := false
+	for _, arg := range argv {
+		if strings.Contains(arg, "{url}") {
+			arg = strings.ReplaceAll(arg, "{url}", rawURL)
+			replaced = true
+		}
+		args = append(args, arg)
+	}
+	if !replaced {
+		args = append(args, rawURL)
+	}
+	return args
+}
+
+type fileOpener struct {
+	path string
+}
+
+func (f *fileOpener) Open(ctx context.Context, rawURL string) error {
+	if f.path == "-" {
+		_, err := fmt.Fprintln(os.Stderr, rawURL)
+		return err
+	}
+	path, err := expandPath(f.path)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(file, rawURL); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// httpOpener POSTs the URL as form field "url" to a remote helper.
+type httpOpener struct {
+	endpoint string
+	client   *http.Client
+}
+
+func (h *httpOpener) Open(ctx context.Context, rawURL string) error {
+	form := url.Values{}
+	form.Set("url", rawURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if msg := strings.TrimSpace(string(body)); msg != "" {
+			return fmt.Errorf("opener endpoint returned %s: %s", resp.Status, msg)
+		}
+		return fmt.Errorf("opener endpoint returned %s", resp.Status)
 	}
-	cmd := exec.Command("open", url)
-	return cmd.Run()
+	return nil
 }
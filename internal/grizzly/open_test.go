@@ -0,0 +1,107 @@
+package grizzly
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandOpenerArgs(t *testing.T) {
+	got := expandOpenerArgs([]string{"ssh", "mac", "open", "{url}"}, "bear://x-callback-url/tags")
+	want := []string{"ssh", "mac", "open", "bear://x-callback-url/tags"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("args = %q, want %q", got, want)
+	}
+
+	got = expandOpenerArgs([]string{"echo"}, "bear://x")
+	want = []string{"echo", "bear://x"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("args = %q, want %q", got, want)
+	}
+}
+
+func TestNewOpenerValidation(t *testing.T) {
+	cases := []struct {
+		opts    Options
+		wantErr bool
+	}{
+		{Options{}, false},
+		{Options{Opener: "xdg-open"}, false},
+		{Options{Opener: "command"}, true},
+		{Options{Opener: "command", OpenerCommand: "echo {url}"}, false},
+		{Options{Opener: "command", OpenerCommand: `echo "{url}`}, true},
+		{Options{Opener: "file"}, true},
+		{Options{Opener: "http", OpenerURL: "ftp://host"}, true},
+		{Options{Opener: "bogus"}, true},
+	}
+	for _, tc := range cases {
+		_, err := newOpener(&tc.opts)
+		if tc.wantErr && err == nil {
+			t.Fatalf("newOpener(%+v) expected error", tc.opts)
+		}
+		if !tc.wantErr && err != nil {
+			t.Fatalf("newOpener(%+v) error: %v", tc.opts, err)
+		}
+	}
+}
+
+func TestCommandOpenerQuoting(t *testing.T) {
+	opener, err := newOpener(&Options{Opener: OpenerCommand, OpenerCommand: `"/Applications/My Tools/open" --url '{url}'`})
+	if err != nil {
+		t.Fatalf("newOpener: %v", err)
+	}
+	want := []string{"/Applications/My Tools/open", "--url", "{url}"}
+	if got := opener.(*execOpener).argv; !reflect.DeepEqual(got, want) {
+		t.Fatalf("argv = %q, want %q", got, want)
+	}
+}
+
+func TestFileOpenerAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.log")
+	opener, err := newOpener(&Options{Opener: OpenerFile, OpenerFile: path})
+	if err != nil {
+		t.Fatalf("newOpener: %v", err)
+	}
+	for _, u := range []string{"bear://x-callback-url/one", "bear://x-callback-url/two"} {
+		if err := opener.Open(context.Background(), u); err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(data) != "bear://x-callback-url/one\nbear://x-callback-url/two\n" {
+		t.Fatalf("recorded = %q", string(data))
+	}
+}
+
+func TestHTTPOpenerPostsURL(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.FormValue("url")
+		if strings.Contains(got, "fail") {
+			http.Error(w, "nope", http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	opener, err := newOpener(&Options{Opener: OpenerHTTP, OpenerURL: srv.URL})
+	if err != nil {
+		t.Fatalf("newOpener: %v", err)
+	}
+	if err := opener.Open(context.Background(), "bear://x-callback-url/tags?token=a%20b"); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if got != "bear://x-callback-url/tags?token=a%20b" {
+		t.Fatalf("forwarded = %q", got)
+	}
+	if err := opener.Open(context.Background(), "bear://fail"); err == nil {
+		t.Fatalf("expected error for non-2xx response")
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+
+	"grizzly/pkg/bear"
 )
 
 type OutputMode int
@@ -16,6 +18,7 @@ const (
 	ModeHuman OutputMode = iota
 	ModePlain
 	ModeJSON
+	ModeFormat
 )
 
 type Outputter struct {
@@ -33,6 +36,9 @@ func NewOutputter(opts *Options) *Outputter {
 }
 
 func (o *Outputter) mode() OutputMode {
+	if o.opts.Format != "" {
+		return ModeFormat
+	}
 	if o.opts.JSON {
 		return ModeJSON
 	}
@@ -43,49 +49,74 @@ func (o *Outputter) mode() OutputMode {
 }
 
 func (o *Outputter) WriteSuccess(res Result) {
+	res.URL = o.shownURL(res.URL)
+	if o.opts.Query != "" {
+		o.writeQuery(res)
+		return
+	}
 	switch o.mode() {
 	case ModeJSON:
 		o.writeJSON(res, nil)
 	case ModePlain:
 		o.writePlain(res, true, nil)
+	case ModeFormat:
+		o.writeFormatted(res)
 	default:
 		o.writeHuman(res)
 	}
 }
 
 func (o *Outputter) WriteError(res Result, info ErrorInfo, exitCode int) error {
+	res.URL = o.shownURL(res.URL)
+	// Opener failures can quote the URL they were given.
+	info.Message = bear.RedactURL(info.Message)
 	switch o.mode() {
 	case ModeJSON:
 		o.writeJSON(res, &info)
 	case ModePlain:
 		o.writePlain(res, false, &info)
 	default:
+		prefix := newTermStyle(o.opts, o.stderr).paint(ansiRed, "error:")
 		if info.Code != "" {
-			fmt.Fprintf(o.stderr, "error: %s (%s)\n", info.Message, info.Code)
+			fmt.Fprintf(o.stderr, "%s %s (%s)\n", prefix, info.Message, info.Code)
 		} else {
-			fmt.Fprintf(o.stderr, "error: %s\n", info.Message)
+			fmt.Fprintf(o.stderr, "%s %s\n", prefix, info.Message)
 		}
 	}
 	return &ExitError{Code: exitCode, Err: fmt.Errorf("%s", info.Message)}
 }
 
-func (o *Outputter) writeJSON(res Result, errInfo *ErrorInfo) {
-	payload := struct {
-		OK     bool           `json:"ok"`
-		Action string         `json:"action,omitempty"`
-		URL    string         `json:"url,omitempty"`
-		Data   map[string]any `json:"data,omitempty"`
-		Error  *ErrorInfo     `json:"error,omitempty"`
-	}{
+// shownURL returns the Bear URL to print: only under --print-url or
+// --dry-run, as it can carry note text and attachments, and never with the
+// token.
+func (o *Outputter) shownURL(url string) string {
+	if !o.opts.PrintURL && !o.opts.DryRun {
+		return ""
+	}
+	return bear.RedactURL(url)
+}
+
+type envelope struct {
+	OK     bool       `json:"ok"`
+	Action string     `json:"action,omitempty"`
+	URL    string     `json:"url,omitempty"`
+	Data   any        `json:"data,omitempty"`
+	Error  *ErrorInfo `json:"error,omitempty"`
+}
+
+func newEnvelope(res Result, errInfo *ErrorInfo) envelope {
+	return envelope{
 		OK:     errInfo == nil,
 		Action: res.Action,
-		URL:    "", /*res.URL,*/
+		URL:    res.URL,
 		Data:   res.Data,
 		Error:  errInfo,
 	}
+}
 
+func (o *Outputter) writeJSON(res Result, errInfo *ErrorInfo) {
 	enc := json.NewEncoder(o.stdout)
-	_ = enc.Encode(payload)
+	_ = enc.Encode(newEnvelope(res, errInfo))
 }
 
 func (o *Outputter) writePlain(res Result, ok bool, errInfo *ErrorInfo) {
@@ -97,7 +128,7 @@ func (o *Outputter) writePlain(res Result, ok bool, errInfo *ErrorInfo) {
 	if res.Action != "" {
 		writeLine("action", res.Action)
 	}
-	if (o.opts.PrintURL || o.opts.DryRun || o.opts.JSON) && res.URL != "" {
+	if res.URL != "" {
 		writeLine("url", res.URL)
 	}
 	if errInfo != nil {
@@ -108,17 +139,18 @@ func (o *Outputter) writePlain(res Result, ok bool, errInfo *ErrorInfo) {
 		return
 	}
 
-	if len(res.Data) == 0 {
+	data := dataFields(res.Data)
+	if len(data) == 0 {
 		return
 	}
 
-	keys := make([]string, 0, len(res.Data))
-	for k := range res.Data {
+	keys := make([]string, 0, len(data))
+	for k := range data {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
 	for _, key := range keys {
-		val := res.Data[key]
+		val := data[key]
 		switch typed := val.(type) {
 		case string:
 			writeLine(key, escapePlain(typed))
@@ -142,6 +174,56 @@ func (o *Outputter) writePlain(res Result, ok bool, errInfo *ErrorInfo) {
 	}
 }
 
+func (o *Outputter) writeFormatted(res Result) {
+	f, err := lookupFormat(o.opts.Format)
+	if err == nil {
+		err = f.Format(o.stdout, formatRecords(res).selectFields(o.opts.Fields))
+	}
+	if err != nil {
+		o.failOutput(err)
+	}
+}
+
+// writeQuery evaluates --query against the JSON envelope and writes each
+// output value in the selected mode.
+func (o *Outputter) writeQuery(res Result) {
+	outs, err := evalQuery(o.opts.Query, newEnvelope(res, nil))
+	if err != nil {
+		o.failOutput(fmt.Errorf("query: %w", err))
+		return
+	}
+	switch o.mode() {
+	case ModeFormat:
+		f, err := lookupFormat(o.opts.Format)
+		if err == nil {
+			err = f.Format(o.stdout, queryRecords(outs).selectFields(o.opts.Fields))
+		}
+		if err != nil {
+			o.failOutput(err)
+		}
+	case ModeJSON:
+		for _, out := range outs {
+			fmt.Fprintln(o.stdout, formatJSONLine(out))
+		}
+	default:
+		// Like jq -r: strings are written raw, other values as JSON.
+		for _, out := range outs {
+			line, ok := out.(string)
+			if !ok {
+				line = formatJSONLine(out)
+			} else if o.mode() == ModePlain {
+				line = escapePlain(line)
+			}
+			fmt.Fprintln(o.stdout, line)
+		}
+	}
+}
+
+func (o *Outputter) failOutput(err error) {
+	fmt.Fprintf(o.stderr, "error: %v\n", err)
+	o.opts.outputErr = err
+}
+
 func (o *Outputter) writeHuman(res Result) {
 	if o.opts.Quiet && !o.opts.PrintURL && !o.opts.DryRun {
 		return
@@ -152,46 +234,104 @@ func (o *Outputter) writeHuman(res Result) {
 			return
 		}
 	}
-	if len(res.Data) == 0 {
+	switch data := res.Data.(type) {
+	case report:
+		data.writeHuman(o.stdout, newTermStyle(o.opts, o.stdout))
+	case nil:
 		if !o.opts.Quiet {
 			fmt.Fprintln(o.stdout, "OK")
 		}
-		return
-	}
-	if note, ok := res.Data["note"].(string); ok {
-		fmt.Fprint(o.stdout, note)
-		if !strings.HasSuffix(note, "\n") {
-			fmt.Fprintln(o.stdout)
+	case *bear.Note:
+		o.writeNote(data)
+	case *bear.AddTextResult:
+		o.writeNoteText(data.Text)
+	case *bear.TagsResult:
+		newTermStyle(o.opts, o.stdout).writeTags(o.stdout, data.Tags)
+	case *bear.NotesResult:
+		// Pipes get one tab-separated line per note, like before; terminals
+		// get an aligned table.
+		if style := newTermStyle(o.opts, o.stdout); style.width > 0 {
+			style.writeNotesTable(o.stdout, data.Notes)
+			return
 		}
-		return
-	}
-	if tags := extractTagNames(res.Data["tags"]); len(tags) > 0 {
-		for _, tag := range tags {
-			fmt.Fprintln(o.stdout, tag)
+		for _, note := range data.Notes {
+			fmt.Fprintln(o.stdout, formatNoteLine(note))
 		}
-		return
+	case *bear.CreateResult:
+		o.writeTitleID(data.Title, data.Identifier)
+	case map[string]any:
+		o.writeHumanMap(data)
+	default:
+		fmt.Fprintln(o.stdout, formatJSONLine(data))
+	}
+}
+
+func (o *Outputter) writeNoteText(note string) {
+	fmt.Fprint(o.stdout, note)
+	if !strings.HasSuffix(note, "\n") {
+		fmt.Fprintln(o.stdout)
 	}
-	if notes := extractNotes(res.Data["notes"]); len(notes) > 0 {
-		for _, note := range notes {
-			fmt.Fprintln(o.stdout, note)
+}
+
+func (o *Outputter) writeTitleID(title, id string) {
+	style := newTermStyle(o.opts, o.stdout)
+	switch {
+	case title != "" && id != "":
+		fmt.Fprintf(o.stdout, "%s (%s)\n", style.link(openNoteURL(id), style.paint(ansiBold, title)), style.paint(ansiDim, id))
+	case title != "":
+		fmt.Fprintln(o.stdout, title)
+	case id != "":
+		fmt.Fprintln(o.stdout, id)
+	default:
+		fmt.Fprintln(o.stdout, "OK")
+	}
+}
+
+func (o *Outputter) writeHumanMap(data map[string]any) {
+	if len(data) == 0 {
+		if !o.opts.Quiet {
+			fmt.Fprintln(o.stdout, "OK")
 		}
 		return
 	}
-	if title, ok := res.Data["title"].(string); ok && title != "" {
-		if id, ok := res.Data["identifier"].(string); ok && id != "" {
-			fmt.Fprintf(o.stdout, "%s (%s)\n", title, id)
-			return
-		}
-		fmt.Fprintln(o.stdout, title)
+	if note, ok := data["note"].(string); ok {
+		o.writeNoteText(note)
 		return
 	}
-	if id, ok := res.Data["identifier"].(string); ok && id != "" {
-		fmt.Fprintln(o.stdout, id)
+	title, _ := data["title"].(string)
+	id, _ := data["identifier"].(string)
+	if title != "" || id != "" {
+		o.writeTitleID(title, id)
 		return
 	}
-	for key, value := range res.Data {
-		fmt.Fprintf(o.stdout, "%s: %v\n", key, value)
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(o.stdout, "%s: %v\n", key, data[key])
+	}
+}
+
+// dataFields flattens a typed payload into the generic map used by the plain
+// writer, keyed by the payload's JSON field names.
+func dataFields(data any) map[string]any {
+	switch typed := data.(type) {
+	case nil:
+		return nil
+	case map[string]any:
+		return typed
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return map[string]any{"data": fmt.Sprintf("%v", data)}
+	}
+	fields := map[string]any{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return map[string]any{"data": string(raw)}
 	}
+	return fields
 }
 
 func formatJSONLine(v any) string {
@@ -210,68 +350,9 @@ func escapePlain(value string) string {
 	return value
 }
 
-func extractTagNames(value any) []string {
-	var tags []string
-	switch typed := value.(type) {
-	case []string:
-		return typed
-	case []any:
-		for _, item := range typed {
-			switch tag := item.(type) {
-			case map[string]any:
-				if name, ok := tag["name"].(string); ok {
-					tags = append(tags, name)
-				}
-			case string:
-				tags = append(tags, tag)
-			}
-		}
-	case []map[string]any:
-		for _, item := range typed {
-			if name, ok := item["name"].(string); ok {
-				tags = append(tags, name)
-			}
-		}
-	case string:
-		if typed != "" {
-			tags = append(tags, typed)
-		}
-	}
-	return tags
-}
-
-func extractNotes(value any) []string {
-	var notes []string
-	switch typed := value.(type) {
-	case []any:
-		for _, item := range typed {
-			if note, ok := item.(map[string]any); ok {
-				notes = append(notes, formatNoteLine(note))
-			}
-		}
-	case []map[string]any:
-		for _, note := range typed {
-			notes = append(notes, formatNoteLine(note))
-		}
-	case string:
-		if typed != "" {
-			notes = append(notes, typed)
-		}
-	}
-	return notes
-}
-
-func formatNoteLine(note map[string]any) string {
-	title, _ := note["title"].(string)
-	identifier, _ := note["identifier"].(string)
-	if title != "" && identifier != "" {
-		return fmt.Sprintf("%s\t%s", title, identifier)
-	}
-	if title != "" {
-		return title
-	}
-	if identifier != "" {
-		return identifier
+func formatNoteLine(note bear.NoteSummary) string {
+	if note.Title != "" {
+		return fmt.Sprintf("%s\t%s", note.Title, note.Identifier)
 	}
-	return formatJSONLine(note)
+	return note.Identifier
 }
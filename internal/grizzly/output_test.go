@@ -5,12 +5,14 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+
+	"grizzly/pkg/bear"
 )
 
 func TestOutputJSONSuccess(t *testing.T) {
 	bufOut := &bytes.Buffer{}
 	bufErr := &bytes.Buffer{}
-	opts := &Options{JSON: true}
+	opts := &Options{JSON: true, PrintURL: true}
 	out := &Outputter{opts: opts, stdout: bufOut, stderr: bufErr}
 
 	res := Result{Action: "open-note", URL: "bear://x-callback-url/open-note?id=123", Data: map[string]any{"identifier": "123"}}
@@ -114,3 +116,59 @@ func TestOutputJSONError(t *testing.T) {
 		t.Fatalf("error = %#v", payload.Error)
 	}
 }
+
+func TestOutputTypedNote(t *testing.T) {
+	note := &bear.Note{Identifier: "ABC", Title: "Plan", Text: "# Plan\nbody", Tags: []string{"work", "home"}}
+
+	bufOut := &bytes.Buffer{}
+	out := &Outputter{opts: &Options{Plain: true}, stdout: bufOut, stderr: &bytes.Buffer{}}
+	out.WriteSuccess(Result{Action: "open-note", Data: note})
+	got := bufOut.String()
+	for _, want := range []string{"identifier=ABC\n", "note=# Plan\\nbody\n", "tags=\"work\"\n", "tags=\"home\"\n", "pinned=false\n"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("plain output missing %q:\n%s", want, got)
+		}
+	}
+
+	bufOut.Reset()
+	out = &Outputter{opts: &Options{}, stdout: bufOut, stderr: &bytes.Buffer{}}
+	out.WriteSuccess(Result{Action: "search", Data: &bear.NotesResult{Notes: []bear.NoteSummary{{Identifier: "1", Title: "A"}, {Identifier: "2"}}}})
+	if bufOut.String() != "A\t1\n2\n" {
+		t.Fatalf("human output = %q", bufOut.String())
+	}
+}
+
+func TestOutputRedactsToken(t *testing.T) {
+	res := Result{Action: "tags", URL: "bear://x-callback-url/tags?token=secrettoken&x-success=a"}
+	for _, opts := range []*Options{{JSON: true, PrintURL: true}, {JSON: true, Query: ".url", DryRun: true}, {Plain: true, PrintURL: true}, {DryRun: true}, {Format: "csv", PrintURL: true}} {
+		bufOut := &bytes.Buffer{}
+		out := &Outputter{opts: opts, stdout: bufOut, stderr: &bytes.Buffer{}}
+		out.WriteSuccess(res)
+		_ = out.WriteError(res, ErrorInfo{Message: "failed"}, ExitFailure)
+		if strings.Contains(bufOut.String(), "secrettoken") || !strings.Contains(bufOut.String(), "token=REDACTED") {
+			t.Fatalf("%+v output = %q", opts, bufOut.String())
+		}
+	}
+}
+
+func TestOutputPlainListItemsAreJSON(t *testing.T) {
+	bufOut := &bytes.Buffer{}
+	out := &Outputter{opts: &Options{Plain: true}, stdout: bufOut, stderr: &bytes.Buffer{}}
+	out.WriteSuccess(Result{Action: "tags", Data: map[string]any{"tags": []any{"a", map[string]any{"name": "b"}}}})
+	if got := bufOut.String(); got != "ok=true\naction=tags\ntags=\"a\"\ntags={\"name\":\"b\"}\n" {
+		t.Fatalf("plain = %q", got)
+	}
+}
+
+func TestOutputURLOnlyWhenAsked(t *testing.T) {
+	res := Result{Action: "create", URL: "bear://x-callback-url/create?text=private"}
+	for _, opts := range []*Options{{JSON: true}, {JSON: true, Query: "."}, {Plain: true}, {Format: "csv"}, {}} {
+		bufOut := &bytes.Buffer{}
+		out := &Outputter{opts: opts, stdout: bufOut, stderr: &bytes.Buffer{}}
+		out.WriteSuccess(res)
+		_ = out.WriteError(res, ErrorInfo{Message: "failed"}, ExitFailure)
+		if strings.Contains(bufOut.String(), "bear://") {
+			t.Fatalf("%+v output = %q", opts, bufOut.String())
+		}
+	}
+}
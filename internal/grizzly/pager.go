@@ -0,0 +1,55 @@
+package grizzly
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"grizzly/pkg/bear"
+)
+
+// writeNote renders a note's Markdown when stdout is a terminal, paging it
+// when it is taller than the window. --raw and pipes get the text as-is.
+func (o *Outputter) writeNote(note *bear.Note) {
+	style := newTermStyle(o.opts, o.stdout)
+	if o.opts.Raw || style.width == 0 {
+		o.writeNoteText(note.Text)
+		return
+	}
+	rendered := renderMarkdown(note.Text, style)
+	if style.height > 0 && strings.Count(rendered, "\n") >= style.height {
+		if err := runPager(rendered); err == nil {
+			return
+		} else if o.opts.Verbose {
+			fmt.Fprintf(o.stderr, "pager: %v\n", err)
+		}
+	}
+	fmt.Fprint(o.stdout, rendered)
+}
+
+// runPager pipes text through GRIZZLY_PAGER, PAGER or less. An empty value
+// or "cat" disables paging.
+func runPager(text string) error {
+	pager, ok := os.LookupEnv("GRIZZLY_PAGER")
+	if !ok {
+		pager, ok = os.LookupEnv("PAGER")
+	}
+	if !ok {
+		pager = "less"
+	}
+	pager = strings.TrimSpace(pager)
+	if pager == "" || pager == "cat" {
+		return fmt.Errorf("paging disabled")
+	}
+	cmd := exec.Command("sh", "-c", pager)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		// Keep colors and links, and quit if the note fits after all.
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+	return cmd.Run()
+}
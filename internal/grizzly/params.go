@@ -2,28 +2,9 @@ package grizzly
 
 import (
 	"fmt"
-	"net/url"
 	"strings"
 )
 
-func addStringParam(params url.Values, key, value string) {
-	if value != "" {
-		params.Set(key, value)
-	}
-}
-
-func addYesParam(params url.Values, key string, value bool) {
-	if value {
-		params.Set(key, "yes")
-	}
-}
-
-func addNoParam(params url.Values, key string, value bool) {
-	if value {
-		params.Set(key, "no")
-	}
-}
-
 func mergeTags(tags []string, csv string) (string, error) {
 	combined := []string{}
 	for _, tag := range tags {
@@ -46,6 +27,16 @@ func mergeTags(tags []string, csv string) (string, error) {
 	return strings.Join(combined, ","), nil
 }
 
+func splitCSV(csv string) []string {
+	var out []string
+	for _, part := range strings.Split(csv, ",") {
+		if clean := strings.TrimSpace(part); clean != "" {
+			out = append(out, clean)
+		}
+	}
+	return out
+}
+
 func normalizeMode(mode string) (string, error) {
 	if mode == "" {
 		return "", nil
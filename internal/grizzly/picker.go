@@ -0,0 +1,333 @@
+package grizzly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"grizzly/pkg/bear"
+)
+
+var errPickCancelled = errors.New("no note selected")
+
+// pickTarget runs the note picker when pick is set, or when no target was
+// given and stdin and stderr are terminals. It returns "" when the picker
+// did not run, so the command keeps its usual usage error. The picker
+// searches Bear, so it never runs under --dry-run.
+func pickTarget(cmd *cobra.Command, opts *Options, action string, pick, hasTarget bool) (string, error) {
+	if pick && hasTarget {
+		return "", usageError(cmd, "--pick cannot be combined with a note target")
+	}
+	if pick && opts.DryRun {
+		return "", usageError(cmd, "--pick cannot be combined with --dry-run; pass the note's --id")
+	}
+	if !pick && (hasTarget || opts.NoInput || opts.DryRun) {
+		return "", nil
+	}
+	if !stdinIsTTY() || !term.IsTerminal(int(os.Stderr.Fd())) {
+		if pick {
+			return "", usageError(cmd, "--pick requires an interactive terminal")
+		}
+		return "", nil
+	}
+
+	out := NewOutputter(opts)
+	token, err := maybeRequireToken(opts, true)
+	if err != nil {
+		return "", out.WriteError(Result{Action: action}, ErrorInfo{Message: "the picker searches Bear: " + err.Error(), Code: "invalid_request"}, ExitUsage)
+	}
+	notes, err := searchForPicker(opts, token)
+	if err != nil {
+		return "", writeClientError(out, action, err)
+	}
+	id, err := runPicker(notes)
+	if err != nil {
+		if errors.Is(err, errPickCancelled) {
+			return "", &ExitError{Code: ExitFailure, Err: err}
+		}
+		return "", out.WriteError(Result{Action: action}, ErrorInfo{Message: err.Error(), Code: "picker"}, ExitFailure)
+	}
+	return id, nil
+}
+
+// searchForPicker lists the notes to offer, most recently modified first.
+func searchForPicker(opts *Options, token string) ([]bear.NoteSummary, error) {
+	client, err := newClient(opts, token)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := withCallTimeout(context.Background(), opts)
+	defer cancel()
+	res, err := client.Search(ctx, bear.SearchOptions{NoShowWindow: true})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(res.Notes, func(i, j int) bool {
+		return res.Notes[i].ModificationDate.After(res.Notes[j].ModificationDate)
+	})
+	return res.Notes, nil
+}
+
+func runPicker(notes []bear.NoteSummary) (string, error) {
+	if len(notes) == 0 {
+		return "", fmt.Errorf("no notes to pick from")
+	}
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(fd, state)
+
+	width, height, err := term.GetSize(int(os.Stderr.Fd()))
+	if err != nil {
+		width, height = 80, 24
+	}
+	p := newPicker(notes)
+	rows := height - 2
+	if rows > 15 {
+		rows = 15
+	}
+	defer io.WriteString(os.Stderr, "\r\x1b[J")
+
+	buf := make([]byte, 64)
+	for {
+		p.render(os.Stderr, width, rows)
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return "", err
+		}
+		if done, chosen := p.feed(buf[:n]); done {
+			if chosen == nil {
+				return "", errPickCancelled
+			}
+			return chosen.Identifier, nil
+		}
+	}
+}
+
+type picker struct {
+	notes    []bear.NoteSummary
+	query    []rune
+	matches  []bear.NoteSummary
+	selected int
+}
+
+func newPicker(notes []bear.NoteSummary) *picker {
+	p := &picker{notes: notes}
+	p.filter()
+	return p
+}
+
+func (p *picker) filter() {
+	p.matches = filterNotes(p.notes, string(p.query))
+	if p.selected >= len(p.matches) {
+		p.selected = len(p.matches) - 1
+	}
+	if p.selected < 0 {
+		p.selected = 0
+	}
+}
+
+// feed applies raw terminal input. It reports done with the chosen note, or
+// done with nil when the picker was cancelled.
+func (p *picker) feed(input []byte) (bool, *bear.NoteSummary) {
+	for len(input) > 0 {
+		switch {
+		case hasAnyPrefix(input, "\x1b[A", "\x1bOA"):
+			p.move(-1)
+			input = input[3:]
+			continue
+		case hasAnyPrefix(input, "\x1b[B", "\x1bOB"):
+			p.move(1)
+			input = input[3:]
+			continue
+		case input[0] == 0x1b && len(input) > 1 && input[1] == '[':
+			// Skip other escape sequences (arrows left/right, function keys).
+			i := 2
+			for i < len(input) && (input[i] < 0x40 || input[i] > 0x7e) {
+				i++
+			}
+			input = input[min(i+1, len(input)):]
+			continue
+		}
+		switch b := input[0]; b {
+		case '\r', '\n':
+			if len(p.matches) == 0 {
+				return true, nil
+			}
+			chosen := p.matches[p.selected]
+			return true, &chosen
+		case 0x1b, 0x03, 0x04: // Esc, Ctrl-C, Ctrl-D
+			return true, nil
+		case 0x10, 0x0b: // Ctrl-P, Ctrl-K
+			p.move(-1)
+		case 0x0e, '\t': // Ctrl-N, Tab
+			p.move(1)
+		case 0x7f, 0x08:
+			if len(p.query) > 0 {
+				p.query = p.query[:len(p.query)-1]
+				p.filter()
+			}
+		case 0x15: // Ctrl-U
+			p.query = nil
+			p.filter()
+		default:
+			if b < 0x20 {
+				break
+			}
+			r := []rune(string(input))
+			if len(r) > 0 && unicode.IsPrint(r[0]) {
+				p.query = append(p.query, r[0])
+				p.filter()
+				input = input[len(string(r[0])):]
+				continue
+			}
+		}
+		input = input[1:]
+	}
+	return false, nil
+}
+
+func (p *picker) move(delta int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.selected = (p.selected + delta + len(p.matches)) % len(p.matches)
+}
+
+// render draws the query line and up to rows matches below it, leaving the
+// cursor on the query line so the next frame can overwrite this one.
+func (p *picker) render(w io.Writer, width, rows int) {
+	var b strings.Builder
+	prompt := fmt.Sprintf("%d/%d > ", len(p.matches), len(p.notes))
+	b.WriteString("\r\x1b[J" + prompt + string(p.query))
+
+	first := 0
+	if p.selected >= rows {
+		first = p.selected - rows + 1
+	}
+	lines := 0
+	for i := first; i < len(p.matches) && lines < rows; i++ {
+		line := truncateRunes(pickerLine(p.matches[i]), width-3)
+		if i == p.selected {
+			fmt.Fprintf(&b, "\r\n\x1b[7m> %s\x1b[0m", line)
+		} else {
+			fmt.Fprintf(&b, "\r\n  %s", line)
+		}
+		lines++
+	}
+	if lines > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", lines)
+	}
+	fmt.Fprintf(&b, "\r\x1b[%dC", len(prompt)+len(p.query))
+	io.WriteString(w, b.String())
+}
+
+func pickerLine(note bear.NoteSummary) string {
+	title := note.Title
+	if title == "" {
+		title = "(untitled)"
+	}
+	parts := []string{title}
+	if len(note.Tags) > 0 {
+		parts = append(parts, "#"+strings.Join(note.Tags, " #"))
+	}
+	if !note.ModificationDate.IsZero() {
+		parts = append(parts, note.ModificationDate.Local().Format("2006-01-02"))
+	}
+	return strings.Join(parts, "  ")
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if n <= 0 || len(r) <= n {
+		return s
+	}
+	if n == 1 {
+		return "…"
+	}
+	return string(r[:n-1]) + "…"
+}
+
+func hasAnyPrefix(b []byte, prefixes ...string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(string(b), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterNotes keeps notes whose title and tags fuzzily match every
+// space-separated word of query, best matches first.
+func filterNotes(notes []bear.NoteSummary, query string) []bear.NoteSummary {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return notes
+	}
+	type scored struct {
+		note  bear.NoteSummary
+		score int
+	}
+	var hits []scored
+	for _, note := range notes {
+		text := note.Title
+		for _, tag := range note.Tags {
+			text += " #" + tag
+		}
+		total := 0
+		matched := true
+		for _, word := range words {
+			score, ok := fuzzyScore(word, text)
+			if !ok {
+				matched = false
+				break
+			}
+			total += score
+		}
+		if matched {
+			hits = append(hits, scored{note: note, score: total})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].score > hits[j].score })
+	out := make([]bear.NoteSummary, len(hits))
+	for i, hit := range hits {
+		out[i] = hit.note
+	}
+	return out
+}
+
+// fuzzyScore matches pattern as a case-insensitive subsequence of text.
+// Consecutive characters and matches at word starts score higher.
+func fuzzyScore(pattern, text string) (int, bool) {
+	p := []rune(strings.ToLower(pattern))
+	t := []rune(strings.ToLower(text))
+	score, pi, prev := 0, 0, -2
+	for i := 0; i < len(t) && pi < len(p); i++ {
+		if t[i] != p[pi] {
+			continue
+		}
+		score++
+		if prev == i-1 {
+			score += 5
+		}
+		if i == 0 || !unicode.IsLetter(t[i-1]) && !unicode.IsDigit(t[i-1]) {
+			score += 3
+		}
+		prev = i
+		pi++
+	}
+	if pi < len(p) {
+		return 0, false
+	}
+	return score, true
+}
@@ -0,0 +1,70 @@
+package grizzly
+
+import (
+	"testing"
+
+	"grizzly/pkg/bear"
+)
+
+func TestFilterNotesRanksFuzzyMatches(t *testing.T) {
+	notes := []bear.NoteSummary{
+		{Identifier: "1", Title: "Grocery list", Tags: []string{"home"}},
+		{Identifier: "2", Title: "Meeting notes", Tags: []string{"work/standup"}},
+		{Identifier: "3", Title: "Managing notebooks"},
+	}
+	got := filterNotes(notes, "mtg")
+	if len(got) != 1 || got[0].Identifier != "2" {
+		t.Fatalf("filter mtg = %+v", got)
+	}
+	got = filterNotes(notes, "note")
+	if len(got) != 2 || got[0].Identifier != "2" {
+		t.Fatalf("filter note = %+v", got)
+	}
+	got = filterNotes(notes, "notes #work")
+	if len(got) != 1 || got[0].Identifier != "2" {
+		t.Fatalf("filter by tag = %+v", got)
+	}
+	if got := filterNotes(notes, ""); len(got) != 3 {
+		t.Fatalf("empty query = %d notes", len(got))
+	}
+}
+
+func TestPickerFeed(t *testing.T) {
+	notes := []bear.NoteSummary{
+		{Identifier: "A", Title: "Alpha"},
+		{Identifier: "B", Title: "Beta"},
+		{Identifier: "C", Title: "Gamma"},
+	}
+	p := newPicker(notes)
+	if done, _ := p.feed([]byte("\x1b[B\x1b[B\x1b[A")); done {
+		t.Fatal("arrow keys ended the picker")
+	}
+	if done, chosen := p.feed([]byte("\r")); !done || chosen == nil || chosen.Identifier != "B" {
+		t.Fatalf("enter chose %+v", chosen)
+	}
+
+	p = newPicker(notes)
+	p.feed([]byte("gx\x7fm"))
+	if string(p.query) != "gm" || len(p.matches) != 1 {
+		t.Fatalf("query %q matches %+v", string(p.query), p.matches)
+	}
+	if done, chosen := p.feed([]byte("\r")); !done || chosen == nil || chosen.Identifier != "C" {
+		t.Fatalf("enter chose %+v", chosen)
+	}
+
+	p = newPicker(notes)
+	if done, chosen := p.feed([]byte{0x1b}); !done || chosen != nil {
+		t.Fatalf("escape = %v, %+v", done, chosen)
+	}
+}
+
+func TestPickTargetSkipsDryRun(t *testing.T) {
+	opts := &Options{DryRun: true}
+	cmd := NewRootCmd()
+	if id, err := pickTarget(cmd, opts, "trash", false, false); id != "" || err != nil {
+		t.Fatalf("picker under --dry-run = %q, %v", id, err)
+	}
+	if _, err := pickTarget(cmd, opts, "trash", true, false); ExitCode(err) != ExitUsage {
+		t.Fatalf("--pick --dry-run = %v", err)
+	}
+}
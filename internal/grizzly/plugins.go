@@ -0,0 +1,404 @@
+package grizzly
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// annotationPlugin marks commands that run an external grizzly-<name>
+// program; the value is its path.
+const annotationPlugin = "grizzly/plugin"
+
+const pluginPrefix = "grizzly-"
+
+// pluginHandshakeVersion is bumped when pluginHandshake changes
+// incompatibly.
+const pluginHandshakeVersion = 1
+
+// plugin is one grizzly-<name> executable. Shadowed is set when an earlier
+// plugin, an alias or a built-in command already uses the name.
+type plugin struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Shadowed string `json:"shadowed_by"`
+}
+
+type pluginsReport struct {
+	Dirs    []string `json:"dirs"`
+	Plugins []plugin `json:"plugins"`
+}
+
+func (r *pluginsReport) records() recordSet {
+	return listRecords(r.Plugins, plugin{})
+}
+
+func (r *pluginsReport) writeHuman(w io.Writer, style termStyle) {
+	if len(r.Plugins) == 0 {
+		fmt.Fprintln(w, "No plugins installed")
+		return
+	}
+	rows := make([][]tableCell, len(r.Plugins))
+	for i, p := range r.Plugins {
+		note := tableCell{}
+		if p.Shadowed != "" {
+			note = tableCell{text: "shadowed by " + p.Shadowed, style: func(s string) string { return style.paint(ansiDim, s) }}
+		}
+		rows[i] = []tableCell{{text: p.Name}, {text: p.Path}, note}
+	}
+	style.writeTable(w, []string{"NAME", "PATH", "NOTE"}, rows, []int{1})
+}
+
+// pluginHandshake is the first line a plugin reads from stdin.
+type pluginHandshake struct {
+	Version        int               `json:"version"`
+	Name           string            `json:"name"`
+	Args           []string          `json:"args"`
+	Grizzly        string            `json:"grizzly"`
+	GrizzlyVersion string            `json:"grizzly_version"`
+	Profile        string            `json:"profile,omitempty"`
+	Config         map[string]string `json:"config"`
+	Output         pluginOutput      `json:"output"`
+	DryRun         bool              `json:"dry_run"`
+	Callback       bool              `json:"callback"`
+	NoCallback     bool              `json:"no_callback"`
+	Verbose        bool              `json:"verbose"`
+	NoInput        bool              `json:"no_input"`
+	Force          bool              `json:"force"`
+}
+
+type pluginOutput struct {
+	Format  string   `json:"format"`
+	Fields  []string `json:"fields,omitempty"`
+	Query   string   `json:"query,omitempty"`
+	Quiet   bool     `json:"quiet"`
+	NoColor bool     `json:"no_color"`
+}
+
+// pluginDirs lists where plugins are looked up: the plugins_dir setting of
+// profile (default: plugins next to the user config), then PATH. Empty and
+// relative PATH entries are skipped, so the current directory is never
+// searched.
+func pluginDirs(profile string) []string {
+	var dirs []string
+	cfg, err := LoadProfileConfig(profile)
+	if err == nil && cfg.PluginsDir != "" {
+		if dir, err := expandPath(cfg.PluginsDir); err == nil {
+			dirs = append(dirs, dir)
+		}
+	} else if path, err := userConfigPath(); err == nil {
+		dirs = append(dirs, filepath.Join(filepath.Dir(path), "plugins"))
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if filepath.IsAbs(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// findPlugins lists the grizzly-<name> executables in dirs, in lookup
+// order. Names taken by root's commands or an earlier plugin are marked
+// shadowed.
+func findPlugins(root *cobra.Command, dirs []string) []plugin {
+	var plugins []plugin
+	seen := map[string]string{}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), pluginPrefix)
+			if !ok {
+				continue
+			}
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, ".exe")
+			}
+			path := filepath.Join(dir, entry.Name())
+			if !aliasNamePattern.MatchString(name) || !isExecutable(path) {
+				continue
+			}
+			p := plugin{Name: name, Path: path}
+			if first, ok := seen[name]; ok {
+				p.Shadowed = first
+			} else if cmd := pluginShadow(root, name); cmd != "" {
+				p.Shadowed = cmd
+			} else {
+				seen[name] = path
+			}
+			plugins = append(plugins, p)
+		}
+	}
+	return plugins
+}
+
+// pluginShadow describes the command that takes precedence over a plugin
+// called name, or returns "".
+func pluginShadow(root *cobra.Command, name string) string {
+	if name == "help" {
+		return "built-in command"
+	}
+	for _, cmd := range root.Commands() {
+		if cmd.Name() != name && !cmd.HasAlias(name) {
+			continue
+		}
+		if _, ok := cmd.Annotations[annotationAlias]; ok {
+			return "alias"
+		}
+		if _, ok := cmd.Annotations[annotationPlugin]; !ok {
+			return "built-in command"
+		}
+	}
+	return ""
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	return runtime.GOOS == "windows" || info.Mode().Perm()&0o111 != 0
+}
+
+// addPluginsFor adds the plugins root needs to run args. Commands and
+// aliases are left alone; a bare "grizzly", "--help", "help" and command
+// completion list every plugin, and any other name is looked up on its own.
+func addPluginsFor(root *cobra.Command, opts *Options, args []string) {
+	globals, rest := splitGlobalFlags(root, args)
+	name := ""
+	switch {
+	case len(rest) == 0 || rest[0] == "--help" || rest[0] == "-h":
+	case rest[0] == "help" || rest[0] == cobra.ShellCompRequestCmd || rest[0] == cobra.ShellCompNoDescRequestCmd:
+		if len(rest) > 2 || (rest[0] == "help" && len(rest) == 2) {
+			name = rest[1]
+		}
+	default:
+		name = rest[0]
+		more, _ := splitGlobalFlags(root, rest[1:])
+		globals = append(globals, more...)
+	}
+	if name != "" && (!aliasNamePattern.MatchString(name) || pluginShadow(root, name) != "") {
+		return
+	}
+	dirs := pluginDirs(profileFlag(globals))
+	if name == "" {
+		for _, p := range findPlugins(root, dirs) {
+			if p.Shadowed == "" {
+				root.AddCommand(newPluginCmd(opts, p))
+			}
+		}
+		return
+	}
+	if p, ok := lookupPlugin(dirs, name); ok {
+		root.AddCommand(newPluginCmd(opts, p))
+	}
+}
+
+// lookupPlugin finds the first grizzly-<name> executable in dirs.
+func lookupPlugin(dirs []string, name string) (plugin, bool) {
+	file := pluginPrefix + name
+	if runtime.GOOS == "windows" {
+		file += ".exe"
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, file)
+		if isExecutable(path) {
+			return plugin{Name: name, Path: path}, true
+		}
+	}
+	return plugin{}, false
+}
+
+// profileFlag returns the --profile value among the global flags in args,
+// which are not parsed yet when plugins are looked up.
+func profileFlag(args []string) string {
+	fs := pflag.NewFlagSet("plugins", pflag.ContinueOnError)
+	fs.ParseErrorsWhitelist.UnknownFlags = true
+	fs.SetOutput(io.Discard)
+	profile := fs.String("profile", "", "")
+	_ = fs.Parse(args)
+	return *profile
+}
+
+func newPluginCmd(opts *Options, p plugin) *cobra.Command {
+	return &cobra.Command{
+		Use:                p.Name + " [args...]",
+		Short:              "Plugin " + p.Path,
+		Annotations:        map[string]string{annotationPlugin: p.Path},
+		DisableFlagParsing: true,
+		// RunE loads the config once the global flags are parsed.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			globals, rest := splitGlobalFlags(cmd.Root(), args)
+			// Flag parsing is off, so merge and parse the global flags here.
+			_ = cmd.InheritedFlags()
+			if err := cmd.Flags().Parse(globals); err != nil {
+				return usageError(cmd, "%v", err)
+			}
+			if err := cmd.Root().PersistentPreRunE(cmd, rest); err != nil {
+				return err
+			}
+			if opts.TokenStdin {
+				return usageError(cmd, "--token-stdin cannot be passed to plugins; use token_file or token_command")
+			}
+			return runPlugin(cmd, opts, p, rest)
+		},
+	}
+}
+
+// runPlugin runs p with the resolved configuration in GRIZZLY_* variables,
+// so grizzly commands it runs use the same settings, and as a JSON line at
+// the start of its stdin.
+func runPlugin(cmd *cobra.Command, opts *Options, p plugin, args []string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return &ExitError{Code: ExitFailure, Err: err}
+	}
+	config := pluginConfig(opts)
+	handshake := pluginHandshake{
+		Version:        pluginHandshakeVersion,
+		Name:           p.Name,
+		Args:           append([]string{}, args...),
+		Grizzly:        self,
+		GrizzlyVersion: Version,
+		Profile:        opts.Profile,
+		Config:         config,
+		Output: pluginOutput{
+			Format:  outputFormatName(opts),
+			Fields:  opts.Fields,
+			Query:   opts.Query,
+			Quiet:   opts.Quiet,
+			NoColor: opts.NoColor,
+		},
+		DryRun:     opts.DryRun,
+		Callback:   opts.EnableCallback,
+		NoCallback: opts.NoCallback,
+		Verbose:    opts.Verbose,
+		NoInput:    opts.NoInput,
+		Force:      opts.Force,
+	}
+	env := os.Environ()
+	for key, value := range config {
+		env = append(env, "GRIZZLY_"+strings.ToUpper(key)+"="+value)
+	}
+	env = append(env,
+		"GRIZZLY_BIN="+self,
+		"GRIZZLY_PLUGIN_NAME="+p.Name,
+		"GRIZZLY_OUTPUT="+handshake.Output.Format,
+	)
+	if opts.Profile != "" {
+		env = append(env, "GRIZZLY_PROFILE="+opts.Profile)
+	}
+	line, err := json.Marshal(handshake)
+	if err != nil {
+		return &ExitError{Code: ExitFailure, Err: err}
+	}
+
+	child := exec.CommandContext(cmd.Context(), p.Path, args...)
+	child.Env = env
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+	// Our own pipe, rather than an io.Reader, lets Run return when the plugin
+	// exits even if our stdin stays open. A terminal cannot be passed through
+	// a pipe; plugins that prompt open /dev/tty themselves.
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		return &ExitError{Code: ExitFailure, Err: err}
+	}
+	go func() {
+		defer stdinW.Close()
+		if _, err := stdinW.Write(append(line, '\n')); err != nil {
+			return
+		}
+		if !stdinIsTTY() {
+			_, _ = io.Copy(stdinW, os.Stdin)
+		}
+	}()
+	child.Stdin = stdinR
+	err = child.Run()
+	stdinR.Close()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return &ExitError{Code: exitErr.ExitCode(), Err: err}
+		}
+		return NewOutputter(opts).WriteError(Result{Action: p.Name}, ErrorInfo{Message: err.Error(), Code: "plugin"}, ExitFailure)
+	}
+	return nil
+}
+
+// pluginConfig lists the resolved settings a plugin and the grizzly commands
+// it runs should share, by config key. Empty settings are left out.
+func pluginConfig(opts *Options) map[string]string {
+	config := map[string]string{
+		"token_file":      opts.TokenFile,
+		"token_command":   opts.TokenCommand,
+		"callback_url":    opts.Callback,
+		"opener":          opts.Opener,
+		"opener_command":  opts.OpenerCommand,
+		"opener_file":     opts.OpenerFile,
+		"opener_url":      opts.OpenerURL,
+		"fake_bear_store": opts.FakeBearStore,
+		"daemon_socket":   opts.DaemonSocket,
+		"timeout":         opts.Timeout.String(),
+	}
+	for key, value := range config {
+		if value == "" {
+			delete(config, key)
+		}
+	}
+	return config
+}
+
+func outputFormatName(opts *Options) string {
+	switch {
+	case opts.Format != "":
+		return opts.Format
+	case opts.JSON:
+		return "json"
+	case opts.Plain:
+		return "plain"
+	}
+	return "human"
+}
+
+func newPluginsCmd(opts *Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plugins",
+		Short: "List external grizzly-<name> commands",
+		Long: fmt.Sprintf(`List external grizzly-<name> commands.
+
+"grizzly <name>" runs a grizzly-<name> executable found in the plugins
+directory (plugins_dir, default: plugins next to the user config) or on PATH.
+Built-in commands and aliases take precedence. The plugin gets the resolved
+configuration in GRIZZLY_* environment variables and as a JSON line at the
+start of stdin (handshake version %d), and can run "$GRIZZLY_BIN <command>"
+for Bear actions.`, pluginHandshakeVersion),
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List installed plugins, including shadowed ones",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dirs := pluginDirs(opts.Profile)
+			report := &pluginsReport{Dirs: dirs, Plugins: findPlugins(cmd.Root(), dirs)}
+			if report.Plugins == nil {
+				report.Plugins = []plugin{}
+			}
+			NewOutputter(opts).WriteSuccess(Result{Action: "plugins-list", Data: report})
+			return nil
+		},
+	})
+	return cmd
+}
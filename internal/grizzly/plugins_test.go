@@ -0,0 +1,164 @@
+package grizzly
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePlugin(t *testing.T, dir, name, script string, mode os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), mode); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestFindPlugins(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	hello := writePlugin(t, first, "grizzly-hello", "", 0o755)
+	writePlugin(t, second, "grizzly-hello", "", 0o755)
+	writePlugin(t, second, "grizzly-tags", "", 0o755)
+	writePlugin(t, second, "grizzly-notes", "", 0o644)
+	writePlugin(t, second, "grizzly-Bad", "", 0o755)
+	writePlugin(t, second, "other-tool", "", 0o755)
+
+	got := findPlugins(NewRootCmd(), []string{first, filepath.Join(first, "missing"), second})
+	want := []plugin{
+		{Name: "hello", Path: hello},
+		{Name: "hello", Path: filepath.Join(second, "grizzly-hello"), Shadowed: hello},
+		{Name: "tags", Path: filepath.Join(second, "grizzly-tags"), Shadowed: "built-in command"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("plugins = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("plugin %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRunPlugin(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	t.Setenv("GRIZZLY_OPENER", "fake")
+	for _, name := range []string{"GRIZZLY_PROFILE", "GRIZZLY_TIMEOUT", "GRIZZLY_TOKEN_FILE", "GRIZZLY_TOKEN_COMMAND", "GRIZZLY_PLUGINS_DIR"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	dir := filepath.Join(root, "grizzly", "plugins")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	record := filepath.Join(root, "record")
+	writePlugin(t, dir, "grizzly-hello", `read -r handshake
+printf '%s\n%s\n%s\n' "$handshake" "$GRIZZLY_TOKEN_FILE $GRIZZLY_TIMEOUT $GRIZZLY_PLUGIN_NAME" "$*" > "`+record+`"
+exit 3
+`, 0o755)
+
+	cmd := NewRootCmdWithArgs([]string{"--timeout", "2s", "hello", "--token-file", "/tmp/tok", "--no-callback", "a b", "--flag"})
+	if err := cmd.Execute(); ExitCode(err) != 3 {
+		t.Fatalf("exit code = %d (%v)", ExitCode(err), err)
+	}
+	data, err := os.ReadFile(record)
+	if err != nil {
+		t.Fatalf("plugin did not run: %v", err)
+	}
+	lines := strings.Split(string(data), "\n")
+	var handshake pluginHandshake
+	if err := json.Unmarshal([]byte(lines[0]), &handshake); err != nil {
+		t.Fatalf("handshake %q: %v", lines[0], err)
+	}
+	if handshake.Version != pluginHandshakeVersion || handshake.Name != "hello" || handshake.Config["timeout"] != "2s" ||
+		handshake.Config["token_file"] != "/tmp/tok" || handshake.Output.Format != "json" || len(handshake.Args) != 2 ||
+		handshake.Callback || !handshake.NoCallback {
+		t.Fatalf("handshake = %+v", handshake)
+	}
+	if lines[1] != "/tmp/tok 2s hello" || lines[2] != "a b --flag" {
+		t.Fatalf("env and args = %q", lines[1:])
+	}
+}
+
+func TestPluginLookupIsLazy(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	t.Setenv("PATH", "")
+	for _, name := range []string{"GRIZZLY_PROFILE", "GRIZZLY_PLUGINS_DIR"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	dir := filepath.Join(root, "grizzly", "plugins")
+	work := filepath.Join(root, "work-plugins")
+	for _, d := range []string{dir, work} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	writePlugin(t, dir, "grizzly-hello", "", 0o755)
+	writePlugin(t, dir, "grizzly-other", "", 0o755)
+	writePlugin(t, work, "grizzly-weekly", "", 0o755)
+	config := "[profiles.work]\nplugins_dir = \"" + work + "\"\n"
+	if err := os.WriteFile(filepath.Join(root, "grizzly", "config.toml"), []byte(config), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	plugins := func(args ...string) string {
+		var names []string
+		for _, cmd := range NewRootCmdWithArgs(args).Commands() {
+			if _, ok := cmd.Annotations[annotationPlugin]; ok {
+				names = append(names, cmd.Name())
+			}
+		}
+		return strings.Join(names, ",")
+	}
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"tags"}, ""},
+		{[]string{"--json", "hello", "x"}, "hello"},
+		{[]string{"help", "hello"}, "hello"},
+		{[]string{"missing"}, ""},
+		{[]string{"--help"}, "hello,other"},
+		{[]string{"help"}, "hello,other"},
+		{[]string{"--profile", "work", "weekly"}, "weekly"},
+		{[]string{"weekly", "--profile=work"}, "weekly"},
+		{[]string{"weekly"}, ""},
+	} {
+		if got := plugins(tc.args...); got != tc.want {
+			t.Fatalf("%q plugins = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+
+	// Empty and relative PATH entries do not make the current directory a
+	// plugin directory.
+	checkout := t.TempDir()
+	writePlugin(t, checkout, "grizzly-dot", "", 0o755)
+	if err := os.MkdirAll(filepath.Join(checkout, "bin"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writePlugin(t, filepath.Join(checkout, "bin"), "grizzly-rel", "", 0o755)
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(checkout); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	defer func() {
+		_ = os.Chdir(oldWd)
+	}()
+	t.Setenv("PATH", string(os.PathListSeparator)+"."+string(os.PathListSeparator)+"bin")
+	for _, name := range []string{"dot", "rel"} {
+		if got := plugins(name); got != "" {
+			t.Fatalf("%s plugins = %q", name, got)
+		}
+	}
+	if got := plugins("--help"); got != "hello,other" {
+		t.Fatalf("--help plugins = %q", got)
+	}
+}
@@ -0,0 +1,1441 @@
+package grizzly
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// queryFunc evaluates a compiled --query expression against one input and
+// returns its outputs, like a jq filter.
+type queryFunc func(v any) ([]any, error)
+
+// compileQuery parses a jq-style expression. Supported: paths (.a.b, .[0],
+// .[], .[1:3], ."key", ?), pipes, commas, literals, array and object
+// construction, comparisons, and/or, //, + - * / %, and the functions listed
+// in queryBuiltins.
+func compileQuery(src string) (queryFunc, error) {
+	toks, err := lexQuery(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{toks: toks}
+	f, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if !p.at(tokEOF, "") {
+		return nil, fmt.Errorf("unexpected %q", p.peek().text)
+	}
+	return f, nil
+}
+
+// queryValue converts v to the generic form the query engine works on:
+// the values encoding/json produces when decoding into any.
+func queryValue(v any) (any, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	err = json.Unmarshal(raw, &out)
+	return out, err
+}
+
+// evalQuery runs src against v and returns every output value.
+func evalQuery(src string, v any) ([]any, error) {
+	f, err := compileQuery(src)
+	if err != nil {
+		return nil, err
+	}
+	in, err := queryValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return f(in)
+}
+
+// queryRecords turns query outputs into records for --format. Arrays are
+// flattened one level; values that are not objects become {"value": v}.
+func queryRecords(outs []any) recordSet {
+	var items []any
+	rs := recordSet{List: len(outs) != 1}
+	for _, out := range outs {
+		if arr, ok := out.([]any); ok {
+			items = append(items, arr...)
+			rs.List = true
+			continue
+		}
+		items = append(items, out)
+	}
+	seen := map[string]bool{}
+	for _, item := range items {
+		record, ok := item.(map[string]any)
+		if !ok {
+			record = map[string]any{"value": item}
+		}
+		for _, key := range sortedKeys(record) {
+			if !seen[key] {
+				seen[key] = true
+				rs.Fields = append(rs.Fields, key)
+			}
+		}
+		rs.Records = append(rs.Records, record)
+	}
+	return rs
+}
+
+type queryTokKind int
+
+const (
+	tokEOF queryTokKind = iota
+	tokIdent
+	tokField
+	tokNumber
+	tokString
+	tokPunct
+)
+
+type queryTok struct {
+	kind queryTokKind
+	text string
+	num  float64
+}
+
+var queryPuncts = []string{"//", "==", "!=", "<=", ">=", "..", "|", ",", "(", ")", "[", "]", "{", "}", ":", ";", "?", "<", ">", "+", "-", "*", "/", "%", "."}
+
+func lexQuery(src string) ([]queryTok, error) {
+	var toks []queryTok
+	r := []rune(src)
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(r) && r[j] != '"' {
+				if r[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(r) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			s, err := strconv.Unquote(string(r[i : j+1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", string(r[i:j+1]))
+			}
+			toks = append(toks, queryTok{kind: tokString, text: s})
+			i = j + 1
+		case unicode.IsDigit(c):
+			j := i
+			for j < len(r) && (unicode.IsDigit(r[j]) || r[j] == '.' || r[j] == 'e' || r[j] == 'E') {
+				j++
+			}
+			n, err := strconv.ParseFloat(string(r[i:j]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %s", string(r[i:j]))
+			}
+			toks = append(toks, queryTok{kind: tokNumber, text: string(r[i:j]), num: n})
+			i = j
+		case c == '.' && i+1 < len(r) && isQueryIdentStart(r[i+1]):
+			j := i + 1
+			for j < len(r) && isQueryIdent(r[j]) {
+				j++
+			}
+			toks = append(toks, queryTok{kind: tokField, text: string(r[i+1 : j])})
+			i = j
+		case isQueryIdentStart(c):
+			j := i
+			for j < len(r) && isQueryIdent(r[j]) {
+				j++
+			}
+			toks = append(toks, queryTok{kind: tokIdent, text: string(r[i:j])})
+			i = j
+		default:
+			matched := false
+			for _, p := range queryPuncts {
+				if strings.HasPrefix(string(r[i:]), p) {
+					toks = append(toks, queryTok{kind: tokPunct, text: p})
+					i += len([]rune(p))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+		}
+	}
+	return append(toks, queryTok{kind: tokEOF}), nil
+}
+
+func isQueryIdentStart(c rune) bool { return c == '_' || unicode.IsLetter(c) }
+func isQueryIdent(c rune) bool      { return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c) }
+
+type queryParser struct {
+	toks []queryTok
+	pos  int
+}
+
+func (p *queryParser) peek() queryTok { return p.toks[p.pos] }
+
+func (p *queryParser) at(kind queryTokKind, text string) bool {
+	t := p.peek()
+	return t.kind == kind && (text == "" || t.text == text)
+}
+
+func (p *queryParser) accept(text string) bool {
+	if p.at(tokPunct, text) || p.at(tokIdent, text) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) expect(text string) error {
+	if !p.accept(text) {
+		return fmt.Errorf("expected %q, found %q", text, p.peek().text)
+	}
+	return nil
+}
+
+func (p *queryParser) parsePipe() (queryFunc, error) {
+	left, err := p.parseComma()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("|") {
+		right, err := p.parseComma()
+		if err != nil {
+			return nil, err
+		}
+		left = pipeQuery(left, right)
+	}
+	return left, nil
+}
+
+func pipeQuery(left, right queryFunc) queryFunc {
+	return func(v any) ([]any, error) {
+		ins, err := left(v)
+		if err != nil {
+			return nil, err
+		}
+		var outs []any
+		for _, in := range ins {
+			res, err := right(in)
+			if err != nil {
+				return nil, err
+			}
+			outs = append(outs, res...)
+		}
+		return outs, nil
+	}
+}
+
+func (p *queryParser) parseComma() (queryFunc, error) {
+	left, err := p.parseAlt()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(",") {
+		right, err := p.parseAlt()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(v any) ([]any, error) {
+			a, err := l(v)
+			if err != nil {
+				return nil, err
+			}
+			b, err := right(v)
+			return append(a, b...), err
+		}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAlt() (queryFunc, error) {
+	left, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("//") {
+		return left, nil
+	}
+	right, err := p.parseAlt()
+	if err != nil {
+		return nil, err
+	}
+	return func(v any) ([]any, error) {
+		a, err := left(v)
+		var kept []any
+		if err == nil {
+			for _, x := range a {
+				if queryTruthy(x) {
+					kept = append(kept, x)
+				}
+			}
+		}
+		if len(kept) > 0 {
+			return kept, nil
+		}
+		return right(v)
+	}, nil
+}
+
+func (p *queryParser) parseOr() (queryFunc, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryQuery(left, right, func(a, b any) (any, error) { return queryTruthy(a) || queryTruthy(b), nil })
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryFunc, error) {
+	left, err := p.parseCompare()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and") {
+		right, err := p.parseCompare()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryQuery(left, right, func(a, b any) (any, error) { return queryTruthy(a) && queryTruthy(b), nil })
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseCompare() (queryFunc, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if !p.accept(op) {
+			continue
+		}
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		op := op
+		return binaryQuery(left, right, func(a, b any) (any, error) {
+			c := compareQueryValues(a, b)
+			switch op {
+			case "==":
+				return c == 0, nil
+			case "!=":
+				return c != 0, nil
+			case "<=":
+				return c <= 0, nil
+			case ">=":
+				return c >= 0, nil
+			case "<":
+				return c < 0, nil
+			default:
+				return c > 0, nil
+			}
+		}), nil
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAdditive() (queryFunc, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		switch {
+		case p.accept("+"):
+			op = "+"
+		case p.accept("-"):
+			op = "-"
+		default:
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryQuery(left, right, func(a, b any) (any, error) { return queryArith(op, a, b) })
+	}
+}
+
+func (p *queryParser) parseMultiplicative() (queryFunc, error) {
+	left, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		switch {
+		case p.accept("*"):
+			op = "*"
+		case p.accept("/"):
+			op = "/"
+		case p.accept("%"):
+			op = "%"
+		default:
+			return left, nil
+		}
+		right, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryQuery(left, right, func(a, b any) (any, error) { return queryArith(op, a, b) })
+	}
+}
+
+// binaryQuery evaluates both sides against the same input and combines
+// every pair of outputs.
+func binaryQuery(left, right queryFunc, op func(a, b any) (any, error)) queryFunc {
+	return func(v any) ([]any, error) {
+		rs, err := right(v)
+		if err != nil {
+			return nil, err
+		}
+		ls, err := left(v)
+		if err != nil {
+			return nil, err
+		}
+		var outs []any
+		for _, r := range rs {
+			for _, l := range ls {
+				out, err := op(l, r)
+				if err != nil {
+					return nil, err
+				}
+				outs = append(outs, out)
+			}
+		}
+		return outs, nil
+	}
+}
+
+func (p *queryParser) parsePostfix() (queryFunc, error) {
+	term, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.at(tokField, ""):
+			name := p.peek().text
+			p.pos++
+			term = pipeQuery(term, indexQuery(constQuery(name)))
+		case p.at(tokPunct, ".") && p.toks[p.pos+1].kind == tokString:
+			name := p.toks[p.pos+1].text
+			p.pos += 2
+			term = pipeQuery(term, indexQuery(constQuery(name)))
+		case p.at(tokPunct, ".") && p.toks[p.pos+1].kind == tokPunct && p.toks[p.pos+1].text == "[":
+			p.pos++
+		case p.at(tokPunct, "["):
+			p.pos++
+			suffix, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			term = pipeQuery(term, suffix)
+		case p.accept("?"):
+			t := term
+			term = func(v any) ([]any, error) {
+				out, err := t(v)
+				if err != nil {
+					return nil, nil
+				}
+				return out, nil
+			}
+		default:
+			return term, nil
+		}
+	}
+}
+
+// parseBracket parses the inside of [...] after a path: [], [i], [a:b].
+func (p *queryParser) parseBracket() (queryFunc, error) {
+	if p.accept("]") {
+		return iterateQuery, nil
+	}
+	var from, to queryFunc
+	var err error
+	if !p.at(tokPunct, ":") {
+		if from, err = p.parsePipe(); err != nil {
+			return nil, err
+		}
+	}
+	if p.accept(":") {
+		if !p.at(tokPunct, "]") {
+			if to, err = p.parsePipe(); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return sliceQuery(from, to), nil
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return indexQuery(from), nil
+}
+
+func (p *queryParser) parseTerm() (queryFunc, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokField:
+		// Handled as a suffix on the identity.
+		return identityQuery, nil
+	case t.kind == tokNumber:
+		p.pos++
+		return constQuery(t.num), nil
+	case t.kind == tokString:
+		p.pos++
+		return constQuery(t.text), nil
+	case p.accept(".."):
+		return recurseQuery, nil
+	case p.accept("."):
+		if p.at(tokString, "") {
+			name := p.peek().text
+			p.pos++
+			return indexQuery(constQuery(name)), nil
+		}
+		return identityQuery, nil
+	case p.accept("-"):
+		inner, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		return binaryQuery(constQuery(0.0), inner, func(a, b any) (any, error) { return queryArith("-", a, b) }), nil
+	case p.accept("("):
+		inner, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	case p.accept("["):
+		if p.accept("]") {
+			return constQuery([]any{}), nil
+		}
+		inner, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return func(v any) ([]any, error) {
+			out, err := inner(v)
+			if out == nil {
+				out = []any{}
+			}
+			return []any{out}, err
+		}, nil
+	case p.accept("{"):
+		return p.parseObject()
+	case t.kind == tokIdent:
+		p.pos++
+		switch t.text {
+		case "true":
+			return constQuery(true), nil
+		case "false":
+			return constQuery(false), nil
+		case "null":
+			return constQuery(nil), nil
+		case "if":
+			return p.parseIf()
+		}
+		var args []queryFunc
+		if p.accept("(") {
+			for {
+				arg, err := p.parsePipe()
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, arg)
+				if p.accept(")") {
+					break
+				}
+				if err := p.expect(";"); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return queryCall(t.text, args)
+	}
+	if t.kind == tokEOF {
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+func (p *queryParser) parseIf() (queryFunc, error) {
+	cond, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("then"); err != nil {
+		return nil, err
+	}
+	then, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	var otherwise queryFunc = identityQuery
+	switch {
+	case p.accept("elif"):
+		if otherwise, err = p.parseIf(); err != nil {
+			return nil, err
+		}
+		return ifQuery(cond, then, otherwise), nil
+	case p.accept("else"):
+		if otherwise, err = p.parsePipe(); err != nil {
+			return nil, err
+		}
+	}
+	return ifQuery(cond, then, otherwise), p.expect("end")
+}
+
+func ifQuery(cond, then, otherwise queryFunc) queryFunc {
+	return func(v any) ([]any, error) {
+		conds, err := cond(v)
+		if err != nil {
+			return nil, err
+		}
+		var outs []any
+		for _, c := range conds {
+			branch := otherwise
+			if queryTruthy(c) {
+				branch = then
+			}
+			out, err := branch(v)
+			if err != nil {
+				return nil, err
+			}
+			outs = append(outs, out...)
+		}
+		return outs, nil
+	}
+}
+
+func (p *queryParser) parseObject() (queryFunc, error) {
+	type entry struct {
+		key   queryFunc
+		value queryFunc
+	}
+	var entries []entry
+	for !p.accept("}") {
+		var e entry
+		t := p.peek()
+		switch {
+		case t.kind == tokIdent || t.kind == tokString:
+			p.pos++
+			e.key = constQuery(t.text)
+			e.value = indexQuery(constQuery(t.text))
+		case p.accept("("):
+			key, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			e.key = key
+		default:
+			return nil, fmt.Errorf("unexpected %q in object", t.text)
+		}
+		if p.accept(":") {
+			value, err := p.parseAlt()
+			if err != nil {
+				return nil, err
+			}
+			e.value = value
+		} else if e.value == nil {
+			return nil, fmt.Errorf("expected \":\" in object")
+		}
+		entries = append(entries, e)
+		if !p.accept(",") {
+			if err := p.expect("}"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return func(v any) ([]any, error) {
+		objs := []map[string]any{{}}
+		for _, e := range entries {
+			keys, err := e.key(v)
+			if err != nil {
+				return nil, err
+			}
+			values, err := e.value(v)
+			if err != nil {
+				return nil, err
+			}
+			var next []map[string]any
+			for _, obj := range objs {
+				for _, k := range keys {
+					ks, ok := k.(string)
+					if !ok {
+						return nil, fmt.Errorf("object keys must be strings, got %s", queryType(k))
+					}
+					for _, val := range values {
+						copied := make(map[string]any, len(obj)+1)
+						for ok, ov := range obj {
+							copied[ok] = ov
+						}
+						copied[ks] = val
+						next = append(next, copied)
+					}
+				}
+			}
+			objs = next
+		}
+		outs := make([]any, len(objs))
+		for i, obj := range objs {
+			outs[i] = obj
+		}
+		return outs, nil
+	}, nil
+}
+
+func identityQuery(v any) ([]any, error) { return []any{v}, nil }
+
+func constQuery(c any) queryFunc {
+	return func(any) ([]any, error) { return []any{c}, nil }
+}
+
+func indexQuery(key queryFunc) queryFunc {
+	return func(v any) ([]any, error) {
+		keys, err := key(v)
+		if err != nil {
+			return nil, err
+		}
+		outs := make([]any, 0, len(keys))
+		for _, k := range keys {
+			out, err := queryIndex(v, k)
+			if err != nil {
+				return nil, err
+			}
+			outs = append(outs, out)
+		}
+		return outs, nil
+	}
+}
+
+func queryIndex(v, k any) (any, error) {
+	switch typed := v.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		if s, ok := k.(string); ok {
+			return typed[s], nil
+		}
+	case []any:
+		if n, ok := k.(float64); ok {
+			i := int(math.Floor(n))
+			if i < 0 {
+				i += len(typed)
+			}
+			if i < 0 || i >= len(typed) {
+				return nil, nil
+			}
+			return typed[i], nil
+		}
+	}
+	return nil, fmt.Errorf("cannot index %s with %s", queryType(v), queryType(k))
+}
+
+func iterateQuery(v any) ([]any, error) {
+	switch typed := v.(type) {
+	case []any:
+		return append([]any(nil), typed...), nil
+	case map[string]any:
+		keys := sortedKeys(typed)
+		outs := make([]any, len(keys))
+		for i, k := range keys {
+			outs[i] = typed[k]
+		}
+		return outs, nil
+	}
+	return nil, fmt.Errorf("cannot iterate over %s", queryType(v))
+}
+
+func recurseQuery(v any) ([]any, error) {
+	outs := []any{v}
+	switch typed := v.(type) {
+	case []any, map[string]any:
+		children, _ := iterateQuery(typed)
+		for _, child := range children {
+			more, _ := recurseQuery(child)
+			outs = append(outs, more...)
+		}
+	}
+	return outs, nil
+}
+
+func sliceQuery(from, to queryFunc) queryFunc {
+	return func(v any) ([]any, error) {
+		bound := func(f queryFunc, def int) (int, error) {
+			if f == nil {
+				return def, nil
+			}
+			out, err := f(v)
+			if err != nil {
+				return 0, err
+			}
+			if len(out) != 1 {
+				return 0, fmt.Errorf("slice bounds must be single numbers")
+			}
+			n, ok := out[0].(float64)
+			if !ok {
+				if out[0] == nil {
+					return def, nil
+				}
+				return 0, fmt.Errorf("slice bounds must be numbers")
+			}
+			return int(math.Floor(n)), nil
+		}
+		var length int
+		switch typed := v.(type) {
+		case nil:
+			return []any{nil}, nil
+		case []any:
+			length = len(typed)
+		case string:
+			length = len([]rune(typed))
+		default:
+			return nil, fmt.Errorf("cannot slice %s", queryType(v))
+		}
+		start, err := bound(from, 0)
+		if err != nil {
+			return nil, err
+		}
+		end, err := bound(to, length)
+		if err != nil {
+			return nil, err
+		}
+		clamp := func(i int) int {
+			if i < 0 {
+				i += length
+			}
+			return max(0, min(i, length))
+		}
+		start, end = clamp(start), clamp(end)
+		if end < start {
+			end = start
+		}
+		if s, ok := v.(string); ok {
+			return []any{string([]rune(s)[start:end])}, nil
+		}
+		return []any{append([]any{}, v.([]any)[start:end]...)}, nil
+	}
+}
+
+// queryBuiltins lists the supported functions by name and arity.
+var queryBuiltins = map[string]int{
+	"length": 0, "keys": 0, "sort": 0, "reverse": 0, "first": 0, "last": 0,
+	"unique": 0, "add": 0, "tostring": 0, "tonumber": 0, "type": 0, "not": 0,
+	"empty": 0, "ascii_downcase": 0, "ascii_upcase": 0, "min": 0, "max": 0,
+	"any": 0, "all": 0, "to_entries": 0, "from_entries": 0,
+	"map": 1, "select": 1, "sort_by": 1, "group_by": 1, "unique_by": 1,
+	"min_by": 1, "max_by": 1, "has": 1, "contains": 1, "test": 1,
+	"startswith": 1, "endswith": 1, "join": 1, "split": 1, "limit": 2,
+}
+
+func queryCall(name string, args []queryFunc) (queryFunc, error) {
+	arity, ok := queryBuiltins[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	if len(args) != arity {
+		return nil, fmt.Errorf("%s takes %d argument(s), got %d", name, arity, len(args))
+	}
+	switch name {
+	case "map":
+		return pipeQuery(iterateQuery, args[0]).collect(), nil
+	case "select":
+		return func(v any) ([]any, error) {
+			conds, err := args[0](v)
+			if err != nil {
+				return nil, err
+			}
+			var outs []any
+			for _, c := range conds {
+				if queryTruthy(c) {
+					outs = append(outs, v)
+				}
+			}
+			return outs, nil
+		}, nil
+	case "empty":
+		return func(any) ([]any, error) { return nil, nil }, nil
+	case "sort_by", "group_by", "unique_by", "min_by", "max_by":
+		return byKeyQuery(name, args[0]), nil
+	case "limit":
+		return func(v any) ([]any, error) {
+			ns, err := args[0](v)
+			if err != nil {
+				return nil, err
+			}
+			outs, err := args[1](v)
+			if err != nil {
+				return nil, err
+			}
+			var res []any
+			for _, n := range ns {
+				count, ok := n.(float64)
+				if !ok {
+					return nil, fmt.Errorf("limit needs a number")
+				}
+				res = append(res, outs[:max(0, min(int(count), len(outs)))]...)
+			}
+			return res, nil
+		}, nil
+	}
+	if arity == 1 {
+		return func(v any) ([]any, error) {
+			argv, err := args[0](v)
+			if err != nil {
+				return nil, err
+			}
+			outs := make([]any, 0, len(argv))
+			for _, a := range argv {
+				out, err := queryFunc1(name, v, a)
+				if err != nil {
+					return nil, err
+				}
+				outs = append(outs, out)
+			}
+			return outs, nil
+		}, nil
+	}
+	return func(v any) ([]any, error) {
+		out, err := queryFunc0(name, v)
+		if err != nil {
+			return nil, err
+		}
+		return []any{out}, nil
+	}, nil
+}
+
+// collect wraps all outputs of f into one array, like [f].
+func (f queryFunc) collect() queryFunc {
+	return func(v any) ([]any, error) {
+		out, err := f(v)
+		if out == nil {
+			out = []any{}
+		}
+		return []any{out}, err
+	}
+}
+
+func queryFunc0(name string, v any) (any, error) {
+	switch name {
+	case "length":
+		switch typed := v.(type) {
+		case nil:
+			return 0.0, nil
+		case string:
+			return float64(len([]rune(typed))), nil
+		case []any:
+			return float64(len(typed)), nil
+		case map[string]any:
+			return float64(len(typed)), nil
+		case float64:
+			return math.Abs(typed), nil
+		}
+	case "keys":
+		switch typed := v.(type) {
+		case map[string]any:
+			keys := sortedKeys(typed)
+			out := make([]any, len(keys))
+			for i, k := range keys {
+				out[i] = k
+			}
+			return out, nil
+		case []any:
+			out := make([]any, len(typed))
+			for i := range typed {
+				out[i] = float64(i)
+			}
+			return out, nil
+		}
+	case "type":
+		return queryType(v), nil
+	case "not":
+		return !queryTruthy(v), nil
+	case "tostring":
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+		return formatJSONLine(v), nil
+	case "tonumber":
+		switch typed := v.(type) {
+		case float64:
+			return typed, nil
+		case string:
+			n, err := strconv.ParseFloat(strings.TrimSpace(typed), 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse %q as a number", typed)
+			}
+			return n, nil
+		}
+	case "ascii_downcase", "ascii_upcase":
+		if s, ok := v.(string); ok {
+			if name == "ascii_downcase" {
+				return strings.ToLower(s), nil
+			}
+			return strings.ToUpper(s), nil
+		}
+	case "to_entries":
+		if obj, ok := v.(map[string]any); ok {
+			var out []any
+			for _, k := range sortedKeys(obj) {
+				out = append(out, map[string]any{"key": k, "value": obj[k]})
+			}
+			return out, nil
+		}
+	case "from_entries":
+		if arr, ok := v.([]any); ok {
+			out := map[string]any{}
+			for _, item := range arr {
+				entry, ok := item.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("from_entries needs objects")
+				}
+				key, ok := entry["key"].(string)
+				if !ok {
+					key, ok = entry["name"].(string)
+				}
+				if !ok {
+					return nil, fmt.Errorf("from_entries needs string keys")
+				}
+				out[key] = entry["value"]
+			}
+			return out, nil
+		}
+	}
+
+	arr, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s cannot be applied to %s", name, queryType(v))
+	}
+	switch name {
+	case "sort":
+		out := append([]any{}, arr...)
+		sort.SliceStable(out, func(i, j int) bool { return compareQueryValues(out[i], out[j]) < 0 })
+		return out, nil
+	case "reverse":
+		out := make([]any, len(arr))
+		for i, item := range arr {
+			out[len(arr)-1-i] = item
+		}
+		return out, nil
+	case "first":
+		if len(arr) == 0 {
+			return nil, nil
+		}
+		return arr[0], nil
+	case "last":
+		if len(arr) == 0 {
+			return nil, nil
+		}
+		return arr[len(arr)-1], nil
+	case "unique":
+		out, _ := queryFunc0("sort", arr)
+		return dedupeSorted(out.([]any), func(x any) any { return x }), nil
+	case "min", "max":
+		if len(arr) == 0 {
+			return nil, nil
+		}
+		best := arr[0]
+		for _, item := range arr[1:] {
+			c := compareQueryValues(item, best)
+			if (name == "min" && c < 0) || (name == "max" && c >= 0) {
+				best = item
+			}
+		}
+		return best, nil
+	case "add":
+		var sum any
+		for _, item := range arr {
+			var err error
+			if sum, err = queryArith("+", sum, item); err != nil {
+				return nil, err
+			}
+		}
+		return sum, nil
+	case "any", "all":
+		for _, item := range arr {
+			if queryTruthy(item) == (name == "any") {
+				return name == "any", nil
+			}
+		}
+		return name == "all", nil
+	}
+	return nil, fmt.Errorf("%s cannot be applied to %s", name, queryType(v))
+}
+
+func queryFunc1(name string, v, arg any) (any, error) {
+	switch name {
+	case "has":
+		switch typed := v.(type) {
+		case map[string]any:
+			if k, ok := arg.(string); ok {
+				_, found := typed[k]
+				return found, nil
+			}
+		case []any:
+			if n, ok := arg.(float64); ok {
+				return n >= 0 && int(n) < len(typed), nil
+			}
+		}
+		return nil, fmt.Errorf("cannot check whether %s has a %s key", queryType(v), queryType(arg))
+	case "contains":
+		return queryContains(v, arg), nil
+	}
+
+	if name == "join" {
+		arr, ok := v.([]any)
+		sep, sepOK := arg.(string)
+		if !ok || !sepOK {
+			return nil, fmt.Errorf("join needs an array and a string separator")
+		}
+		parts := make([]string, len(arr))
+		for i, item := range arr {
+			if item != nil {
+				parts[i] = cellText(item)
+			}
+		}
+		return strings.Join(parts, sep), nil
+	}
+
+	s, ok := v.(string)
+	a, argOK := arg.(string)
+	if !ok || !argOK {
+		return nil, fmt.Errorf("%s needs string input and argument, got %s and %s", name, queryType(v), queryType(arg))
+	}
+	switch name {
+	case "test":
+		re, err := regexp.Compile(a)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(s), nil
+	case "startswith":
+		return strings.HasPrefix(s, a), nil
+	case "endswith":
+		return strings.HasSuffix(s, a), nil
+	default: // split
+		parts := strings.Split(s, a)
+		out := make([]any, len(parts))
+		for i, part := range parts {
+			out[i] = part
+		}
+		return out, nil
+	}
+}
+
+func byKeyQuery(name string, key queryFunc) queryFunc {
+	return func(v any) ([]any, error) {
+		arr, ok := v.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s cannot be applied to %s", name, queryType(v))
+		}
+		type keyed struct {
+			key  any
+			item any
+		}
+		items := make([]keyed, len(arr))
+		for i, item := range arr {
+			k, err := key.collect()(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = keyed{key: k[0], item: item}
+		}
+		sort.SliceStable(items, func(i, j int) bool { return compareQueryValues(items[i].key, items[j].key) < 0 })
+		switch name {
+		case "min_by", "max_by":
+			if len(items) == 0 {
+				return []any{nil}, nil
+			}
+			if name == "min_by" {
+				return []any{items[0].item}, nil
+			}
+			return []any{items[len(items)-1].item}, nil
+		case "group_by":
+			var groups []any
+			for i, it := range items {
+				if i == 0 || compareQueryValues(items[i-1].key, it.key) != 0 {
+					groups = append(groups, []any{})
+				}
+				last := len(groups) - 1
+				groups[last] = append(groups[last].([]any), it.item)
+			}
+			if groups == nil {
+				groups = []any{}
+			}
+			return []any{groups}, nil
+		}
+		out := make([]any, 0, len(items))
+		for i, it := range items {
+			if name == "unique_by" && i > 0 && compareQueryValues(items[i-1].key, it.key) == 0 {
+				continue
+			}
+			out = append(out, it.item)
+		}
+		return []any{out}, nil
+	}
+}
+
+func dedupeSorted(arr []any, key func(any) any) []any {
+	out := make([]any, 0, len(arr))
+	for i, item := range arr {
+		if i > 0 && compareQueryValues(key(arr[i-1]), key(item)) == 0 {
+			continue
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+func queryContains(v, arg any) bool {
+	switch typed := v.(type) {
+	case string:
+		s, ok := arg.(string)
+		return ok && strings.Contains(typed, s)
+	case []any:
+		want, ok := arg.([]any)
+		if !ok {
+			return false
+		}
+		for _, w := range want {
+			found := false
+			for _, item := range typed {
+				if queryContains(item, w) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		want, ok := arg.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, w := range want {
+			item, found := typed[k]
+			if !found || !queryContains(item, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return compareQueryValues(v, arg) == 0
+}
+
+func queryArith(op string, a, b any) (any, error) {
+	if op == "+" {
+		if a == nil {
+			return b, nil
+		}
+		if b == nil {
+			return a, nil
+		}
+	}
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			switch op {
+			case "+":
+				return x + y, nil
+			case "-":
+				return x - y, nil
+			case "*":
+				return x * y, nil
+			case "/":
+				if y == 0 {
+					return nil, fmt.Errorf("division by zero")
+				}
+				return x / y, nil
+			case "%":
+				if int(y) == 0 {
+					return nil, fmt.Errorf("division by zero")
+				}
+				return float64(int(x) % int(y)), nil
+			}
+		}
+	case string:
+		if y, ok := b.(string); ok && op == "+" {
+			return x + y, nil
+		}
+	case []any:
+		if y, ok := b.([]any); ok {
+			switch op {
+			case "+":
+				return append(append([]any{}, x...), y...), nil
+			case "-":
+				var out []any
+				for _, item := range x {
+					keep := true
+					for _, drop := range y {
+						if compareQueryValues(item, drop) == 0 {
+							keep = false
+							break
+						}
+					}
+					if keep {
+						out = append(out, item)
+					}
+				}
+				if out == nil {
+					out = []any{}
+				}
+				return out, nil
+			}
+		}
+	case map[string]any:
+		if y, ok := b.(map[string]any); ok && op == "+" {
+			out := make(map[string]any, len(x)+len(y))
+			for k, v := range x {
+				out[k] = v
+			}
+			for k, v := range y {
+				out[k] = v
+			}
+			return out, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot apply %s to %s and %s", op, queryType(a), queryType(b))
+}
+
+func queryTruthy(v any) bool {
+	if v == nil {
+		return false
+	}
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return true
+}
+
+func queryType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return reflect.TypeOf(v).String()
+}
+
+// compareQueryValues orders values like jq: null < false < true < numbers <
+// strings < arrays < objects.
+func compareQueryValues(a, b any) int {
+	rank := func(v any) int {
+		switch typed := v.(type) {
+		case nil:
+			return 0
+		case bool:
+			if typed {
+				return 2
+			}
+			return 1
+		case float64:
+			return 3
+		case string:
+			return 4
+		case []any:
+			return 5
+		default:
+			return 6
+		}
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra - rb
+	}
+	switch x := a.(type) {
+	case float64:
+		y := b.(float64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(x, b.(string))
+	case []any:
+		y := b.([]any)
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c := compareQueryValues(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return len(x) - len(y)
+	case map[string]any:
+		y := b.(map[string]any)
+		kx, ky := sortedKeys(x), sortedKeys(y)
+		if c := compareQueryValues(stringsToAny(kx), stringsToAny(ky)); c != 0 {
+			return c
+		}
+		for _, k := range kx {
+			if c := compareQueryValues(x[k], y[k]); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func stringsToAny(ss []string) []any {
+	out := make([]any, len(ss))
+	for i, s := range ss {
+		out[i] = s
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+package grizzly
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"grizzly/pkg/bear"
+)
+
+func TestEvalQuery(t *testing.T) {
+	input := map[string]any{
+		"ok": true,
+		"data": map[string]any{
+			"notes": []any{
+				map[string]any{"identifier": "b", "title": "Beta", "tags": []any{"work"}, "pinned": true},
+				map[string]any{"identifier": "a", "title": "Alpha", "tags": []any{"home", "work"}, "pinned": false},
+				map[string]any{"identifier": "c", "title": "Gamma", "tags": []any{}, "pinned": false},
+			},
+		},
+	}
+	cases := []struct {
+		query string
+		want  string
+	}{
+		{".", `{"data":{"notes":[{"identifier":"b","pinned":true,"tags":["work"],"title":"Beta"},{"identifier":"a","pinned":false,"tags":["home","work"],"title":"Alpha"},{"identifier":"c","pinned":false,"tags":[],"title":"Gamma"}]},"ok":true}`},
+		{".data.notes[].identifier", `"b" "a" "c"`},
+		{".data.notes[0].title", `"Beta"`},
+		{".data.notes[-1].title", `"Gamma"`},
+		{".data.notes[1:].[].identifier", `"a" "c"`},
+		{`.data["notes"] | length`, `3`},
+		{".data.notes | map(.title)", `["Beta","Alpha","Gamma"]`},
+		{".data.notes | sort_by(.title) | map(.identifier)", `["a","b","c"]`},
+		{".data.notes | map(.identifier) | sort | reverse | first", `"c"`},
+		{`.data.notes[] | select(.tags | contains(["work"])) | .title`, `"Beta" "Alpha"`},
+		{`.data.notes[] | select(.pinned | not) | .identifier`, `"a" "c"`},
+		{`.data.notes[] | select(.title | test("^[AB]")) | .identifier`, `"b" "a"`},
+		{`[.data.notes[] | select(.tags | length > 0)] | length`, `2`},
+		{`.data.notes[] | {id: .identifier, title}`, `{"id":"b","title":"Beta"} {"id":"a","title":"Alpha"} {"id":"c","title":"Gamma"}`},
+		{`.data.notes | map(.tags[]) | unique | join(",")`, `"home,work"`},
+		{`.data.notes[0] | keys`, `["identifier","pinned","tags","title"]`},
+		{`.missing // "none"`, `"none"`},
+		{`.ok and (.data.notes | length == 3)`, `true`},
+		{`.data.notes[] | if .pinned then "*" + .title else .title end`, `"*Beta" "Alpha" "Gamma"`},
+		{`.data.notes | group_by(.pinned) | map(length)`, `[2,1]`},
+		{`[.data.notes[].identifier] | limit(2; .[])`, `"b" "a"`},
+		{`.ok?, .data.notes[0].title[0]?`, `true`},
+		{`1 + 2 * 3 - 4 / 2`, `5`},
+	}
+	for _, tc := range cases {
+		outs, err := evalQuery(tc.query, input)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.query, err)
+		}
+		got := make([]string, len(outs))
+		for i, out := range outs {
+			got[i] = formatJSONLine(out)
+		}
+		if strings.Join(got, " ") != tc.want {
+			t.Fatalf("%s:\n got %s\nwant %s", tc.query, strings.Join(got, " "), tc.want)
+		}
+	}
+}
+
+func TestCompileQueryErrors(t *testing.T) {
+	for _, query := range []string{".data.", ".[", "map(.a", "nope", "length(1)", `"open`, ".a ==", "{a b}"} {
+		if _, err := compileQuery(query); err == nil {
+			t.Fatalf("compileQuery(%q) expected error", query)
+		}
+	}
+	if _, err := evalQuery(".data.title[0]", map[string]any{"data": map[string]any{"title": "x"}}); err == nil {
+		t.Fatalf("expected index error")
+	}
+}
+
+func TestOutputQuery(t *testing.T) {
+	notes := &bear.NotesResult{Notes: []bear.NoteSummary{
+		{Identifier: "1", Title: "Plan", ModificationDate: time.Date(2024, 3, 14, 9, 0, 0, 0, time.UTC)},
+		{Identifier: "2", Title: "Trip\nnotes"},
+	}}
+	cases := []struct {
+		opts Options
+		want string
+	}{
+		{Options{JSON: true, Query: ".data.notes[].identifier"}, "\"1\"\n\"2\"\n"},
+		{Options{JSON: true, Query: ".action"}, "\"search\"\n"},
+		{Options{Plain: true, Query: ".data.notes[].title"}, "Plan\nTrip\\nnotes\n"},
+		{Options{Query: ".data.notes | length"}, "2\n"},
+		{Options{Format: "csv", Query: ".data.notes | map({identifier, title})"}, "identifier,title\n1,Plan\n2,\"Trip\nnotes\"\n"},
+		{Options{Format: "tsv", Query: ".data.notes[].identifier"}, "value\n1\n2\n"},
+	}
+	for _, tc := range cases {
+		var buf bytes.Buffer
+		opts := tc.opts
+		out := &Outputter{opts: &opts, stdout: &buf, stderr: &buf}
+		out.WriteSuccess(Result{Action: "search", Data: notes})
+		if buf.String() != tc.want {
+			t.Fatalf("--query %s:\n got %q\nwant %q", tc.opts.Query, buf.String(), tc.want)
+		}
+		if opts.outputErr != nil {
+			t.Fatalf("--query %s: unexpected error %v", tc.opts.Query, opts.outputErr)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	opts := &Options{JSON: true, Query: ".data.notes.title"}
+	out := &Outputter{opts: opts, stdout: &stdout, stderr: &stderr}
+	out.WriteSuccess(Result{Action: "search", Data: notes})
+	if opts.outputErr == nil || stdout.Len() != 0 || !strings.Contains(stderr.String(), "cannot index array") {
+		t.Fatalf("expected query error, stdout=%q stderr=%q", stdout.String(), stderr.String())
+	}
+}
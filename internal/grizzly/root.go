@@ -10,7 +10,23 @@ import (
 
 var Version = "dev"
 
+// NewRootCmd returns the grizzly command with its built-in commands and the
+// configured aliases. Plugins are added by NewRootCmdWithArgs.
 func NewRootCmd() *cobra.Command {
+	root, _ := newRootCmd(true)
+	return root
+}
+
+// NewRootCmdWithArgs is NewRootCmd set up to run args. Plugins are looked up
+// only when args name no other command, or list the commands.
+func NewRootCmdWithArgs(args []string) *cobra.Command {
+	root, opts := newRootCmd(true)
+	addPluginsFor(root, opts, args)
+	root.SetArgs(args)
+	return root
+}
+
+func newRootCmd(withAliases bool) (*cobra.Command, *Options) {
 	opts := &Options{}
 
 	root := &cobra.Command{
@@ -27,6 +43,9 @@ func NewRootCmd() *cobra.Command {
 	root.PersistentFlags().BoolVarP(&opts.Verbose, "verbose", "v", false, "Verbose diagnostics")
 	root.PersistentFlags().BoolVar(&opts.JSON, "json", true, "Output JSON")
 	root.PersistentFlags().BoolVar(&opts.Plain, "plain", false, "Output plain text")
+	root.PersistentFlags().StringVar(&opts.Format, "format", "", "Output format: json, plain, human, table, csv, tsv, yaml, ndjson, template=<go-template>")
+	root.PersistentFlags().StringSliceVar(&opts.Fields, "fields", nil, "Comma-separated fields to output, in order (with --format)")
+	root.PersistentFlags().StringVar(&opts.Query, "query", "", "Filter the result envelope with a jq-style expression")
 	root.PersistentFlags().BoolVar(&opts.NoColor, "no-color", false, "Disable color output")
 	root.PersistentFlags().BoolVar(&opts.DryRun, "dry-run", false, "Print URL without opening Bear")
 	root.PersistentFlags().BoolVar(&opts.PrintURL, "print-url", false, "Print generated Bear URL")
@@ -34,18 +53,46 @@ func NewRootCmd() *cobra.Command {
 	root.PersistentFlags().BoolVar(&opts.NoCallback, "no-callback", false, "Disable x-callback handling even if enabled")
 	root.PersistentFlags().StringVar(&opts.Callback, "callback", "", "Use a custom x-callback URL (implies callback enabled)")
 	root.PersistentFlags().DurationVar(&opts.Timeout, "timeout", 5*time.Second, "Wait for x-callback when enabled (0 disables waiting)")
+	root.PersistentFlags().StringVar(&opts.Opener, "opener", "", "URL opener: auto, open, xdg-open, command, file, http, fake")
+	root.PersistentFlags().BoolVar(&opts.NoDaemon, "no-daemon", false, "Do not route requests through a running grizzly daemon")
 	root.PersistentFlags().StringVar(&opts.TokenFile, "token-file", "", "Read Bear API token from file")
 	root.PersistentFlags().BoolVar(&opts.TokenStdin, "token-stdin", false, "Read Bear API token from stdin")
 	root.PersistentFlags().BoolVar(&opts.NoInput, "no-input", false, "Do not prompt for input")
 	root.PersistentFlags().BoolVarP(&opts.Force, "force", "f", false, "Skip confirmation prompts")
+	root.PersistentFlags().StringVar(&opts.Profile, "profile", "", "Config profile to use (default: $GRIZZLY_PROFILE or the config's profile key)")
 
-	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
-		cfg, err := LoadConfig()
+	_ = root.RegisterFlagCompletionFunc("profile", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		names, err := configProfiles()
 		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	var aliasProblems []string
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		cfg, err := LoadProfileConfig(opts.Profile)
+		lenient := cmd.Annotations[annotationLenientConfig] != ""
+		switch {
+		case err == nil:
+			opts.Profile = cfg.Profile
+			if !lenient {
+				for _, warning := range append(cfg.Warnings, aliasProblems...) {
+					fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s\n", warning)
+				}
+			}
+		case lenient:
+			// config subcommands report and fix broken files themselves.
+		case opts.Profile != "":
+			return usageError(cmd, "%v", err)
+		default:
+			fmt.Fprintf(cmd.ErrOrStderr(), "error: %v\n", err)
 			return &ExitError{Code: ExitFailure, Err: err}
 		}
-		if !cmd.Flags().Changed("token-file") {
+		// A token flag overrides both token settings of the config.
+		if !cmd.Flags().Changed("token-file") && !opts.TokenStdin {
 			opts.TokenFile = cfg.TokenFile
+			opts.TokenCommand = cfg.TokenCommand
 		}
 		if !cmd.Flags().Changed("callback") {
 			opts.Callback = cfg.CallbackURL
@@ -53,8 +100,43 @@ func NewRootCmd() *cobra.Command {
 		if !cmd.Flags().Changed("timeout") && cfg.TimeoutSet {
 			opts.Timeout = cfg.Timeout
 		}
+		if !cmd.Flags().Changed("opener") {
+			opts.Opener = cfg.Opener
+		}
+		opts.OpenerCommand = cfg.OpenerCommand
+		opts.OpenerFile = cfg.OpenerFile
+		opts.OpenerURL = cfg.OpenerURL
+		opts.FakeBearStore = cfg.FakeBearStore
+		if !cmd.Flags().Changed("socket") {
+			opts.DaemonSocket = cfg.DaemonSocket
+		}
+		opts.Daily = cfg.Daily
+		opts.Inbox = cfg.Inbox
 
-		opts.JSON = !opts.Plain
+		human := false
+		switch opts.Format {
+		case "", "json":
+			opts.Format = ""
+		case "plain":
+			opts.Format = ""
+			opts.Plain = true
+		case "human":
+			opts.Format = ""
+			human = true
+		default:
+			if _, err := lookupFormat(opts.Format); err != nil {
+				return usageError(cmd, "%v", err)
+			}
+		}
+		if len(opts.Fields) > 0 && opts.Format == "" {
+			return usageError(cmd, "--fields requires --format")
+		}
+		if opts.Query != "" {
+			if _, err := compileQuery(opts.Query); err != nil {
+				return usageError(cmd, "invalid --query: %v", err)
+			}
+		}
+		opts.JSON = !opts.Plain && !human
 		opts.EnableCallback = !opts.NoCallback
 
 		if opts.JSON && opts.Plain {
@@ -69,6 +151,10 @@ func NewRootCmd() *cobra.Command {
 		if opts.EnableCallback && opts.Callback == "" && opts.Timeout == 0 {
 			return usageError(cmd, "--enable-callback requires --timeout > 0 or --callback")
 		}
+		// config subcommands must still run to fix a broken opener setting.
+		if err := checkOpener(opts); err != nil && !lenient {
+			return usageError(cmd, "%v", err)
+		}
 		if opts.ShowVersion {
 			fmt.Fprintln(os.Stdout, Version)
 			return &ExitError{Code: ExitSuccess}
@@ -76,6 +162,16 @@ func NewRootCmd() *cobra.Command {
 		return nil
 	}
 
+	root.PersistentPostRunE = func(cmd *cobra.Command, args []string) error {
+		if opts.outputErr != nil {
+			return &ExitError{Code: ExitFailure, Err: opts.outputErr}
+		}
+		return nil
+	}
+
 	AddCommands(root, opts)
-	return root
+	if withAliases {
+		aliasProblems = registerAliases(root, loadAliases())
+	}
+	return root, opts
 }
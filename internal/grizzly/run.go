@@ -4,88 +4,162 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net/url"
-	"strings"
+	"sync"
+
+	"grizzly/pkg/bear"
 )
 
 const callbackSource = "grizzly"
 
-func executeAction(opts *Options, action string, params url.Values) error {
-	out := NewOutputter(opts)
-
-	var server *CallbackServer
-	var successURL string
-	var errorURL string
+func newClient(opts *Options, token string) (*bear.Client, error) {
+	opener, err := newOpener(opts)
+	if err != nil {
+		return nil, err
+	}
+	transport := &bear.URLTransport{Opener: opener, Source: callbackSource, DryRun: opts.DryRun}
 	callbackEnabled := !opts.NoCallback && (opts.EnableCallback || opts.Callback != "")
-
 	if callbackEnabled {
 		if opts.Callback != "" {
-			successURL = opts.Callback
-			errorURL = opts.Callback
+			transport.CallbackURL = opts.Callback
 		} else if opts.Timeout > 0 {
-			var err error
-			server, err = StartCallbackServer()
-			if err != nil {
-				return out.WriteError(Result{Action: action}, ErrorInfo{Message: err.Error(), Code: "callback_start"}, ExitFailure)
-			}
-			successURL = server.SuccessURL
-			errorURL = server.ErrorURL
+			transport.Callbacks = bear.LocalCallbacks{Scheme: callbackScheme, Source: callbackSource, OnReject: callbackRejectLogger(opts)}
 		}
 	}
-
-	if successURL != "" {
-		params.Set("x-success", successURL)
-		params.Set("x-error", errorURL)
-		params.Set("x-source", callbackSource)
+	tokenFunc := bear.StaticToken(token)
+	if token == "" {
+		tokenFunc = deferredTokenFunc(opts)
 	}
+	if transport.Callbacks != nil && !opts.DryRun {
+		if socket, ok := runningDaemon(opts); ok {
+			return &bear.Client{Transport: &daemonTransport{socket: socket}, Token: tokenFunc}, nil
+		}
+	}
+	return &bear.Client{Transport: transport, Token: tokenFunc}, nil
+}
 
-	urlStr := BuildURL(action, params)
-	res := Result{Action: action, URL: urlStr}
-
+// newSessionClient returns a client for commands that make many calls and
+// need Bear's answers. All calls share one callback listener; call the
+// returned func when done.
+func newSessionClient(opts *Options, token string) (*bear.Client, func(), error) {
 	if opts.DryRun {
-		if server != nil {
-			_ = server.Shutdown()
-		}
-		out.WriteSuccess(res)
-		return nil
+		return nil, nil, fmt.Errorf("--dry-run is not supported here")
+	}
+	if opts.NoCallback || opts.Callback != "" || opts.Timeout == 0 {
+		return nil, nil, fmt.Errorf("local callbacks are required (--timeout > 0, no --callback)")
 	}
+	client, err := newClient(opts, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	done := func() {}
+	if shared := shareCallbacks(client); shared != nil {
+		done = func() { _ = shared.Close() }
+	}
+	return client, done, nil
+}
 
-	if err := openURL(urlStr); err != nil {
-		if server != nil {
-			_ = server.Shutdown()
-		}
-		return out.WriteError(res, ErrorInfo{Message: err.Error(), Code: "open_url"}, ExitOpen)
+func withCallTimeout(ctx context.Context, opts *Options) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.Timeout > 0 {
+		return context.WithTimeout(ctx, opts.Timeout)
 	}
+	return context.WithCancel(ctx)
+}
 
-	if server == nil {
-		out.WriteSuccess(res)
-		return nil
+func executeAction[T bear.Result](opts *Options, action string, token string, call func(context.Context, *bear.Client) (T, error)) error {
+	out := NewOutputter(opts)
+	client, err := newClient(opts, token)
+	if err != nil {
+		return out.WriteError(Result{Action: action}, ErrorInfo{Message: err.Error(), Code: "opener"}, ExitUsage)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	ctx, cancel := withCallTimeout(context.Background(), opts)
 	defer cancel()
-	cbRes, err := server.Wait(ctx)
+
+	res, err := call(ctx, client)
 	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) {
-			return out.WriteError(res, ErrorInfo{Message: "callback timed out", Code: "timeout"}, ExitTimeout)
-		}
-		return out.WriteError(res, ErrorInfo{Message: err.Error(), Code: "callback_error"}, ExitCallback)
+		return writeClientError(out, action, err)
+	}
+	out.WriteSuccess(Result{Action: action, URL: res.Raw().URL, Data: resultData(res)})
+	return nil
+}
+
+func resultData(res bear.Result) any {
+	raw := res.Raw()
+	if raw.Values == nil {
+		return nil
+	}
+	switch typed := res.(type) {
+	case *bear.NoteResult:
+		return &typed.Note
+	case *bear.CreateResult, *bear.AddTextResult, *bear.TagsResult, *bear.NotesResult:
+		return typed
 	}
+	if len(raw.Values) == 0 {
+		return nil
+	}
+	return ParseCallbackValues(raw.Values)
+}
 
-	if !cbRes.Success {
-		errInfo := ErrorInfo{Message: "bear returned an error", Code: "x-error"}
-		if msg := strings.TrimSpace(cbRes.Values.Get("errorMessage")); msg != "" {
-			errInfo.Message = msg
-		}
-		if code := strings.TrimSpace(cbRes.Values.Get("errorCode")); code != "" {
-			errInfo.Code = code
-		}
-		return out.WriteError(res, errInfo, ExitCallback)
+func writeClientError(out *Outputter, action string, err error) error {
+	var tce *tokenCommandError
+	if errors.As(err, &tce) {
+		return writeTokenCommandError(out, action, tce)
+	}
+	var be *bear.Error
+	if !errors.As(err, &be) {
+		return out.WriteError(Result{Action: action}, ErrorInfo{Message: err.Error(), Code: "invalid_request"}, ExitUsage)
 	}
+	res := Result{Action: action, URL: be.URL}
+	info := ErrorInfo{Message: be.Message, Code: be.Code}
+	return out.WriteError(res, info, exitCodeForError(be))
+}
 
-	res.Data = ParseCallbackValues(cbRes.Values)
-	out.WriteSuccess(res)
-	return nil
+// writeTokenError reports a failure to read the token before any call is
+// made.
+func writeTokenError(opts *Options, action string, err error) error {
+	out := NewOutputter(opts)
+	var tce *tokenCommandError
+	if errors.As(err, &tce) {
+		return writeTokenCommandError(out, action, tce)
+	}
+	code := "token_unavailable"
+	if errors.Is(err, bear.ErrTokenRequired) {
+		code = bear.CodeTokenRequired
+	}
+	return out.WriteError(Result{Action: action}, ErrorInfo{Message: err.Error(), Code: code}, ExitUsage)
+}
+
+func writeTokenCommandError(out *Outputter, action string, err *tokenCommandError) error {
+	code := ExitFailure
+	if err.Timeout {
+		code = ExitTimeout
+	}
+	return out.WriteError(Result{Action: action}, ErrorInfo{Message: err.Error(), Code: "token_command_failed"}, code)
+}
+
+func exitCodeForError(be *bear.Error) int {
+	switch be.Kind {
+	case bear.KindOpen:
+		return ExitOpen
+	case bear.KindTimeout:
+		return ExitTimeout
+	case bear.KindBear:
+		return ExitCallback
+	case bear.KindToken:
+		return ExitUsage
+	case bear.KindDecode:
+		return ExitCallback
+	case bear.KindCallback:
+		if be.Code == bear.CodeCallbackStart {
+			return ExitFailure
+		}
+		return ExitCallback
+	default:
+		return ExitFailure
+	}
 }
 
 func resolveToken(opts *Options) (string, error) {
@@ -101,16 +175,45 @@ func resolveToken(opts *Options) (string, error) {
 	return "", nil
 }
 
+// deferredToken resolves the sources that cost a process or a keychain
+// lookup: token_command, then the token saved by "auth login".
+func deferredToken(opts *Options) (string, error) {
+	if opts.TokenCommand != "" {
+		return runTokenCommand(opts.TokenCommand)
+	}
+	token, _, err := storedToken(opts)
+	return token, err
+}
+
+// deferredTokenFunc calls deferredToken the first time an action needs a
+// token, so commands that never send one do not run token_command or touch
+// the keychain.
+func deferredTokenFunc(opts *Options) bear.TokenFunc {
+	var once sync.Once
+	var token string
+	var err error
+	return func(context.Context) (string, error) {
+		once.Do(func() {
+			token, err = deferredToken(opts)
+		})
+		return token, err
+	}
+}
+
 func maybeRequireToken(opts *Options, required bool) (string, error) {
 	if !required {
 		return resolveToken(opts)
 	}
 	token, err := resolveToken(opts)
+	if err != nil || token != "" {
+		return token, err
+	}
+	token, err = deferredToken(opts)
 	if err != nil {
 		return "", err
 	}
 	if token == "" {
-		return "", fmt.Errorf("missing Bear API token")
+		return "", fmt.Errorf("%w (run grizzly auth login)", bear.ErrTokenRequired)
 	}
 	return token, nil
 }
@@ -0,0 +1,207 @@
+package grizzly
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+
+	"grizzly/pkg/bear"
+)
+
+// termStyle decorates human output for a terminal: ANSI colors, OSC 8
+// hyperlinks and the terminal width. The zero value writes undecorated text
+// with no width limit.
+type termStyle struct {
+	color  bool
+	width  int
+	height int
+}
+
+const (
+	ansiBold  = "1"
+	ansiDim   = "2"
+	ansiRed   = "31"
+	ansiGreen = "32"
+	ansiCyan  = "36"
+)
+
+// newTermStyle enables decoration only when w is a terminal and neither
+// --no-color, NO_COLOR nor TERM=dumb asks for plain text.
+func newTermStyle(opts *Options, w io.Writer) termStyle {
+	f, ok := w.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return termStyle{}
+	}
+	s := termStyle{
+		color: !opts.NoColor && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb",
+		width: 80,
+	}
+	if width, height, err := term.GetSize(int(f.Fd())); err == nil && width > 0 {
+		s.width, s.height = width, height
+	} else if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		s.width = n
+	}
+	return s
+}
+
+func (s termStyle) paint(code, text string) string {
+	if !s.color || text == "" {
+		return text
+	}
+	return "\x1b[" + code + "m" + text + "\x1b[0m"
+}
+
+// link wraps text in an OSC 8 hyperlink. Links are escape sequences too, so
+// they follow the color setting.
+func (s termStyle) link(target, text string) string {
+	if !s.color || target == "" || text == "" {
+		return text
+	}
+	return "\x1b]8;;" + target + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+func openNoteURL(id string) string {
+	return "bear://x-callback-url/open-note?id=" + url.QueryEscape(id)
+}
+
+// tableCell is one cell: its visible text and a function that decorates the
+// padded or truncated text.
+type tableCell struct {
+	text  string
+	style func(string) string
+}
+
+// writeTable aligns rows under a header. When the style has a width, the
+// flexible columns shrink, longest first, until the row fits.
+func (s termStyle) writeTable(w io.Writer, header []string, rows [][]tableCell, flexible []int) {
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = utf8.RuneCountInString(h)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell.text))
+		}
+	}
+	if s.width > 0 {
+		total := 2 * (len(widths) - 1)
+		for _, width := range widths {
+			total += width
+		}
+		for total > s.width {
+			widest := -1
+			for _, col := range flexible {
+				if widths[col] > 10 && (widest < 0 || widths[col] > widths[widest]) {
+					widest = col
+				}
+			}
+			if widest < 0 {
+				break
+			}
+			shrink := min(total-s.width, widths[widest]-10)
+			widths[widest] -= shrink
+			total -= shrink
+		}
+	}
+
+	writeRow := func(cells []tableCell) {
+		var b strings.Builder
+		for i, cell := range cells {
+			text := truncateText(cell.text, widths[i])
+			pad := widths[i] - utf8.RuneCountInString(text)
+			if cell.style != nil {
+				text = cell.style(text)
+			}
+			b.WriteString(text)
+			if i < len(cells)-1 {
+				b.WriteString(strings.Repeat(" ", pad+2))
+			}
+		}
+		fmt.Fprintln(w, b.String())
+	}
+	headerCells := make([]tableCell, len(header))
+	for i, h := range header {
+		headerCells[i] = tableCell{text: h, style: func(text string) string { return s.paint(ansiBold, text) }}
+	}
+	writeRow(headerCells)
+	for _, row := range rows {
+		writeRow(row)
+	}
+}
+
+func truncateText(text string, width int) string {
+	if utf8.RuneCountInString(text) <= width {
+		return text
+	}
+	if width <= 1 {
+		return string([]rune(text)[:width])
+	}
+	return string([]rune(text)[:width-1]) + "…"
+}
+
+func (s termStyle) writeNotesTable(w io.Writer, notes []bear.NoteSummary) {
+	rows := make([][]tableCell, 0, len(notes))
+	for _, note := range notes {
+		note := note
+		tags := make([]string, len(note.Tags))
+		for i, tag := range note.Tags {
+			tags[i] = "#" + tag
+		}
+		modified := ""
+		if !note.ModificationDate.IsZero() {
+			modified = note.ModificationDate.Local().Format("2006-01-02 15:04")
+		}
+		title := note.Title
+		if note.Pinned {
+			title = "* " + title
+		}
+		rows = append(rows, []tableCell{
+			{text: title, style: func(text string) string { return s.link(openNoteURL(note.Identifier), s.paint(ansiBold, text)) }},
+			{text: strings.Join(tags, " "), style: func(text string) string { return s.paint(ansiCyan, text) }},
+			{text: modified, style: func(text string) string { return s.paint(ansiGreen, text) }},
+			{text: note.Identifier, style: func(text string) string { return s.paint(ansiDim, text) }},
+		})
+	}
+	s.writeTable(w, []string{"TITLE", "TAGS", "MODIFIED", "ID"}, rows, []int{0, 1})
+}
+
+// writeTags lays tag names out in columns across the terminal width, or one
+// per line when the width is unknown.
+func (s termStyle) writeTags(w io.Writer, tags []bear.Tag) {
+	if len(tags) == 0 {
+		return
+	}
+	colWidth := 0
+	for _, tag := range tags {
+		colWidth = max(colWidth, utf8.RuneCountInString(tag.Name))
+	}
+	perRow := 1
+	if s.width > 0 {
+		perRow = max(1, (s.width+2)/(colWidth+2))
+	}
+	rowCount := (len(tags) + perRow - 1) / perRow
+	for r := 0; r < rowCount; r++ {
+		var b strings.Builder
+		for c := 0; c < perRow; c++ {
+			i := c*rowCount + r
+			if i >= len(tags) {
+				break
+			}
+			text := tags[i].Name
+			if c > 0 {
+				b.WriteString("  ")
+			}
+			b.WriteString(s.paint(ansiCyan, text))
+			if next := (c+1)*rowCount + r; c < perRow-1 && next < len(tags) {
+				b.WriteString(strings.Repeat(" ", colWidth-utf8.RuneCountInString(text)))
+			}
+		}
+		fmt.Fprintln(w, b.String())
+	}
+}
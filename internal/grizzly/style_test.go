@@ -0,0 +1,64 @@
+package grizzly
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"grizzly/pkg/bear"
+)
+
+func TestNotesTable(t *testing.T) {
+	notes := []bear.NoteSummary{
+		{Identifier: "A1", Title: "A rather long note title that will not fit", Tags: []string{"work"}, ModificationDate: time.Date(2024, 3, 14, 9, 30, 0, 0, time.Local)},
+		{Identifier: "B2", Title: "Short", Pinned: true},
+	}
+	var buf bytes.Buffer
+	termStyle{width: 60}.writeNotesTable(&buf, notes)
+	want := "TITLE                            TAGS   MODIFIED          ID\n" +
+		"A rather long note title that …  #work  2024-03-14 09:30  A1\n" +
+		"* Short                                                   B2\n"
+	if buf.String() != want {
+		t.Fatalf("table:\n got %q\nwant %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	termStyle{color: true, width: 200}.writeNotesTable(&buf, notes[1:])
+	got := buf.String()
+	for _, want := range []string{
+		"\x1b[1mTITLE\x1b[0m",
+		"\x1b]8;;bear://x-callback-url/open-note?id=B2\x1b\\\x1b[1m* Short\x1b[0m\x1b]8;;\x1b\\",
+		"\x1b[2mB2\x1b[0m",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("colored table missing %q:\n%q", want, got)
+		}
+	}
+}
+
+func TestTagColumns(t *testing.T) {
+	tags := []bear.Tag{{Name: "alpha"}, {Name: "b"}, {Name: "gamma"}, {Name: "d"}, {Name: "e"}}
+	var buf bytes.Buffer
+	termStyle{width: 20}.writeTags(&buf, tags)
+	if want := "alpha  gamma  e\nb      d\n"; buf.String() != want {
+		t.Fatalf("tags:\n got %q\nwant %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	termStyle{}.writeTags(&buf, tags[:2])
+	if want := "alpha\nb\n"; buf.String() != want {
+		t.Fatalf("tags without width = %q", buf.String())
+	}
+}
+
+func TestTermStyleDisabled(t *testing.T) {
+	var buf bytes.Buffer
+	s := newTermStyle(&Options{}, &buf)
+	if s.color || s.width != 0 {
+		t.Fatalf("non-terminal writer should not be styled: %+v", s)
+	}
+	if got := s.link("bear://x", s.paint(ansiRed, "x")); got != "x" {
+		t.Fatalf("undecorated text = %q", got)
+	}
+}
@@ -0,0 +1,566 @@
+package grizzly
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"grizzly/pkg/bear"
+)
+
+const syncStateName = ".grizzly-sync.json"
+
+// conflictMarker appears in the names of conflict copies, which sync never
+// pushes.
+const conflictMarker = " (conflict "
+
+type syncState struct {
+	Version int                       `json:"version"`
+	Tag     string                    `json:"tag,omitempty"`
+	Files   map[string]syncStateEntry `json:"files"`
+}
+
+type syncStateEntry struct {
+	Identifier string    `json:"identifier"`
+	SHA256     string    `json:"sha256"`
+	Modified   time.Time `json:"modified"`
+	// Conflict is the unresolved conflict copy; the file is not synced
+	// while it exists.
+	Conflict string `json:"conflict,omitempty"`
+}
+
+type syncedFile struct {
+	Path       string `json:"path"`
+	Identifier string `json:"identifier,omitempty"`
+	Action     string `json:"action"`
+	Conflict   string `json:"conflict,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type syncReport struct {
+	Dir       string       `json:"dir"`
+	Pushed    int          `json:"pushed"`
+	Pulled    int          `json:"pulled"`
+	Created   int          `json:"created"`
+	Trashed   int          `json:"trashed"`
+	Removed   int          `json:"removed"`
+	Conflicts int          `json:"conflicts"`
+	Unchanged int          `json:"unchanged"`
+	Failed    int          `json:"failed"`
+	Files     []syncedFile `json:"files"`
+}
+
+// localFile is a Markdown file as found on disk.
+type localFile struct {
+	hash string
+	meta noteFrontMatter
+	body string
+	// frontMatter records whether the file had a YAML header, so pulls keep
+	// the file's style.
+	frontMatter bool
+}
+
+type syncer struct {
+	opts   *Options
+	client *bear.Client
+	dir    string
+	tag    string
+	state  syncState
+	now    func() time.Time
+	// remote holds the notes the listing returned, by identifier.
+	remote map[string]bear.NoteSummary
+}
+
+func newSyncCmd(opts *Options) *cobra.Command {
+	var tag string
+
+	cmd := &cobra.Command{
+		Use:   "sync <dir>",
+		Short: "Two-way sync between a folder of Markdown files and Bear",
+		Long: `Two-way sync between a folder of Markdown files and Bear.
+
+A state file (` + syncStateName + `) records each file's note identifier,
+content hash and Bear modification date as of the last sync. On each run:
+
+  - files changed locally are pushed with add-text --mode replace_all
+  - notes changed in Bear are pulled with open-note
+  - new files become notes; deleted files move their notes to the trash
+  - notes trashed in Bear remove their (unchanged) files
+  - when both sides changed, Bear's version is written next to the file as
+    "<name> (conflict <time>).md" and neither side is overwritten
+
+Resolve a conflict by merging into the local file and deleting the copy; the
+file is not synced while the copy exists, and the next sync after that pushes
+the merged file. With --tag, new notes get the tag and new
+notes carrying it in Bear are pulled into the folder.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := expandPath(args[0])
+			if err != nil {
+				return &ExitError{Code: ExitUsage, Err: err}
+			}
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				return usageError(cmd, "not a directory: %s", args[0])
+			}
+			token, err := resolveToken(opts)
+			if err != nil {
+				return writeTokenError(opts, "sync", err)
+			}
+			out := NewOutputter(opts)
+			client, done, err := newSessionClient(opts, token)
+			if err != nil {
+				return usageError(cmd, "%v", err)
+			}
+			defer done()
+
+			s := &syncer{opts: opts, client: client, dir: dir, tag: tag, now: time.Now}
+			report, err := s.run(cmd.Context())
+			if err != nil {
+				return writeClientError(out, "sync", err)
+			}
+			out.WriteSuccess(Result{Action: "sync", Data: report})
+			if report.Failed > 0 {
+				return &ExitError{Code: ExitFailure, Err: fmt.Errorf("%d files failed to sync", report.Failed)}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&tag, "tag", "", "Tag that marks notes belonging to this folder")
+	return cmd
+}
+
+func (s *syncer) run(ctx context.Context) (*syncReport, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := s.loadState(); err != nil {
+		return nil, err
+	}
+	if s.tag == "" {
+		s.tag = s.state.Tag
+	}
+	s.state.Tag = s.tag
+
+	listCtx, cancel := withCallTimeout(ctx, s.opts)
+	list, err := s.client.Search(listCtx, bear.SearchOptions{Tag: s.tag, NoShowWindow: true})
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	s.remote = map[string]bear.NoteSummary{}
+	for _, note := range list.Notes {
+		s.remote[note.Identifier] = note
+	}
+
+	files, err := markdownFiles(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	local := map[string]bool{}
+	for _, rel := range files {
+		if !strings.Contains(rel, conflictMarker) {
+			local[rel] = true
+		}
+	}
+
+	report := &syncReport{Dir: s.dir, Files: []syncedFile{}}
+	record := func(file syncedFile) {
+		if file.Action != "unchanged" && file.Action != "failed" {
+			// Save as we go, so a run that is killed does not create its
+			// notes again next time.
+			if err := s.saveState(); err != nil {
+				file = failSync(file, err)
+			}
+		}
+		switch file.Action {
+		case "pushed":
+			report.Pushed++
+		case "pulled":
+			report.Pulled++
+		case "created":
+			report.Created++
+		case "trashed":
+			report.Trashed++
+		case "removed":
+			report.Removed++
+		case "conflict":
+			report.Conflicts++
+		case "unchanged":
+			report.Unchanged++
+			return
+		default:
+			report.Failed++
+		}
+		report.Files = append(report.Files, file)
+	}
+
+	tracked := map[string]bool{}
+	paths := make([]string, 0, len(s.state.Files))
+	for rel := range s.state.Files {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	for _, rel := range paths {
+		entry := s.state.Files[rel]
+		tracked[entry.Identifier] = true
+		record(s.syncTracked(ctx, rel, entry, local[rel]))
+	}
+	for _, rel := range files {
+		if !local[rel] {
+			continue
+		}
+		if _, ok := s.state.Files[rel]; ok {
+			continue
+		}
+		record(s.createNote(ctx, rel))
+	}
+	if s.tag != "" {
+		for _, note := range list.Notes {
+			if !tracked[note.Identifier] {
+				record(s.pullNew(ctx, note))
+			}
+		}
+	}
+
+	if err := s.saveState(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *syncer) syncTracked(ctx context.Context, rel string, entry syncStateEntry, exists bool) syncedFile {
+	file := syncedFile{Path: rel, Identifier: entry.Identifier}
+
+	if entry.Conflict != "" {
+		if _, err := os.Stat(filepath.Join(s.dir, filepath.FromSlash(entry.Conflict))); err == nil {
+			file.Action = "conflict"
+			file.Conflict = entry.Conflict
+			return file
+		}
+		entry.Conflict = ""
+		s.state.Files[rel] = entry
+	}
+
+	var lf *localFile
+	if exists {
+		var err error
+		if lf, err = s.readLocal(rel); err != nil {
+			return failSync(file, err)
+		}
+	}
+	note, err := s.fetchIfChanged(ctx, entry)
+	if err != nil && !errors.Is(err, errNoteGone) {
+		return failSync(file, err)
+	}
+	remoteGone := errors.Is(err, errNoteGone)
+	remoteChanged := note != nil
+	localChanged := lf != nil && lf.hash != entry.SHA256
+
+	switch {
+	case lf == nil && remoteGone:
+		delete(s.state.Files, rel)
+		file.Action = "removed"
+		return file
+	case lf == nil && remoteChanged:
+		// Deleted here but edited in Bear: keep Bear's edit.
+		return s.pull(rel, entry, note, nil, file)
+	case lf == nil:
+		if err := s.trash(ctx, entry.Identifier); err != nil {
+			return failSync(file, err)
+		}
+		delete(s.state.Files, rel)
+		file.Action = "trashed"
+		return file
+	case remoteGone && !localChanged:
+		if err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(rel))); err != nil {
+			return failSync(file, err)
+		}
+		delete(s.state.Files, rel)
+		file.Action = "removed"
+		return file
+	case remoteGone:
+		// Trashed in Bear but edited here: bring it back as a new note.
+		delete(s.state.Files, rel)
+		return s.createNote(ctx, rel)
+	case localChanged && remoteChanged:
+		if sameText(fullNoteText(rel, lf.meta, lf.body), note.Text) {
+			s.state.Files[rel] = syncStateEntry{Identifier: entry.Identifier, SHA256: lf.hash, Modified: note.ModificationDate}
+			file.Action = "unchanged"
+			return file
+		}
+		return s.conflict(rel, entry, note, lf, file)
+	case localChanged:
+		return s.push(ctx, rel, entry, lf, file)
+	case remoteChanged:
+		return s.pull(rel, entry, note, lf, file)
+	default:
+		file.Action = "unchanged"
+		return file
+	}
+}
+
+var errNoteGone = errors.New("note no longer in Bear")
+
+// fetchIfChanged returns the note if Bear's copy changed since the last sync,
+// nil if it did not, and errNoteGone if it was trashed or Bear says it does
+// not exist. Other errors, such as a locked note, are returned as they are.
+func (s *syncer) fetchIfChanged(ctx context.Context, entry syncStateEntry) (*bear.Note, error) {
+	summary, listed := s.remote[entry.Identifier]
+	if listed && summary.ModificationDate.Equal(entry.Modified) {
+		return nil, nil
+	}
+	note, err := s.openNote(ctx, entry.Identifier)
+	if err != nil {
+		if !listed && bear.IsNoteNotFound(err) {
+			return nil, errNoteGone
+		}
+		return nil, err
+	}
+	if note.IsTrashed {
+		return nil, errNoteGone
+	}
+	if note.ModificationDate.Equal(entry.Modified) {
+		return nil, nil
+	}
+	return note, nil
+}
+
+func (s *syncer) openNote(ctx context.Context, id string) (*bear.Note, error) {
+	callCtx, cancel := withCallTimeout(ctx, s.opts)
+	defer cancel()
+	res, err := s.client.OpenNote(callCtx, bear.OpenNoteOptions{
+		NoteRef: bear.NoteRef{ID: id},
+		Window:  bear.Window{NoShowWindow: true, NoOpen: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &res.Note, nil
+}
+
+func (s *syncer) push(ctx context.Context, rel string, entry syncStateEntry, lf *localFile, file syncedFile) syncedFile {
+	callCtx, cancel := withCallTimeout(ctx, s.opts)
+	text := fullNoteText(rel, lf.meta, lf.body)
+	_, err := s.client.AddText(callCtx, bear.AddTextOptions{
+		NoteRef: bear.NoteRef{ID: entry.Identifier},
+		Window:  bear.Window{NoShowWindow: true, NoOpen: true},
+		Text:    text,
+		Mode:    bear.ModeReplaceAll,
+		// Replacing the text drops the tags Bear appended when the note was
+		// created; send them again.
+		Tags: s.missingTags(text, lf),
+	})
+	cancel()
+	if err != nil {
+		return failSync(file, err)
+	}
+	note, err := s.openNote(ctx, entry.Identifier)
+	if err != nil {
+		return failSync(file, err)
+	}
+	s.state.Files[rel] = syncStateEntry{Identifier: entry.Identifier, SHA256: lf.hash, Modified: note.ModificationDate}
+	file.Action = "pushed"
+	return file
+}
+
+func (s *syncer) pull(rel string, entry syncStateEntry, note *bear.Note, lf *localFile, file syncedFile) syncedFile {
+	data, err := noteFileData(note, lf == nil || lf.frontMatter)
+	if err != nil {
+		return failSync(file, err)
+	}
+	if err := s.writeLocal(rel, data); err != nil {
+		return failSync(file, err)
+	}
+	s.state.Files[rel] = syncStateEntry{Identifier: entry.Identifier, SHA256: hashBytes(data), Modified: note.ModificationDate}
+	file.Action = "pulled"
+	return file
+}
+
+// conflict writes Bear's version beside the local file. The state records
+// the copy, takes the new modification date and keeps the old hash: the file
+// is left alone while the copy exists, and once it is merged and the copy
+// deleted the next sync pushes it (or reports a new conflict if Bear changed
+// again).
+func (s *syncer) conflict(rel string, entry syncStateEntry, note *bear.Note, lf *localFile, file syncedFile) syncedFile {
+	data, err := noteFileData(note, lf.frontMatter)
+	if err != nil {
+		return failSync(file, err)
+	}
+	ext := filepath.Ext(rel)
+	copyRel := strings.TrimSuffix(rel, ext) + conflictMarker + s.now().UTC().Format("20060102-150405") + ")" + ext
+	if err := s.writeLocal(copyRel, data); err != nil {
+		return failSync(file, err)
+	}
+	entry.Modified = note.ModificationDate
+	entry.Conflict = copyRel
+	s.state.Files[rel] = entry
+	file.Action = "conflict"
+	file.Conflict = copyRel
+	return file
+}
+
+func (s *syncer) createNote(ctx context.Context, rel string) syncedFile {
+	file := syncedFile{Path: rel}
+	lf, err := s.readLocal(rel)
+	if err != nil {
+		return failSync(file, err)
+	}
+	text := fullNoteText(rel, lf.meta, lf.body)
+	callCtx, cancel := withCallTimeout(ctx, s.opts)
+	created, err := s.client.Create(callCtx, bear.CreateOptions{
+		Window: bear.Window{NoShowWindow: true, NoOpen: true},
+		Text:   text,
+		Tags:   s.missingTags(text, lf),
+		Pin:    lf.meta.Pinned,
+	})
+	cancel()
+	if err != nil {
+		return failSync(file, err)
+	}
+	if created.Identifier == "" {
+		return failSync(file, fmt.Errorf("bear did not return an identifier"))
+	}
+	file.Identifier = created.Identifier
+	note, err := s.openNote(ctx, created.Identifier)
+	if err != nil {
+		return failSync(file, err)
+	}
+	s.state.Files[rel] = syncStateEntry{Identifier: created.Identifier, SHA256: lf.hash, Modified: note.ModificationDate}
+	file.Action = "created"
+	return file
+}
+
+// missingTags lists the front-matter tags and the sync tag that text does
+// not already contain.
+func (s *syncer) missingTags(text string, lf *localFile) []string {
+	tags := append([]string{}, lf.meta.Tags...)
+	if s.tag != "" {
+		tags = append(tags, s.tag)
+	}
+	return tagsNotInText(text, tags)
+}
+
+func (s *syncer) pullNew(ctx context.Context, summary bear.NoteSummary) syncedFile {
+	file := syncedFile{Identifier: summary.Identifier}
+	note, err := s.openNote(ctx, summary.Identifier)
+	if err != nil {
+		return failSync(file, err)
+	}
+	name := sanitizeFilename(note.Title)
+	if name == "" {
+		name = sanitizeFilename(note.Identifier)
+	}
+	rel := name + ".md"
+	for i := 2; s.pathInUse(rel); i++ {
+		rel = fmt.Sprintf("%s %d.md", name, i)
+	}
+	file.Path = rel
+	return s.pull(rel, syncStateEntry{Identifier: note.Identifier}, note, nil, file)
+}
+
+func (s *syncer) pathInUse(rel string) bool {
+	if _, ok := s.state.Files[rel]; ok {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(s.dir, filepath.FromSlash(rel)))
+	return err == nil
+}
+
+func (s *syncer) trash(ctx context.Context, id string) error {
+	callCtx, cancel := withCallTimeout(ctx, s.opts)
+	defer cancel()
+	_, err := s.client.Trash(callCtx, bear.MoveOptions{ID: id, NoShowWindow: true})
+	return err
+}
+
+func (s *syncer) readLocal(rel string) (*localFile, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(rel)))
+	if err != nil {
+		return nil, err
+	}
+	meta, body, err := parseNoteFile(data)
+	if err != nil {
+		return nil, err
+	}
+	return &localFile{
+		hash:        hashBytes(data),
+		meta:        meta,
+		body:        body,
+		frontMatter: strings.HasPrefix(strings.ReplaceAll(string(data), "\r\n", "\n"), "---\n"),
+	}, nil
+}
+
+func (s *syncer) writeLocal(rel string, data []byte) error {
+	path := filepath.Join(s.dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func noteFileData(note *bear.Note, frontMatter bool) ([]byte, error) {
+	if frontMatter {
+		return renderNoteFile(frontMatterFor(note), note.Text)
+	}
+	text := note.Text
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return []byte(text), nil
+}
+
+func sameText(a, b string) bool {
+	return strings.TrimRight(a, "\n") == strings.TrimRight(b, "\n")
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func failSync(file syncedFile, err error) syncedFile {
+	file.Action = "failed"
+	file.Error = err.Error()
+	return file
+}
+
+func (s *syncer) loadState() error {
+	s.state = syncState{Version: 1, Files: map[string]syncStateEntry{}}
+	data, err := os.ReadFile(filepath.Join(s.dir, syncStateName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := json.Unmarshal(data, &s.state); err != nil {
+		return fmt.Errorf("read %s: %w", syncStateName, err)
+	}
+	if s.state.Files == nil {
+		s.state.Files = map[string]syncStateEntry{}
+	}
+	return nil
+}
+
+func (s *syncer) saveState() error {
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write then rename, so a run killed mid-write keeps the old state.
+	path := filepath.Join(s.dir, syncStateName)
+	if err := os.WriteFile(path+".tmp", append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
@@ -0,0 +1,181 @@
+package grizzly
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"grizzly/pkg/bear"
+)
+
+func TestSyncerPushPullAndConflict(t *testing.T) {
+	fb, client := newFakeSession(t)
+	clock := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	fb.now = func() time.Time {
+		clock = clock.Add(time.Minute)
+		return clock
+	}
+	dir := t.TempDir()
+	write := func(rel, content string) {
+		if err := os.WriteFile(filepath.Join(dir, rel), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(rel string) string {
+		data, err := os.ReadFile(filepath.Join(dir, rel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	opts := &Options{Timeout: 2 * time.Second}
+	runSync := func() *syncReport {
+		t.Helper()
+		s := &syncer{opts: opts, client: client, dir: dir, tag: "docs", now: func() time.Time { return clock }}
+		report, err := s.run(context.Background())
+		if err != nil {
+			t.Fatalf("sync: %v", err)
+		}
+		if report.Failed > 0 {
+			t.Fatalf("failures: %+v", report.Files)
+		}
+		return report
+	}
+
+	write("design.md", "# Design\nv1\n")
+	write("gone.md", "# Gone\nbye\n")
+	if r := runSync(); r.Created != 2 {
+		t.Fatalf("first sync = %+v", r)
+	}
+	s := &syncer{dir: dir}
+	if err := s.loadState(); err != nil {
+		t.Fatal(err)
+	}
+	design := s.state.Files["design.md"].Identifier
+	gone := s.state.Files["gone.md"].Identifier
+	if r := runSync(); r.Unchanged != 2 || len(r.Files) != 0 {
+		t.Fatalf("idle sync = %+v", r)
+	}
+
+	// Local edit is pushed; local delete trashes the note.
+	write("design.md", "# Design\nv2\n")
+	os.Remove(filepath.Join(dir, "gone.md"))
+	if r := runSync(); r.Pushed != 1 || r.Trashed != 1 {
+		t.Fatalf("push sync = %+v", r)
+	}
+	if got := fakeCall(t, fb, "open-note", url.Values{"id": {design}}).Get("note"); !strings.Contains(got, "v2") || strings.Count(got, "#docs") != 1 {
+		t.Fatalf("bear note = %q", got)
+	}
+	if fakeCall(t, fb, "open-note", url.Values{"id": {gone}}).Get("is_trashed") != "yes" {
+		t.Fatalf("note not trashed")
+	}
+
+	// Bear edit is pulled; a new tagged note in Bear becomes a file.
+	fakeCall(t, fb, "add-text", url.Values{"id": {design}, "text": {"from bear"}, "mode": {"append"}, "new_line": {"yes"}})
+	fakeCall(t, fb, "create", url.Values{"title": {"Remote"}, "text": {"hello"}, "tags": {"docs"}})
+	if r := runSync(); r.Pulled != 2 {
+		t.Fatalf("pull sync = %+v", r)
+	}
+	if got := read("design.md"); !strings.Contains(got, "from bear") {
+		t.Fatalf("design.md = %q", got)
+	}
+	if got := read("Remote.md"); !strings.Contains(got, "hello") {
+		t.Fatalf("Remote.md = %q", got)
+	}
+
+	// Both sides change: a conflict copy holds Bear's version.
+	fakeCall(t, fb, "add-text", url.Values{"id": {design}, "text": {"bear side"}, "mode": {"append"}, "new_line": {"yes"}})
+	write("design.md", "# Design\nlocal side\n")
+	r := runSync()
+	if r.Conflicts != 1 || len(r.Files) != 1 || r.Files[0].Conflict == "" {
+		t.Fatalf("conflict sync = %+v", r)
+	}
+	if got := read(r.Files[0].Conflict); !strings.Contains(got, "bear side") {
+		t.Fatalf("conflict copy = %q", got)
+	}
+	if got := read("design.md"); got != "# Design\nlocal side\n" {
+		t.Fatalf("local clobbered: %q", got)
+	}
+	if got := fakeCall(t, fb, "open-note", url.Values{"id": {design}}).Get("note"); !strings.Contains(got, "bear side") {
+		t.Fatalf("bear clobbered: %q", got)
+	}
+
+	// Nothing is pushed while the conflict copy exists.
+	copyRel := r.Files[0].Conflict
+	if r := runSync(); r.Conflicts != 1 || r.Pushed != 0 {
+		t.Fatalf("unresolved sync = %+v", r)
+	}
+	if got := fakeCall(t, fb, "open-note", url.Values{"id": {design}}).Get("note"); !strings.Contains(got, "bear side") {
+		t.Fatalf("bear clobbered while unresolved: %q", got)
+	}
+
+	// After merging locally and deleting the copy, the next sync pushes.
+	write("design.md", "# Design\nlocal side\nbear side\n")
+	os.Remove(filepath.Join(dir, copyRel))
+	if r := runSync(); r.Pushed != 1 || r.Conflicts != 0 {
+		t.Fatalf("resolve sync = %+v", r)
+	}
+
+	// A note Bear cannot open for another reason than not existing keeps its file.
+	note := fb.noteByID(design)
+	note.Text = "# Design\nlocked"
+	note.Locked = true
+	note.ModificationDate = fb.now()
+	s = &syncer{opts: opts, client: client, dir: dir, tag: "docs", now: func() time.Time { return clock }}
+	report, err := s.run(context.Background())
+	if err != nil || report.Failed != 1 || report.Removed != 0 {
+		t.Fatalf("locked sync = %+v, %v", report, err)
+	}
+	if got := read("design.md"); !strings.Contains(got, "local side") {
+		t.Fatalf("design.md = %q", got)
+	}
+}
+
+// stateCheckTransport runs check before each create after the first.
+type stateCheckTransport struct {
+	bear.Transport
+	creates int
+	check   func()
+}
+
+func (c *stateCheckTransport) RoundTrip(ctx context.Context, req *bear.Request) (*bear.Response, error) {
+	if req.Action == "create" {
+		if c.creates > 0 {
+			c.check()
+		}
+		c.creates++
+	}
+	return c.Transport.RoundTrip(ctx, req)
+}
+
+func TestSyncerSavesStateAsItGoes(t *testing.T) {
+	_, client := newFakeSession(t)
+	dir := t.TempDir()
+	for _, name := range []string{"a.md", "b.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("# "+name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checked := false
+	client.Transport = &stateCheckTransport{Transport: client.Transport, check: func() {
+		data, err := os.ReadFile(filepath.Join(dir, syncStateName))
+		var state syncState
+		if err == nil {
+			err = json.Unmarshal(data, &state)
+		}
+		if _, ok := state.Files["a.md"]; err != nil || !ok {
+			t.Errorf("state before the second create = %s, %v", data, err)
+		}
+		checked = true
+	}}
+	s := &syncer{opts: &Options{Timeout: 2 * time.Second}, client: client, dir: dir, tag: "docs", now: time.Now}
+	report, err := s.run(context.Background())
+	if err != nil || report.Created != 2 || !checked {
+		t.Fatalf("sync = %+v, %v (checked %v)", report, err, checked)
+	}
+}
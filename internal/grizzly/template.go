@@ -0,0 +1,406 @@
+package grizzly
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// noteTemplate is a Markdown file whose front matter may set a title
+// pattern, default tags and variable defaults. Title and body are rendered
+// with text/template.
+type noteTemplate struct {
+	Name  string
+	Path  string
+	Title string
+	Tags  []string
+	Pin   bool
+	Vars  map[string]string
+	Body  string
+}
+
+type templateHeader struct {
+	Title string            `yaml:"title"`
+	Tags  []string          `yaml:"tags"`
+	Pin   bool              `yaml:"pin"`
+	Vars  map[string]string `yaml:"vars"`
+}
+
+// templateDirs lists template directories by precedence: the project's
+// .grizzly/templates, then templates next to the user config file.
+func templateDirs() ([]string, error) {
+	var dirs []string
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	dirs = append(dirs, filepath.Join(wd, ".grizzly", "templates"))
+	userPath, err := userConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	return append(dirs, filepath.Join(filepath.Dir(userPath), "templates")), nil
+}
+
+// findTemplate resolves a template by name, or by path when name contains a
+// path separator.
+func findTemplate(name string) (*noteTemplate, error) {
+	if strings.ContainsRune(name, filepath.Separator) || strings.Contains(name, "/") {
+		path, err := expandPath(name)
+		if err != nil {
+			return nil, err
+		}
+		return loadTemplate(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), path)
+	}
+	dirs, err := templateDirs()
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		for _, ext := range []string{".md", ".markdown", ".tmpl"} {
+			path := filepath.Join(dir, name+ext)
+			if _, err := os.Stat(path); err == nil {
+				return loadTemplate(name, path)
+			}
+		}
+	}
+	return nil, fmt.Errorf("template %q not found in %s", name, strings.Join(dirs, ", "))
+}
+
+func loadTemplate(name, path string) (*noteTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tpl, err := parseTemplate(name, data)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", path, err)
+	}
+	tpl.Path = path
+	return tpl, nil
+}
+
+func parseTemplate(name string, data []byte) (*noteTemplate, error) {
+	header, body, err := splitFrontMatter(data)
+	if err != nil {
+		return nil, err
+	}
+	var meta templateHeader
+	if err := yaml.Unmarshal([]byte(header), &meta); err != nil {
+		return nil, fmt.Errorf("front matter: %w", err)
+	}
+	return &noteTemplate{
+		Name:  name,
+		Title: meta.Title,
+		Tags:  meta.Tags,
+		Pin:   meta.Pin,
+		Vars:  meta.Vars,
+		Body:  strings.TrimLeft(body, "\n"),
+	}, nil
+}
+
+// parseTemplateVars turns repeated key=value flags into a map.
+func parseTemplateVars(pairs []string) (map[string]string, error) {
+	vars := map[string]string{}
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("--var must be key=value, got %q", pair)
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// templateRenderer renders templates. ask is nil when prompting is not
+// allowed; missing variables are then an error.
+type templateRenderer struct {
+	vars   map[string]string
+	now    func() time.Time
+	getenv func(string) string
+	branch func() string
+	ask    func(label, def string) (string, error)
+}
+
+func newTemplateRenderer(opts *Options, vars map[string]string) *templateRenderer {
+	r := &templateRenderer{vars: vars, now: time.Now, getenv: os.Getenv, branch: gitBranch}
+	if !opts.NoInput && stdinIsTTY() {
+		reader := bufio.NewReader(os.Stdin)
+		r.ask = func(label, def string) (string, error) {
+			return readPromptLine(reader, os.Stderr, label, def)
+		}
+	}
+	return r
+}
+
+// render returns the rendered title (empty when the template has none) and
+// body. Variables referenced but not set are prompted for, or reported.
+func (r *templateRenderer) render(tpl *noteTemplate) (string, string, error) {
+	data := map[string]string{}
+	for key, value := range r.vars {
+		data[key] = value
+	}
+	names := make([]string, 0, len(tpl.Vars))
+	for name := range tpl.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := data[name]; ok {
+			continue
+		}
+		if def := tpl.Vars[name]; def != "" {
+			data[name] = def
+			continue
+		}
+		if err := r.fill(data, name); err != nil {
+			return "", "", err
+		}
+	}
+
+	asked := map[string]string{}
+	funcs := r.funcs(asked)
+	parts := []struct{ name, text string }{{tpl.Name + ":title", tpl.Title}, {tpl.Name, tpl.Body}}
+	for _, part := range parts {
+		fields, err := templateFields(part.name, part.text, funcs)
+		if err != nil {
+			return "", "", err
+		}
+		for _, name := range fields {
+			if _, ok := data[name]; ok {
+				continue
+			}
+			if err := r.fill(data, name); err != nil {
+				return "", "", err
+			}
+		}
+	}
+	title, err := executeTemplate(parts[0].name, parts[0].text, funcs, data)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := executeTemplate(parts[1].name, parts[1].text, funcs, data)
+	if err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(title), body, nil
+}
+
+// templateFields lists the variables text reads from its data, in order of
+// first use: .name where dot is the data, and $.name anywhere.
+func templateFields(name, text string, funcs template.FuncMap) ([]string, error) {
+	if text == "" {
+		return nil, nil
+	}
+	t, err := template.New(name).Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var fields []string
+	seen := map[string]bool{}
+	add := func(field string) {
+		if !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+	var walk func(node parse.Node, atRoot bool)
+	walkBranch := func(n *parse.BranchNode, atRoot bool) {
+		walk(n.Pipe, atRoot)
+		// range and with move dot inside their body.
+		walk(n.List, atRoot && n.NodeType == parse.NodeIf)
+		if n.ElseList != nil {
+			walk(n.ElseList, atRoot)
+		}
+	}
+	walk = func(node parse.Node, atRoot bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			for _, child := range n.Nodes {
+				walk(child, atRoot)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, atRoot)
+		case *parse.IfNode:
+			walkBranch(&n.BranchNode, atRoot)
+		case *parse.RangeNode:
+			walkBranch(&n.BranchNode, atRoot)
+		case *parse.WithNode:
+			walkBranch(&n.BranchNode, atRoot)
+		case *parse.TemplateNode:
+			if n.Pipe != nil {
+				walk(n.Pipe, atRoot)
+			}
+		case *parse.PipeNode:
+			for _, cmd := range n.Cmds {
+				walk(cmd, atRoot)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg, atRoot)
+			}
+		case *parse.ChainNode:
+			walk(n.Node, atRoot)
+		case *parse.FieldNode:
+			if atRoot {
+				add(n.Ident[0])
+			}
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				add(n.Ident[1])
+			}
+		}
+	}
+	// The main template first, then any it defines, for a stable order.
+	walk(t.Tree.Root, true)
+	defined := t.Templates()
+	sort.Slice(defined, func(i, j int) bool { return defined[i].Name() < defined[j].Name() })
+	for _, tmpl := range defined {
+		if tmpl != t && tmpl.Tree != nil {
+			walk(tmpl.Tree.Root, true)
+		}
+	}
+	return fields, nil
+}
+
+func (r *templateRenderer) fill(data map[string]string, name string) error {
+	if r.ask == nil {
+		return fmt.Errorf("template variable %q is not set (use --var %s=...)", name, name)
+	}
+	value, err := r.ask(name, "")
+	if err != nil {
+		return err
+	}
+	data[name] = value
+	return nil
+}
+
+func (r *templateRenderer) funcs(asked map[string]string) template.FuncMap {
+	return template.FuncMap{
+		"now": func() time.Time { return r.now() },
+		"date": func(layout string, offset ...string) (string, error) {
+			t := r.now()
+			for _, o := range offset {
+				var err error
+				if t, err = applyDateOffset(t, o); err != nil {
+					return "", err
+				}
+			}
+			return t.Format(layout), nil
+		},
+		"env":       func(name string) string { return r.getenv(name) },
+		"gitBranch": func() string { return r.branch() },
+		"prompt": func(label string, def ...string) (string, error) {
+			if value, ok := asked[label]; ok {
+				return value, nil
+			}
+			fallback := strings.Join(def, " ")
+			if r.ask == nil {
+				if fallback != "" {
+					return fallback, nil
+				}
+				return "", fmt.Errorf("template prompt %q needs interactive input", label)
+			}
+			value, err := r.ask(label, fallback)
+			if err != nil {
+				return "", err
+			}
+			asked[label] = value
+			return value, nil
+		},
+		"default": func(def, value string) string {
+			if value == "" {
+				return def
+			}
+			return value
+		},
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"trim":  strings.TrimSpace,
+	}
+}
+
+func executeTemplate(name, text string, funcs template.FuncMap, data map[string]string) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	t, err := template.New(name).Option("missingkey=error").Funcs(funcs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+var dateOffsetPattern = regexp.MustCompile(`^([+-]?\d+)([smhdwMy])$`)
+
+// applyDateOffset shifts t by offsets like "+1d", "-2w", "3h" or "+1M"
+// (months) and "-1y".
+func applyDateOffset(t time.Time, offset string) (time.Time, error) {
+	match := dateOffsetPattern.FindStringSubmatch(strings.TrimSpace(offset))
+	if match == nil {
+		return t, fmt.Errorf("invalid date offset %q (want e.g. +1d, -2w, +1M)", offset)
+	}
+	n, err := strconv.Atoi(match[1])
+	if err != nil {
+		return t, err
+	}
+	switch match[2] {
+	case "s":
+		return t.Add(time.Duration(n) * time.Second), nil
+	case "m":
+		return t.Add(time.Duration(n) * time.Minute), nil
+	case "h":
+		return t.Add(time.Duration(n) * time.Hour), nil
+	case "d":
+		return t.AddDate(0, 0, n), nil
+	case "w":
+		return t.AddDate(0, 0, 7*n), nil
+	case "M":
+		return t.AddDate(0, n, 0), nil
+	default:
+		return t.AddDate(n, 0, 0), nil
+	}
+}
+
+func gitBranch() string {
+	out, err := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func readPromptLine(reader *bufio.Reader, w io.Writer, label, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w, "%s [%s]: ", label, def)
+	} else {
+		fmt.Fprintf(w, "%s: ", label)
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if line = strings.TrimSpace(line); line == "" {
+		return def, nil
+	}
+	return line, nil
+}
@@ -0,0 +1,95 @@
+package grizzly
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateRender(t *testing.T) {
+	tpl, err := parseTemplate("incident", []byte(`---
+title: 'Incident {{ date "2006-01-02" }} {{ .service | upper }}'
+tags: [incident]
+vars:
+  severity: medium
+  service: ""
+---
+Severity: {{ .severity }}
+Review: {{ date "Jan 2" "+1w" }}
+Branch: {{ gitBranch }} by {{ env "USER" }}
+Owner: {{ .owner }}
+Summary: {{ prompt "Summary" }}
+`))
+	if err != nil {
+		t.Fatalf("parseTemplate: %v", err)
+	}
+	if len(tpl.Tags) != 1 || tpl.Tags[0] != "incident" {
+		t.Fatalf("tags = %v", tpl.Tags)
+	}
+
+	var asked []string
+	r := &templateRenderer{
+		vars:   map[string]string{"severity": "high"},
+		now:    func() time.Time { return time.Date(2024, 3, 30, 9, 0, 0, 0, time.UTC) },
+		getenv: func(string) string { return "sam" },
+		branch: func() string { return "main" },
+		ask: func(label, def string) (string, error) {
+			asked = append(asked, label)
+			return "answer-" + label, nil
+		},
+	}
+	title, body, err := r.render(tpl)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if title != "Incident 2024-03-30 ANSWER-SERVICE" {
+		t.Fatalf("title = %q", title)
+	}
+	want := "Severity: high\nReview: Apr 6\nBranch: main by sam\nOwner: answer-owner\nSummary: answer-Summary\n"
+	if body != want {
+		t.Fatalf("body = %q, want %q", body, want)
+	}
+	if strings.Join(asked, ",") != "service,owner,Summary" {
+		t.Fatalf("asked = %v", asked)
+	}
+
+	r.ask = nil
+	if _, _, err := r.render(tpl); err == nil || !strings.Contains(err.Error(), `"service"`) {
+		t.Fatalf("non-interactive render error = %v", err)
+	}
+}
+
+func TestTemplateFields(t *testing.T) {
+	text := `{{ define "sig" }}-- {{ .author }}{{ end }}{{ .b }} {{ if .a }}{{ .c | upper }}{{ else }}{{ .d }}{{ end }}` +
+		`{{ range .items }}{{ .skip }}{{ $.e }}{{ end }}{{ with .f }}{{ .skip }}{{ end }}{{ template "sig" . }}{{ .b }}`
+	fields, err := templateFields("t", text, (&templateRenderer{}).funcs(nil))
+	if err != nil {
+		t.Fatalf("templateFields: %v", err)
+	}
+	if got := strings.Join(fields, ","); got != "b,a,c,d,items,e,f,author" {
+		t.Fatalf("fields = %s", got)
+	}
+}
+
+func TestApplyDateOffset(t *testing.T) {
+	base := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	cases := map[string]string{
+		"+1d": "2024-02-01 12:00",
+		"-2w": "2024-01-17 12:00",
+		"3h":  "2024-01-31 15:00",
+		"+1y": "2025-01-31 12:00",
+		"-1M": "2023-12-31 12:00",
+	}
+	for offset, want := range cases {
+		got, err := applyDateOffset(base, offset)
+		if err != nil {
+			t.Fatalf("applyDateOffset(%q) error: %v", offset, err)
+		}
+		if got.Format("2006-01-02 15:04") != want {
+			t.Fatalf("applyDateOffset(%q) = %s, want %s", offset, got.Format("2006-01-02 15:04"), want)
+		}
+	}
+	if _, err := applyDateOffset(base, "tomorrow"); err == nil {
+		t.Fatal("expected error for invalid offset")
+	}
+}
@@ -0,0 +1,136 @@
+package grizzly
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenCommandTimeout bounds a token_command run. Password managers may ask
+// for a fingerprint or passphrase first.
+var tokenCommandTimeout = 30 * time.Second
+
+// tokenCommandWaitDelay is how long a timed-out token_command's children may
+// keep its output open.
+const tokenCommandWaitDelay = 500 * time.Millisecond
+
+// tokenCommandError reports a token_command that failed, timed out or
+// printed nothing.
+type tokenCommandError struct {
+	Command string
+	Timeout bool
+	Err     error
+}
+
+func (e *tokenCommandError) Error() string {
+	return fmt.Sprintf("token_command %q: %v", e.Command, e.Err)
+}
+
+func (e *tokenCommandError) Unwrap() error {
+	return e.Err
+}
+
+// tokenCommandCache keeps each command's token for the life of the process,
+// so batch runs and sessions call the helper once.
+var tokenCommandCache = struct {
+	sync.Mutex
+	tokens map[string]string
+}{tokens: map[string]string{}}
+
+// runTokenCommand runs command without a shell and returns its trimmed
+// stdout. Its stderr goes to ours so prompts stay visible.
+func runTokenCommand(command string) (string, error) {
+	tokenCommandCache.Lock()
+	defer tokenCommandCache.Unlock()
+	if token, ok := tokenCommandCache.tokens[command]; ok {
+		return token, nil
+	}
+	argv, err := splitCommandLine(command)
+	if err != nil {
+		return "", &tokenCommandError{Command: command, Err: err}
+	}
+	if len(argv) == 0 {
+		return "", &tokenCommandError{Command: command, Err: errors.New("empty command")}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tokenCommandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	// Killing the command leaves its children running; stop waiting for them
+	// to close stdout soon after.
+	cmd.WaitDelay = tokenCommandWaitDelay
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", &tokenCommandError{Command: command, Timeout: true, Err: fmt.Errorf("timed out after %s", tokenCommandTimeout)}
+		}
+		return "", &tokenCommandError{Command: command, Err: err}
+	}
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", &tokenCommandError{Command: command, Err: errors.New("printed no token")}
+	}
+	tokenCommandCache.tokens[command] = token
+	return token, nil
+}
+
+// splitCommandLine splits s into words like a POSIX shell would, honoring
+// single quotes, double quotes and backslashes, but without any expansion.
+func splitCommandLine(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
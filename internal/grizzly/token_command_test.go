@@ -0,0 +1,98 @@
+package grizzly
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	cases := map[string][]string{
+		`op read op://Private/Bear/token`:  {"op", "read", "op://Private/Bear/token"},
+		`security find -s "Bear API" -w`:   {"security", "find", "-s", "Bear API", "-w"},
+		`pass 'bear token'  ; rm -rf ~`:    {"pass", "bear token", ";", "rm", "-rf", "~"},
+		`echo a\ b "c \"d\"" $HOME '$(x)'`: {"echo", "a b", `c "d"`, "$HOME", "$(x)"},
+		`  `:                               nil,
+		`helper ""`:                        {"helper", ""},
+	}
+	for in, want := range cases {
+		got, err := splitCommandLine(in)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Fatalf("splitCommandLine(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{`echo "open`, `echo 'open`, `echo \`} {
+		if _, err := splitCommandLine(in); err == nil {
+			t.Fatalf("splitCommandLine(%q) accepted malformed input", in)
+		}
+	}
+}
+
+func TestRunTokenCommand(t *testing.T) {
+	dir := t.TempDir()
+	count := filepath.Join(dir, "count")
+	script := filepath.Join(dir, "helper")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho x >> \"$1\"\nprintf '  tok  \\n'\n"), 0o700); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	command := script + " " + count
+	for i := 0; i < 3; i++ {
+		if token, err := runTokenCommand(command); err != nil || token != "tok" {
+			t.Fatalf("token = %q, %v", token, err)
+		}
+	}
+	if data, _ := os.ReadFile(count); string(data) != "x\n" {
+		t.Fatalf("helper ran %d times", strings.Count(string(data), "x"))
+	}
+
+	var tce *tokenCommandError
+	if _, err := runTokenCommand("false"); !errors.As(err, &tce) || tce.Timeout {
+		t.Fatalf("failing helper: %v", err)
+	}
+	if _, err := runTokenCommand("true"); err == nil || !strings.Contains(err.Error(), "printed no token") {
+		t.Fatalf("silent helper: %v", err)
+	}
+
+	old := tokenCommandTimeout
+	tokenCommandTimeout = 50 * time.Millisecond
+	defer func() { tokenCommandTimeout = old }()
+	if _, err := runTokenCommand("sleep 5"); !errors.As(err, &tce) || !tce.Timeout {
+		t.Fatalf("slow helper: %v", err)
+	}
+	// A child that keeps stdout open must not hold up the timeout.
+	start := time.Now()
+	if _, err := runTokenCommand(`sh -c "sleep 2; echo tok"`); !errors.As(err, &tce) || !tce.Timeout {
+		t.Fatalf("wrapper helper: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 1500*time.Millisecond {
+		t.Fatalf("wrapper helper took %s", elapsed)
+	}
+}
+
+func TestTokenCommandErrorCode(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("GRIZZLY_OPENER", "fake")
+	t.Setenv("GRIZZLY_TOKEN_COMMAND", "false")
+	for _, name := range []string{"GRIZZLY_PROFILE", "GRIZZLY_TIMEOUT", "GRIZZLY_TOKEN_FILE"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	cmd := NewRootCmd()
+	cmd.SetArgs([]string{"--plain", "tags"})
+	stdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := cmd.Execute()
+	w.Close()
+	os.Stdout = stdout
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	if ExitCode(err) != ExitFailure || !strings.Contains(buf.String(), "error_code=token_command_failed\n") {
+		t.Fatalf("tags = %q, %v", buf.String(), err)
+	}
+}
@@ -7,6 +7,11 @@ type Options struct {
 	Verbose        bool
 	JSON           bool
 	Plain          bool
+	Format         string
+	Fields         []string
+	Query          string
+	Raw            bool
+	Profile        string
 	NoColor        bool
 	DryRun         bool
 	PrintURL       bool
@@ -16,22 +21,74 @@ type Options struct {
 	Timeout        time.Duration
 	TokenFile      string
 	TokenStdin     bool
+	TokenCommand   string
+	Opener         string
+	OpenerCommand  string
+	OpenerFile     string
+	OpenerURL      string
+	FakeBearStore  string
+	DaemonSocket   string
+	NoDaemon       bool
+	Daily          DailyConfig
+	Inbox          InboxConfig
 	NoInput        bool
 	Force          bool
 	ShowVersion    bool
+
+	// outputErr records a failure while writing a result, such as a
+	// --query evaluation error, so the command still exits non-zero.
+	outputErr error
 }
 
 type Config struct {
-	TokenFile   string
-	CallbackURL string
-	Timeout     time.Duration
-	TimeoutSet  bool
+	Profile       string
+	Warnings      []string
+	TokenFile     string
+	TokenCommand  string
+	PluginsDir    string
+	CallbackURL   string
+	Timeout       time.Duration
+	TimeoutSet    bool
+	Opener        string
+	OpenerCommand string
+	OpenerFile    string
+	OpenerURL     string
+	FakeBearStore string
+	DaemonSocket  string
+	Daily         DailyConfig
+	Inbox         InboxConfig
+}
+
+// DailyConfig is the [daily] config table. Formats are Go time layouts;
+// "{week}" in WeekFormat becomes the ISO week number.
+type DailyConfig struct {
+	Format      string
+	WeekFormat  string
+	MonthFormat string
+	Template    string
+	Tag         string
+}
+
+// InboxConfig is the [inbox] config table used by capture. The target is
+// the note with ID, else the day's daily note when Daily is set, else the
+// note titled Title.
+type InboxConfig struct {
+	ID              string
+	Title           string
+	Daily           bool
+	Tag             string
+	Header          string
+	Style           string
+	TimestampFormat string
 }
 
+// Result is what commands hand to Outputter. Data holds a typed payload from
+// pkg/bear (*bear.Note, *bear.NotesResult, ...) or, for actions without one,
+// the raw callback values as a map.
 type Result struct {
 	Action string
 	URL    string
-	Data   map[string]any
+	Data   any
 }
 
 type ErrorInfo struct {
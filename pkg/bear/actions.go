@@ -0,0 +1,485 @@
+package bear
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// NoteRef selects a note by identifier, title, or the current selection.
+// Selected requires a token.
+type NoteRef struct {
+	ID       string
+	Title    string
+	Selected bool
+}
+
+func (r NoteRef) validate() error {
+	if r.Selected && (r.ID != "" || r.Title != "") {
+		return errors.New("selected cannot be combined with id or title")
+	}
+	if r.ID == "" && r.Title == "" && !r.Selected {
+		return errors.New("one of id, title, or selected is required")
+	}
+	return nil
+}
+
+func (r NoteRef) apply(params url.Values) {
+	setString(params, "id", r.ID)
+	setString(params, "title", r.Title)
+	setYes(params, "selected", r.Selected)
+}
+
+// Window controls how Bear presents the note; all fields map to Bear's
+// macOS-only display parameters.
+type Window struct {
+	NewWindow    bool
+	Float        bool
+	NoShowWindow bool
+	NoOpen       bool
+	Edit         bool
+}
+
+func (w Window) apply(params url.Values) {
+	setYes(params, "new_window", w.NewWindow)
+	setYes(params, "float", w.Float)
+	setNo(params, "show_window", w.NoShowWindow)
+	setNo(params, "open_note", w.NoOpen)
+	setYes(params, "edit", w.Edit)
+}
+
+type Mode string
+
+const (
+	ModeAppend     Mode = "append"
+	ModePrepend    Mode = "prepend"
+	ModeReplace    Mode = "replace"
+	ModeReplaceAll Mode = "replace_all"
+)
+
+func (m Mode) validate() error {
+	switch m {
+	case "", ModeAppend, ModePrepend, ModeReplace, ModeReplaceAll:
+		return nil
+	default:
+		return fmt.Errorf("invalid mode: %s", m)
+	}
+}
+
+// File is an attachment with base64-encoded content.
+type File struct {
+	Name   string
+	Base64 string
+}
+
+func NewFile(name string, data []byte) File {
+	return File{Name: name, Base64: base64.StdEncoding.EncodeToString(data)}
+}
+
+type NoteResult struct {
+	Response
+	Note Note
+}
+
+type CreateResult struct {
+	Response
+	Identifier string `json:"identifier"`
+	Title      string `json:"title"`
+}
+
+type AddTextResult struct {
+	Response
+	Title string `json:"title"`
+	Text  string `json:"note"`
+}
+
+type TagsResult struct {
+	Response
+	Tags []Tag `json:"tags"`
+}
+
+type NotesResult struct {
+	Response
+	Notes []NoteSummary `json:"notes"`
+}
+
+type OpenNoteOptions struct {
+	NoteRef
+	Window
+	Header         string
+	Search         string
+	ExcludeTrashed bool
+	Pin            bool
+}
+
+func (c *Client) OpenNote(ctx context.Context, opt OpenNoteOptions) (*NoteResult, error) {
+	if err := opt.NoteRef.validate(); err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	opt.NoteRef.apply(params)
+	setString(params, "header", opt.Header)
+	setString(params, "search", opt.Search)
+	setYes(params, "exclude_trashed", opt.ExcludeTrashed)
+	setYes(params, "pin", opt.Pin)
+	opt.Window.apply(params)
+	if opt.Selected {
+		if err := c.addToken(ctx, "open-note", params, true); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := c.Transport.RoundTrip(ctx, &Request{Action: "open-note", Params: params})
+	if err != nil {
+		return nil, err
+	}
+	res := &NoteResult{Response: *resp}
+	if resp.Values != nil {
+		note, err := DecodeNote(resp.Values)
+		if err != nil {
+			return nil, decodeError(resp, err)
+		}
+		res.Note = *note
+	}
+	return res, nil
+}
+
+type CreateOptions struct {
+	Window
+	Title     string
+	Text      string
+	Clipboard bool
+	Tags      []string
+	File      *File
+	Pin       bool
+	Timestamp bool
+	// HTML sends Text as HTML; BaseURL resolves relative links in it.
+	HTML    bool
+	BaseURL string
+}
+
+func (c *Client) Create(ctx context.Context, opt CreateOptions) (*CreateResult, error) {
+	if opt.BaseURL != "" && !opt.HTML {
+		return nil, errors.New("base url requires html")
+	}
+	params := url.Values{}
+	setString(params, "title", opt.Title)
+	setString(params, "text", opt.Text)
+	setYes(params, "clipboard", opt.Clipboard)
+	setString(params, "tags", joinTags(opt.Tags))
+	if opt.File != nil {
+		if err := setFile(params, opt.File); err != nil {
+			return nil, err
+		}
+	}
+	opt.Window.apply(params)
+	setYes(params, "pin", opt.Pin)
+	setYes(params, "timestamp", opt.Timestamp)
+	if opt.HTML {
+		params.Set("type", "html")
+		setString(params, "url", opt.BaseURL)
+	}
+	resp, err := c.Transport.RoundTrip(ctx, &Request{Action: "create", Params: params})
+	if err != nil {
+		return nil, err
+	}
+	return &CreateResult{Response: *resp, Identifier: resp.Values.Get("identifier"), Title: resp.Values.Get("title")}, nil
+}
+
+type AddTextOptions struct {
+	NoteRef
+	Window
+	Text           string
+	Clipboard      bool
+	Header         string
+	Mode           Mode
+	NewLine        bool
+	Tags           []string
+	ExcludeTrashed bool
+	Timestamp      bool
+}
+
+func (c *Client) AddText(ctx context.Context, opt AddTextOptions) (*AddTextResult, error) {
+	if err := opt.NoteRef.validate(); err != nil {
+		return nil, err
+	}
+	if err := opt.Mode.validate(); err != nil {
+		return nil, err
+	}
+	if opt.NewLine && opt.Mode != "" && opt.Mode != ModeAppend {
+		return nil, errors.New("new line only applies to append mode")
+	}
+	params := url.Values{}
+	opt.NoteRef.apply(params)
+	if opt.Selected {
+		if err := c.addToken(ctx, "add-text", params, true); err != nil {
+			return nil, err
+		}
+	}
+	setString(params, "text", opt.Text)
+	setYes(params, "clipboard", opt.Clipboard)
+	setString(params, "header", opt.Header)
+	setString(params, "mode", string(opt.Mode))
+	setYes(params, "new_line", opt.NewLine)
+	setString(params, "tags", joinTags(opt.Tags))
+	setYes(params, "exclude_trashed", opt.ExcludeTrashed)
+	opt.Window.apply(params)
+	setYes(params, "timestamp", opt.Timestamp)
+	resp, err := c.Transport.RoundTrip(ctx, &Request{Action: "add-text", Params: params})
+	if err != nil {
+		return nil, err
+	}
+	return &AddTextResult{Response: *resp, Title: resp.Values.Get("title"), Text: resp.Values.Get("note")}, nil
+}
+
+type AddFileOptions struct {
+	NoteRef
+	Window
+	File   File
+	Header string
+	Mode   Mode
+}
+
+func (c *Client) AddFile(ctx context.Context, opt AddFileOptions) (*Response, error) {
+	if err := opt.NoteRef.validate(); err != nil {
+		return nil, err
+	}
+	if err := opt.Mode.validate(); err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	opt.NoteRef.apply(params)
+	if opt.Selected {
+		if err := c.addToken(ctx, "add-file", params, true); err != nil {
+			return nil, err
+		}
+	}
+	if err := setFile(params, &opt.File); err != nil {
+		return nil, err
+	}
+	setString(params, "header", opt.Header)
+	setString(params, "mode", string(opt.Mode))
+	opt.Window.apply(params)
+	return c.Transport.RoundTrip(ctx, &Request{Action: "add-file", Params: params})
+}
+
+func (c *Client) Tags(ctx context.Context) (*TagsResult, error) {
+	params := url.Values{}
+	if err := c.addToken(ctx, "tags", params, true); err != nil {
+		return nil, err
+	}
+	resp, err := c.Transport.RoundTrip(ctx, &Request{Action: "tags", Params: params})
+	if err != nil {
+		return nil, err
+	}
+	res := &TagsResult{Response: *resp}
+	if resp.Values != nil {
+		tags, err := DecodeTags(resp.Values.Get("tags"))
+		if err != nil {
+			return nil, decodeError(resp, err)
+		}
+		res.Tags = tags
+	}
+	return res, nil
+}
+
+type OpenTagOptions struct {
+	Names []string
+}
+
+func (c *Client) OpenTag(ctx context.Context, opt OpenTagOptions) (*NotesResult, error) {
+	names := joinTags(opt.Names)
+	if names == "" {
+		return nil, errors.New("tag name required")
+	}
+	params := url.Values{}
+	params.Set("name", names)
+	return c.listNotes(ctx, "open-tag", params, true)
+}
+
+type RenameTagOptions struct {
+	Name         string
+	NewName      string
+	NoShowWindow bool
+}
+
+func (c *Client) RenameTag(ctx context.Context, opt RenameTagOptions) (*Response, error) {
+	if opt.Name == "" || opt.NewName == "" {
+		return nil, errors.New("name and new name are required")
+	}
+	params := url.Values{}
+	params.Set("name", opt.Name)
+	params.Set("new_name", opt.NewName)
+	setNo(params, "show_window", opt.NoShowWindow)
+	return c.Transport.RoundTrip(ctx, &Request{Action: "rename-tag", Params: params})
+}
+
+type DeleteTagOptions struct {
+	Name         string
+	NoShowWindow bool
+}
+
+func (c *Client) DeleteTag(ctx context.Context, opt DeleteTagOptions) (*Response, error) {
+	if opt.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	params := url.Values{}
+	params.Set("name", opt.Name)
+	setNo(params, "show_window", opt.NoShowWindow)
+	return c.Transport.RoundTrip(ctx, &Request{Action: "delete-tag", Params: params})
+}
+
+// MoveOptions targets a note by ID; Search is only used when ID is empty.
+type MoveOptions struct {
+	ID           string
+	Search       string
+	NoShowWindow bool
+}
+
+func (c *Client) Trash(ctx context.Context, opt MoveOptions) (*Response, error) {
+	return c.move(ctx, "trash", opt)
+}
+
+func (c *Client) Archive(ctx context.Context, opt MoveOptions) (*Response, error) {
+	return c.move(ctx, "archive", opt)
+}
+
+func (c *Client) move(ctx context.Context, action string, opt MoveOptions) (*Response, error) {
+	if opt.ID == "" && opt.Search == "" {
+		return nil, errors.New("id or search is required")
+	}
+	params := url.Values{}
+	setString(params, "id", opt.ID)
+	if opt.ID == "" {
+		setString(params, "search", opt.Search)
+	}
+	setNo(params, "show_window", opt.NoShowWindow)
+	return c.Transport.RoundTrip(ctx, &Request{Action: action, Params: params})
+}
+
+type ListOptions struct {
+	Search       string
+	NoShowWindow bool
+}
+
+func (c *Client) Untagged(ctx context.Context, opt ListOptions) (*NotesResult, error) {
+	return c.list(ctx, "untagged", opt, true)
+}
+
+func (c *Client) Todo(ctx context.Context, opt ListOptions) (*NotesResult, error) {
+	return c.list(ctx, "todo", opt, true)
+}
+
+func (c *Client) Today(ctx context.Context, opt ListOptions) (*NotesResult, error) {
+	return c.list(ctx, "today", opt, true)
+}
+
+func (c *Client) Locked(ctx context.Context, opt ListOptions) (*NotesResult, error) {
+	return c.list(ctx, "locked", opt, false)
+}
+
+func (c *Client) list(ctx context.Context, action string, opt ListOptions, useToken bool) (*NotesResult, error) {
+	params := url.Values{}
+	setString(params, "search", opt.Search)
+	setNo(params, "show_window", opt.NoShowWindow)
+	return c.listNotes(ctx, action, params, useToken)
+}
+
+type SearchOptions struct {
+	Term         string
+	Tag          string
+	NoShowWindow bool
+}
+
+func (c *Client) Search(ctx context.Context, opt SearchOptions) (*NotesResult, error) {
+	params := url.Values{}
+	setString(params, "term", opt.Term)
+	setString(params, "tag", opt.Tag)
+	setNo(params, "show_window", opt.NoShowWindow)
+	return c.listNotes(ctx, "search", params, true)
+}
+
+func (c *Client) listNotes(ctx context.Context, action string, params url.Values, useToken bool) (*NotesResult, error) {
+	if useToken {
+		if err := c.addToken(ctx, action, params, false); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := c.Transport.RoundTrip(ctx, &Request{Action: action, Params: params})
+	if err != nil {
+		return nil, err
+	}
+	res := &NotesResult{Response: *resp}
+	if resp.Values != nil {
+		notes, err := DecodeNoteSummaries(resp.Values.Get("notes"))
+		if err != nil {
+			return nil, decodeError(resp, err)
+		}
+		res.Notes = notes
+	}
+	return res, nil
+}
+
+type GrabURLOptions struct {
+	URL    string
+	Tags   []string
+	Pin    bool
+	NoWait bool
+}
+
+func (c *Client) GrabURL(ctx context.Context, opt GrabURLOptions) (*CreateResult, error) {
+	if opt.URL == "" {
+		return nil, errors.New("url is required")
+	}
+	params := url.Values{}
+	params.Set("url", opt.URL)
+	setString(params, "tags", joinTags(opt.Tags))
+	setYes(params, "pin", opt.Pin)
+	if opt.NoWait {
+		params.Set("wait", "no")
+	}
+	resp, err := c.Transport.RoundTrip(ctx, &Request{Action: "grab-url", Params: params})
+	if err != nil {
+		return nil, err
+	}
+	return &CreateResult{Response: *resp, Identifier: resp.Values.Get("identifier"), Title: resp.Values.Get("title")}, nil
+}
+
+func setString(params url.Values, key, value string) {
+	if value != "" {
+		params.Set(key, value)
+	}
+}
+
+func setYes(params url.Values, key string, value bool) {
+	if value {
+		params.Set(key, "yes")
+	}
+}
+
+func setNo(params url.Values, key string, value bool) {
+	if value {
+		params.Set(key, "no")
+	}
+}
+
+func setFile(params url.Values, file *File) error {
+	if file.Name == "" {
+		return errors.New("file name required")
+	}
+	params.Set("file", file.Base64)
+	params.Set("filename", file.Name)
+	return nil
+}
+
+func joinTags(tags []string) string {
+	clean := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			clean = append(clean, tag)
+		}
+	}
+	return strings.Join(clean, ",")
+}
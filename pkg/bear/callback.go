@@ -0,0 +1,360 @@
+package bear
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CallbackResult is what Bear sent to x-success or x-error.
+type CallbackResult struct {
+	Success bool
+	Values  url.Values
+}
+
+// CallbackTransport starts a listener for a single request's callback.
+type CallbackTransport interface {
+	Start() (CallbackSession, error)
+}
+
+// CallbackSession is a pending callback for one request.
+type CallbackSession interface {
+	URLs() (success string, failure string)
+	Wait(ctx context.Context) (CallbackResult, error)
+	Shutdown() error
+}
+
+// RejectFunc is told about callback hits that were ignored because their
+// path or x-source did not match the pending request.
+type RejectFunc func(r *http.Request, reason string)
+
+// LocalCallbacks serves callbacks on an ephemeral 127.0.0.1 port. Bear opens
+// callback URLs through macOS, so Scheme must be registered to forward to
+// http:// (grizzly ships such a helper app for "gzlcb").
+//
+// Callback paths carry a random nonce. When Source is set it is also added to
+// the callback URLs as x-source and required on the way back.
+type LocalCallbacks struct {
+	Scheme   string
+	Source   string
+	OnReject RejectFunc
+}
+
+func (l LocalCallbacks) Start() (CallbackSession, error) {
+	return startCallbackServer(l.Scheme, l.Source, l.OnReject)
+}
+
+type CallbackServer struct {
+	BaseURL    string
+	SuccessURL string
+	ErrorURL   string
+	server     *http.Server
+	listener   net.Listener
+	nonce      string
+	source     string
+	onReject   RejectFunc
+
+	mu        sync.Mutex
+	results   chan CallbackResult
+	delivered bool
+}
+
+// StartCallbackServer listens for a single request's callback.
+func StartCallbackServer(scheme string) (*CallbackServer, error) {
+	return startCallbackServer(scheme, "", nil)
+}
+
+func startCallbackServer(scheme, source string, onReject RejectFunc) (*CallbackServer, error) {
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := callbackBaseURL(scheme, ln)
+	successURL, errorURL := callbackURLs(baseURL, nonce, source)
+	server := &CallbackServer{
+		BaseURL:    baseURL,
+		SuccessURL: successURL,
+		ErrorURL:   errorURL,
+		results:    make(chan CallbackResult, 1),
+		listener:   ln,
+		nonce:      nonce,
+		source:     source,
+		onReject:   onReject,
+	}
+	server.server = &http.Server{Handler: http.HandlerFunc(server.serveHTTP)}
+
+	go func() {
+		_ = server.server.Serve(ln)
+	}()
+
+	return server, nil
+}
+
+func (c *CallbackServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	nonce, success, ok := splitCallbackPath(r.URL.Path)
+	if !ok || !nonceEqual(nonce, c.nonce) {
+		rejectCallback(w, r, c.onReject, "unknown callback path")
+		return
+	}
+	values, err := checkSource(r.URL.Query(), c.source)
+	if err != nil {
+		rejectCallback(w, r, c.onReject, err.Error())
+		return
+	}
+	c.Deliver(success, values)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+// Deliver records a callback as if it had arrived over HTTP. Only the first
+// delivery is kept.
+func (c *CallbackServer) Deliver(success bool, values url.Values) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.delivered {
+		return
+	}
+	c.delivered = true
+	c.results <- CallbackResult{Success: success, Values: values}
+}
+
+func (c *CallbackServer) URLs() (string, string) {
+	return c.SuccessURL, c.ErrorURL
+}
+
+// Wait returns the callback and shuts the server down.
+func (c *CallbackServer) Wait(ctx context.Context) (CallbackResult, error) {
+	select {
+	case res := <-c.results:
+		_ = c.Shutdown()
+		return res, nil
+	case <-ctx.Done():
+		_ = c.Shutdown()
+		return CallbackResult{}, ctx.Err()
+	}
+}
+
+func (c *CallbackServer) Shutdown() error {
+	if c.server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return c.server.Shutdown(ctx)
+}
+
+// SharedCallbacks keeps one listener open across requests. Each request
+// gets its own callback path, so concurrent requests are answered
+// independently. Call Close when done.
+type SharedCallbacks struct {
+	Scheme   string
+	Source   string
+	OnReject RejectFunc
+
+	mu      sync.Mutex
+	baseURL string
+	server  *http.Server
+	pending map[string]*pendingCallback
+}
+
+type pendingCallback struct {
+	results chan CallbackResult
+	once    sync.Once
+}
+
+func (p *pendingCallback) deliver(res CallbackResult) {
+	p.once.Do(func() {
+		p.results <- res
+	})
+}
+
+func (s *SharedCallbacks) Start() (CallbackSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.server == nil {
+		if err := s.listen(); err != nil {
+			return nil, err
+		}
+	}
+	id, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+	s.pending[id] = &pendingCallback{results: make(chan CallbackResult, 1)}
+	return &sharedSession{owner: s, id: id}, nil
+}
+
+func (s *SharedCallbacks) listen() error {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	s.baseURL = callbackBaseURL(s.Scheme, ln)
+	s.pending = map[string]*pendingCallback{}
+	s.server = &http.Server{Handler: http.HandlerFunc(s.serveHTTP)}
+	server := s.server
+	go func() {
+		_ = server.Serve(ln)
+	}()
+	return nil
+}
+
+// serveHTTP routes /<nonce>/success and /<nonce>/error to the pending
+// request.
+func (s *SharedCallbacks) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	id, success, ok := splitCallbackPath(r.URL.Path)
+	var pending *pendingCallback
+	if ok {
+		s.mu.Lock()
+		pending = s.pending[id]
+		s.mu.Unlock()
+	}
+	if pending == nil {
+		rejectCallback(w, r, s.OnReject, "unknown callback path")
+		return
+	}
+	values, err := checkSource(r.URL.Query(), s.Source)
+	if err != nil {
+		rejectCallback(w, r, s.OnReject, err.Error())
+		return
+	}
+	pending.deliver(CallbackResult{Success: success, Values: values})
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+func (s *SharedCallbacks) release(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, id)
+}
+
+// Pending reports how many requests are waiting for a callback.
+func (s *SharedCallbacks) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending)
+}
+
+func (s *SharedCallbacks) Close() error {
+	s.mu.Lock()
+	server := s.server
+	s.server = nil
+	s.pending = nil
+	s.mu.Unlock()
+	if server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
+
+type sharedSession struct {
+	owner *SharedCallbacks
+	id    string
+}
+
+func (s *sharedSession) URLs() (string, string) {
+	s.owner.mu.Lock()
+	defer s.owner.mu.Unlock()
+	return callbackURLs(s.owner.baseURL, s.id, s.owner.Source)
+}
+
+func (s *sharedSession) Wait(ctx context.Context) (CallbackResult, error) {
+	s.owner.mu.Lock()
+	pending := s.owner.pending[s.id]
+	s.owner.mu.Unlock()
+	defer s.owner.release(s.id)
+	if pending == nil {
+		return CallbackResult{}, fmt.Errorf("callback listener closed")
+	}
+	select {
+	case res := <-pending.results:
+		return res, nil
+	case <-ctx.Done():
+		return CallbackResult{}, ctx.Err()
+	}
+}
+
+func (s *sharedSession) Shutdown() error {
+	s.owner.release(s.id)
+	return nil
+}
+
+func newNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func nonceEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func callbackBaseURL(scheme string, ln net.Listener) string {
+	if scheme == "" {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s", scheme, ln.Addr().String())
+}
+
+func callbackURLs(baseURL, nonce, source string) (string, string) {
+	base := baseURL + "/" + nonce
+	query := ""
+	if source != "" {
+		query = "?" + url.Values{"x-source": {source}}.Encode()
+	}
+	return base + "/success" + query, base + "/error" + query
+}
+
+// splitCallbackPath parses /<nonce>/success and /<nonce>/error.
+func splitCallbackPath(path string) (nonce string, success bool, ok bool) {
+	nonce, outcome, found := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if !found || nonce == "" {
+		return "", false, false
+	}
+	switch outcome {
+	case "success":
+		return nonce, true, true
+	case "error":
+		return nonce, false, true
+	default:
+		return "", false, false
+	}
+}
+
+// checkSource verifies the x-source echoed back in a callback and strips it
+// from the values.
+func checkSource(values url.Values, source string) (url.Values, error) {
+	if source == "" {
+		return values, nil
+	}
+	if got := values.Get("x-source"); got != source {
+		return nil, fmt.Errorf("x-source %q does not match", got)
+	}
+	values.Del("x-source")
+	return values, nil
+}
+
+func rejectCallback(w http.ResponseWriter, r *http.Request, onReject RejectFunc, reason string) {
+	if onReject != nil {
+		onReject(r, reason)
+	}
+	http.NotFound(w, r)
+}
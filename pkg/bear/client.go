@@ -0,0 +1,285 @@
+// Package bear is a client for Bear's x-callback-url API.
+//
+// A Client turns typed requests into bear:// URLs and hands them to a
+// Transport. The default URLTransport opens the URL with an Opener and, when
+// Callbacks is set, waits for Bear to answer on x-success or x-error.
+package bear
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// Opener hands a bear:// URL to whatever is responsible for running it.
+type Opener interface {
+	Open(ctx context.Context, rawURL string) error
+}
+
+// OpenerFunc adapts a function to the Opener interface.
+type OpenerFunc func(ctx context.Context, rawURL string) error
+
+func (f OpenerFunc) Open(ctx context.Context, rawURL string) error {
+	return f(ctx, rawURL)
+}
+
+// TokenFunc returns the Bear API token. It is only called for actions that
+// use a token.
+type TokenFunc func(ctx context.Context) (string, error)
+
+// StaticToken returns a TokenFunc that always yields token.
+func StaticToken(token string) TokenFunc {
+	return func(context.Context) (string, error) {
+		return token, nil
+	}
+}
+
+type Request struct {
+	Action string
+	Params url.Values
+}
+
+// Response is the raw outcome of a request. Values is nil when no callback
+// was awaited (callbacks disabled, a custom callback URL, or a dry run).
+type Response struct {
+	Action string     `json:"-"`
+	URL    string     `json:"-"`
+	Values url.Values `json:"-"`
+}
+
+func (r *Response) Raw() *Response {
+	return r
+}
+
+// Result is implemented by every typed result; Raw exposes the underlying
+// response.
+type Result interface {
+	Raw() *Response
+}
+
+type Transport interface {
+	RoundTrip(ctx context.Context, req *Request) (*Response, error)
+}
+
+type ErrorKind int
+
+const (
+	KindTransport ErrorKind = iota
+	KindOpen
+	KindTimeout
+	KindCallback
+	KindBear
+	KindToken
+	KindDecode
+)
+
+const (
+	CodeCallbackStart = "callback_start"
+	CodeOpenURL       = "open_url"
+	CodeTimeout       = "timeout"
+	CodeCallback      = "callback_error"
+	CodeBear          = "x-error"
+	CodeTokenRequired = "token_required"
+	CodeDecode        = "invalid_response"
+
+	// CodeNoteNotFound is the errorCode Bear answers with when the note
+	// does not exist.
+	CodeNoteNotFound = "2"
+)
+
+// Error describes a failed request. For KindBear, Code and Message are the
+// errorCode and errorMessage Bear returned.
+type Error struct {
+	Kind    ErrorKind
+	Action  string
+	URL     string
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// IsNoteNotFound reports whether err is Bear saying the note does not
+// exist, as opposed to a locked note, a timeout or another failure.
+func IsNoteNotFound(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) || e.Kind != KindBear {
+		return false
+	}
+	msg := strings.ToLower(e.Message)
+	return e.Code == CodeNoteNotFound || strings.Contains(msg, "not found") || strings.Contains(msg, "not be found")
+}
+
+// ErrTokenRequired is returned when an action needs a token and the client
+// has none.
+var ErrTokenRequired = errors.New("missing Bear API token")
+
+type Client struct {
+	Transport Transport
+	Token     TokenFunc
+}
+
+// NewClient returns a client that opens URLs with opener and, if callbacks
+// is non-nil, waits for Bear's answer.
+func NewClient(opener Opener, callbacks CallbackTransport) *Client {
+	return &Client{
+		Transport: &URLTransport{Opener: opener, Callbacks: callbacks, Source: "grizzly"},
+	}
+}
+
+// Do sends a raw action. Params is not modified. Unless params already
+// carries one, the client's token is added for actions that take a token.
+func (c *Client) Do(ctx context.Context, action string, params url.Values) (*Response, error) {
+	req := &Request{Action: action, Params: cloneValues(params)}
+	if !req.Params.Has("token") {
+		if use, required := tokenPolicy(action, req.Params); use {
+			if err := c.addToken(ctx, action, req.Params, required); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return c.Transport.RoundTrip(ctx, req)
+}
+
+func tokenPolicy(action string, params url.Values) (use bool, required bool) {
+	switch action {
+	case "tags":
+		return true, true
+	case "open-note", "add-text", "add-file":
+		selected := params.Get("selected") == "yes"
+		return selected, selected
+	case "open-tag", "untagged", "todo", "today", "search":
+		return true, false
+	default:
+		return false, false
+	}
+}
+
+func (c *Client) token(ctx context.Context, action string, required bool) (string, error) {
+	token := ""
+	if c.Token != nil {
+		var err error
+		token, err = c.Token(ctx)
+		if err != nil {
+			return "", &Error{Kind: KindToken, Action: action, Code: CodeTokenRequired, Message: err.Error(), Err: err}
+		}
+	}
+	if token == "" && required {
+		return "", &Error{Kind: KindToken, Action: action, Code: CodeTokenRequired, Message: ErrTokenRequired.Error(), Err: ErrTokenRequired}
+	}
+	return token, nil
+}
+
+func (c *Client) addToken(ctx context.Context, action string, params url.Values, required bool) error {
+	token, err := c.token(ctx, action, required)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		params.Set("token", token)
+	}
+	return nil
+}
+
+// URLTransport opens bear:// URLs and collects Bear's callback.
+type URLTransport struct {
+	Opener Opener
+	// Callbacks, when set, is used to receive x-success/x-error.
+	Callbacks CallbackTransport
+	// CallbackURL, when set, is sent as x-success and x-error and nothing is
+	// awaited. It takes precedence over Callbacks.
+	CallbackURL string
+	Source      string
+	// DryRun builds the URL without opening it.
+	DryRun bool
+}
+
+func (t *URLTransport) RoundTrip(ctx context.Context, req *Request) (*Response, error) {
+	params := req.Params
+	if params == nil {
+		params = url.Values{}
+	}
+
+	var session CallbackSession
+	successURL, errorURL := t.CallbackURL, t.CallbackURL
+	if successURL == "" && t.Callbacks != nil {
+		var err error
+		session, err = t.Callbacks.Start()
+		if err != nil {
+			return nil, &Error{Kind: KindCallback, Action: req.Action, Code: CodeCallbackStart, Message: err.Error(), Err: err}
+		}
+		successURL, errorURL = session.URLs()
+	}
+	if successURL != "" {
+		params.Set("x-success", successURL)
+		params.Set("x-error", errorURL)
+		if t.Source != "" {
+			params.Set("x-source", t.Source)
+		}
+	}
+
+	resp := &Response{Action: req.Action, URL: BuildURL(req.Action, params)}
+	if t.DryRun {
+		if session != nil {
+			_ = session.Shutdown()
+		}
+		return resp, nil
+	}
+
+	if err := t.Opener.Open(ctx, resp.URL); err != nil {
+		if session != nil {
+			_ = session.Shutdown()
+		}
+		return nil, &Error{Kind: KindOpen, Action: req.Action, URL: resp.URL, Code: CodeOpenURL, Message: err.Error(), Err: err}
+	}
+	if session == nil {
+		return resp, nil
+	}
+
+	cbRes, err := session.Wait(ctx)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, &Error{Kind: KindTimeout, Action: req.Action, URL: resp.URL, Code: CodeTimeout, Message: "callback timed out", Err: err}
+		}
+		return nil, &Error{Kind: KindCallback, Action: req.Action, URL: resp.URL, Code: CodeCallback, Message: err.Error(), Err: err}
+	}
+	if !cbRes.Success {
+		return nil, bearError(req.Action, resp.URL, cbRes.Values)
+	}
+	resp.Values = cbRes.Values
+	if resp.Values == nil {
+		resp.Values = url.Values{}
+	}
+	return resp, nil
+}
+
+func bearError(action, rawURL string, values url.Values) *Error {
+	e := &Error{Kind: KindBear, Action: action, URL: rawURL, Code: CodeBear, Message: "bear returned an error"}
+	if msg := strings.TrimSpace(values.Get("errorMessage")); msg != "" {
+		e.Message = msg
+	}
+	if code := strings.TrimSpace(values.Get("errorCode")); code != "" {
+		e.Code = code
+	}
+	return e
+}
+
+func decodeError(resp *Response, err error) *Error {
+	return &Error{Kind: KindDecode, Action: resp.Action, URL: resp.URL, Code: CodeDecode, Message: err.Error(), Err: err}
+}
+
+func cloneValues(values url.Values) url.Values {
+	out := url.Values{}
+	for key, vals := range values {
+		out[key] = append([]string(nil), vals...)
+	}
+	return out
+}
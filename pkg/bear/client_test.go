@@ -0,0 +1,190 @@
+package bear
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingTransport struct {
+	requests []*Request
+	values   url.Values
+}
+
+func (r *recordingTransport) RoundTrip(ctx context.Context, req *Request) (*Response, error) {
+	r.requests = append(r.requests, req)
+	return &Response{Action: req.Action, URL: BuildURL(req.Action, req.Params), Values: r.values}, nil
+}
+
+func TestClientTagsRequiresToken(t *testing.T) {
+	tr := &recordingTransport{}
+	client := &Client{Transport: tr}
+	_, err := client.Tags(context.Background())
+	var be *Error
+	if !errors.As(err, &be) || be.Kind != KindToken {
+		t.Fatalf("err = %v, want token error", err)
+	}
+	if len(tr.requests) != 0 {
+		t.Fatalf("request sent without token")
+	}
+
+	tr.values = url.Values{"tags": {`[{"name":"work"},{"name":"home"}]`}}
+	client.Token = StaticToken("secret")
+	res, err := client.Tags(context.Background())
+	if err != nil {
+		t.Fatalf("Tags: %v", err)
+	}
+	if tr.requests[0].Params.Get("token") != "secret" {
+		t.Fatalf("token param = %q", tr.requests[0].Params.Get("token"))
+	}
+	if len(res.Tags) != 2 || res.Tags[0].Name != "work" {
+		t.Fatalf("tags = %#v", res.Tags)
+	}
+}
+
+func TestClientAddTextParams(t *testing.T) {
+	tr := &recordingTransport{values: url.Values{"title": {"Plan"}, "note": {"# Plan\nx"}}}
+	client := &Client{Transport: tr}
+	res, err := client.AddText(context.Background(), AddTextOptions{
+		NoteRef: NoteRef{Title: "Plan"},
+		Text:    "x",
+		Mode:    ModeAppend,
+		NewLine: true,
+		Tags:    []string{"a", " ", "b"},
+	})
+	if err != nil {
+		t.Fatalf("AddText: %v", err)
+	}
+	params := tr.requests[0].Params
+	if params.Get("mode") != "append" || params.Get("new_line") != "yes" || params.Get("tags") != "a,b" {
+		t.Fatalf("params = %v", params)
+	}
+	if params.Has("token") {
+		t.Fatalf("token sent for non-selected note")
+	}
+	if res.Title != "Plan" || res.Text != "# Plan\nx" {
+		t.Fatalf("result = %#v", res)
+	}
+
+	if _, err := client.AddText(context.Background(), AddTextOptions{Text: "x"}); err == nil {
+		t.Fatalf("expected error without a note reference")
+	}
+}
+
+func TestURLTransportRoundTrip(t *testing.T) {
+	var server *CallbackServer
+	callbacks := callbackFunc(func() (CallbackSession, error) {
+		var err error
+		server, err = StartCallbackServer("test")
+		return server, err
+	})
+	opener := OpenerFunc(func(ctx context.Context, rawURL string) error {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return err
+		}
+		if u.Query().Get("x-success") != server.SuccessURL {
+			t.Errorf("x-success = %q", u.Query().Get("x-success"))
+		}
+		if u.Query().Get("id") == "missing" {
+			server.Deliver(false, url.Values{"errorCode": {"2"}, "errorMessage": {"note not found"}})
+			return nil
+		}
+		server.Deliver(true, url.Values{"identifier": {"ABC"}, "title": {"Hi"}, "note": {"# Hi"}})
+		return nil
+	})
+	client := NewClient(opener, callbacks)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	res, err := client.OpenNote(ctx, OpenNoteOptions{NoteRef: NoteRef{ID: "ABC"}})
+	if err != nil {
+		t.Fatalf("OpenNote: %v", err)
+	}
+	if res.Note.Identifier != "ABC" || res.Note.Text != "# Hi" {
+		t.Fatalf("note = %#v", res.Note)
+	}
+
+	_, err = client.OpenNote(ctx, OpenNoteOptions{NoteRef: NoteRef{ID: "missing"}})
+	var be *Error
+	if !errors.As(err, &be) || be.Kind != KindBear || be.Code != "2" || be.Message != "note not found" {
+		t.Fatalf("err = %#v", err)
+	}
+	if !IsNoteNotFound(err) || IsNoteNotFound(&Error{Kind: KindBear, Code: "6", Message: "note is locked"}) || IsNoteNotFound(&Error{Kind: KindTimeout, Message: "not found"}) {
+		t.Fatalf("IsNoteNotFound misclassified")
+	}
+}
+
+type callbackFunc func() (CallbackSession, error)
+
+func (f callbackFunc) Start() (CallbackSession, error) {
+	return f()
+}
+
+func TestLocalCallbacksRejectForeignHits(t *testing.T) {
+	var rejected []string
+	callbacks := LocalCallbacks{Scheme: "http", Source: "grizzly", OnReject: func(r *http.Request, reason string) {
+		rejected = append(rejected, r.URL.Path)
+	}}
+	session, err := callbacks.Start()
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	successURL, _ := session.URLs()
+	u, err := url.Parse(successURL)
+	if err != nil {
+		t.Fatalf("parse %q: %v", successURL, err)
+	}
+	if u.Query().Get("x-source") != "grizzly" || len(strings.Split(strings.Trim(u.Path, "/"), "/")) != 2 {
+		t.Fatalf("success url = %q", successURL)
+	}
+
+	get := func(rawURL string) int {
+		resp, err := http.Get(rawURL)
+		if err != nil {
+			t.Fatalf("GET %s: %v", rawURL, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	base := "http://" + u.Host
+	if code := get(base + "/success?identifier=FAKE&x-source=grizzly"); code != http.StatusNotFound {
+		t.Fatalf("path without nonce: status %d", code)
+	}
+	if code := get(base + "/0123/success?identifier=FAKE&x-source=grizzly"); code != http.StatusNotFound {
+		t.Fatalf("wrong nonce: status %d", code)
+	}
+	if code := get(base + u.Path + "?identifier=FAKE&x-source=other"); code != http.StatusNotFound {
+		t.Fatalf("wrong source: status %d", code)
+	}
+	if code := get(successURL + "&identifier=REAL"); code != http.StatusOK {
+		t.Fatalf("real callback: status %d", code)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	res, err := session.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if !res.Success || res.Values.Get("identifier") != "REAL" || res.Values.Has("x-source") {
+		t.Fatalf("result = %#v", res)
+	}
+	if len(rejected) != 3 {
+		t.Fatalf("rejected = %v", rejected)
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	got := RedactURL("bear://x-callback-url/tags?show_window=no&token=s%20ecret&x-success=a")
+	if got != "bear://x-callback-url/tags?show_window=no&token=REDACTED&x-success=a" {
+		t.Fatalf("redacted = %q", got)
+	}
+	if got := RedactURL("bear://x-callback-url/create?title=token=x"); got != "bear://x-callback-url/create?title=token=x" {
+		t.Fatalf("non-token parameter changed: %q", got)
+	}
+}
@@ -0,0 +1,230 @@
+package bear
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type Note struct {
+	Identifier       string    `json:"identifier"`
+	Title            string    `json:"title"`
+	Text             string    `json:"note"`
+	Tags             []string  `json:"tags"`
+	CreationDate     time.Time `json:"creationDate"`
+	ModificationDate time.Time `json:"modificationDate"`
+	IsTrashed        bool      `json:"is_trashed"`
+	Pinned           bool      `json:"pinned"`
+}
+
+type NoteSummary struct {
+	Identifier       string    `json:"identifier"`
+	Title            string    `json:"title"`
+	Tags             []string  `json:"tags"`
+	CreationDate     time.Time `json:"creationDate"`
+	ModificationDate time.Time `json:"modificationDate"`
+	Pinned           bool      `json:"pinned"`
+}
+
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// DecodeError reports a callback payload that does not have the shape Bear
+// documents.
+type DecodeError struct {
+	Field string
+	Value string
+	Err   error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("invalid %s %q: %v", e.Field, e.Value, e.Err)
+	}
+	return fmt.Sprintf("invalid %s %q", e.Field, e.Value)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+}
+
+// ParseDate parses the timestamps Bear returns. An empty value yields the
+// zero time.
+func ParseDate(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date format")
+}
+
+// DecodeNote decodes an open-note callback.
+func DecodeNote(values url.Values) (*Note, error) {
+	note := &Note{
+		Identifier: values.Get("identifier"),
+		Title:      values.Get("title"),
+		Text:       values.Get("note"),
+	}
+	if note.Identifier == "" {
+		return nil, &DecodeError{Field: "identifier", Value: ""}
+	}
+	var err error
+	if note.Tags, err = decodeTagNames(values.Get("tags")); err != nil {
+		return nil, err
+	}
+	if note.IsTrashed, err = decodeFlag("is_trashed", values.Get("is_trashed")); err != nil {
+		return nil, err
+	}
+	if note.Pinned, err = decodeFlag("pin", values.Get("pin")); err != nil {
+		return nil, err
+	}
+	if note.CreationDate, err = decodeDate("creationDate", values.Get("creationDate")); err != nil {
+		return nil, err
+	}
+	if note.ModificationDate, err = decodeDate("modificationDate", values.Get("modificationDate")); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// DecodeTags decodes the "tags" value of a tags callback, a JSON array of
+// {"name": ...} objects. Plain strings are accepted as names.
+func DecodeTags(raw string) ([]Tag, error) {
+	if strings.TrimSpace(raw) == "" {
+		return []Tag{}, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &items); err != nil {
+		return nil, &DecodeError{Field: "tags", Value: raw, Err: err}
+	}
+	tags := make([]Tag, 0, len(items))
+	for _, item := range items {
+		var name string
+		if err := json.Unmarshal(item, &name); err == nil {
+			tags = append(tags, Tag{Name: name})
+			continue
+		}
+		var tag Tag
+		if err := json.Unmarshal(item, &tag); err != nil || tag.Name == "" {
+			return nil, &DecodeError{Field: "tag", Value: string(item), Err: err}
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+type rawNoteSummary struct {
+	Identifier       string          `json:"identifier"`
+	Title            string          `json:"title"`
+	Tags             json.RawMessage `json:"tags"`
+	CreationDate     string          `json:"creationDate"`
+	ModificationDate string          `json:"modificationDate"`
+	Pin              json.RawMessage `json:"pin"`
+}
+
+// DecodeNoteSummaries decodes the "notes" value returned by search and the
+// other note-listing actions.
+func DecodeNoteSummaries(raw string) ([]NoteSummary, error) {
+	if strings.TrimSpace(raw) == "" {
+		return []NoteSummary{}, nil
+	}
+	var items []rawNoteSummary
+	if err := json.Unmarshal([]byte(raw), &items); err != nil {
+		return nil, &DecodeError{Field: "notes", Value: raw, Err: err}
+	}
+	notes := make([]NoteSummary, 0, len(items))
+	for _, item := range items {
+		if item.Identifier == "" {
+			return nil, &DecodeError{Field: "note identifier", Value: ""}
+		}
+		note := NoteSummary{Identifier: item.Identifier, Title: item.Title}
+		var err error
+		if note.Tags, err = decodeTagList(item.Tags); err != nil {
+			return nil, err
+		}
+		if note.Pinned, err = decodeJSONFlag("pin", item.Pin); err != nil {
+			return nil, err
+		}
+		if note.CreationDate, err = decodeDate("creationDate", item.CreationDate); err != nil {
+			return nil, err
+		}
+		if note.ModificationDate, err = decodeDate("modificationDate", item.ModificationDate); err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, nil
+}
+
+func decodeTagNames(raw string) ([]string, error) {
+	tags, err := DecodeTags(raw)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names, nil
+}
+
+// decodeTagList accepts a JSON array of tags or a JSON string holding one.
+func decodeTagList(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return []string{}, nil
+	}
+	var nested string
+	if err := json.Unmarshal(raw, &nested); err == nil {
+		return decodeTagNames(nested)
+	}
+	return decodeTagNames(string(raw))
+}
+
+func decodeFlag(field, raw string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", "no", "false", "0":
+		return false, nil
+	case "yes", "true", "1":
+		return true, nil
+	default:
+		return false, &DecodeError{Field: field, Value: raw}
+	}
+}
+
+func decodeJSONFlag(field string, raw json.RawMessage) (bool, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return false, nil
+	}
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return false, &DecodeError{Field: field, Value: string(raw), Err: err}
+	}
+	return decodeFlag(field, s)
+}
+
+func decodeDate(field, raw string) (time.Time, error) {
+	t, err := ParseDate(raw)
+	if err != nil {
+		return time.Time{}, &DecodeError{Field: field, Value: raw, Err: err}
+	}
+	return t, nil
+}
@@ -0,0 +1,71 @@
+package bear
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestDecodeNote(t *testing.T) {
+	values := url.Values{}
+	values.Set("identifier", "ABC")
+	values.Set("title", "Plan")
+	values.Set("note", "# Plan")
+	values.Set("tags", `["work","home/errand"]`)
+	values.Set("is_trashed", "no")
+	values.Set("pin", "yes")
+	values.Set("creationDate", "2024-03-01T09:30:00Z")
+	values.Set("modificationDate", "2024-03-02T10:00:00+0100")
+
+	note, err := DecodeNote(values)
+	if err != nil {
+		t.Fatalf("DecodeNote: %v", err)
+	}
+	if note.Identifier != "ABC" || note.Text != "# Plan" || !note.Pinned || note.IsTrashed {
+		t.Fatalf("note = %#v", note)
+	}
+	if len(note.Tags) != 2 || note.Tags[1] != "home/errand" {
+		t.Fatalf("tags = %#v", note.Tags)
+	}
+	if !note.CreationDate.Equal(time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)) {
+		t.Fatalf("creationDate = %v", note.CreationDate)
+	}
+	if !note.ModificationDate.Equal(time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("modificationDate = %v", note.ModificationDate)
+	}
+}
+
+func TestDecodeNoteRejectsUnexpectedShapes(t *testing.T) {
+	cases := map[string]url.Values{
+		"missing identifier": {"title": {"x"}},
+		"bad tags":           {"identifier": {"A"}, "tags": {"work"}},
+		"bad flag":           {"identifier": {"A"}, "is_trashed": {"maybe"}},
+		"bad date":           {"identifier": {"A"}, "creationDate": {"yesterday"}},
+	}
+	for name, values := range cases {
+		_, err := DecodeNote(values)
+		var de *DecodeError
+		if !errors.As(err, &de) {
+			t.Fatalf("%s: err = %v, want DecodeError", name, err)
+		}
+	}
+}
+
+func TestDecodeNoteSummaries(t *testing.T) {
+	raw := `[{"identifier":"1","title":"A","tags":["x"],"pin":"yes","modificationDate":"2024-01-01T00:00:00Z"},` +
+		`{"identifier":"2","title":"B","tags":"[\"y\"]","pin":false}]`
+	notes, err := DecodeNoteSummaries(raw)
+	if err != nil {
+		t.Fatalf("DecodeNoteSummaries: %v", err)
+	}
+	if len(notes) != 2 || !notes[0].Pinned || notes[1].Pinned || notes[1].Tags[0] != "y" {
+		t.Fatalf("notes = %#v", notes)
+	}
+	if _, err := DecodeNoteSummaries(`{"identifier":"1"}`); err == nil {
+		t.Fatalf("expected error for non-array notes")
+	}
+	if _, err := DecodeNoteSummaries(`[{"title":"no id"}]`); err == nil {
+		t.Fatalf("expected error for note without identifier")
+	}
+}
@@ -0,0 +1,65 @@
+package bear
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var tokenParam = regexp.MustCompile(`([?&]token=)[^&#]*`)
+
+// BuildURL returns the bear://x-callback-url URL for action with params.
+func BuildURL(action string, params url.Values) string {
+	action = strings.TrimPrefix(action, "/")
+	u := url.URL{
+		Scheme: "bear",
+		Host:   "x-callback-url",
+		Path:   "/" + action,
+	}
+	if params != nil && len(params) > 0 {
+		u.RawQuery = strings.ReplaceAll(params.Encode(), "+", "%20")
+	}
+	return u.String()
+}
+
+// RedactURL replaces the value of the token parameter in a Bear URL, so the
+// URL can be shown or logged without the API token.
+func RedactURL(raw string) string {
+	return tokenParam.ReplaceAllString(raw, "${1}REDACTED")
+}
+
+// ParseCallbackValues flattens callback query values into a generic map,
+// decoding the JSON-encoded "tags" and "notes" fields.
+func ParseCallbackValues(values url.Values) map[string]any {
+	data := map[string]any{}
+	for key, vals := range values {
+		if len(vals) == 0 {
+			continue
+		}
+		if key == "tags" || key == "notes" {
+			if parsed, ok := parseJSONValue(vals[0]); ok {
+				data[key] = parsed
+				continue
+			}
+		}
+		if len(vals) == 1 {
+			data[key] = vals[0]
+		} else {
+			var list []string
+			for _, item := range vals {
+				list = append(list, item)
+			}
+			data[key] = list
+		}
+	}
+	return data
+}
+
+func parseJSONValue(raw string) (any, bool) {
+	var parsed any
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, false
+	}
+	return parsed, true
+}